@@ -0,0 +1,95 @@
+package skink
+
+import (
+	"net/url"
+	"reflect"
+
+	"github.com/skillian/errors"
+)
+
+// The functions in this file are used by the typed accessors generated by
+// GenerateAccessors, but they can be used on their own as well.
+
+// GetChild gets node's direct child named name.  Unlike GetChildByPath, name
+// isn't split into a path.
+func GetChild(node Node, name string) (Node, error) {
+	children := node.Children()
+	if children == nil {
+		return nil, MakeNodeNotFoundByNameString(node, name)
+	}
+	child, err := children.GetName(MakeString(name))
+	if err != nil {
+		return nil, MakeNodeNotFoundByNameString(node, name)
+	}
+	return child, nil
+}
+
+// GetChildValue gets the value of node's child named name (see GetChild),
+// which must be a Value, and coerces it into the variable that ptr points to
+// (see Coerce).
+func GetChildValue(node Node, name string, ptr interface{}) error {
+	out := reflect.ValueOf(ptr)
+	if out.Kind() != reflect.Ptr || out.IsNil() {
+		return errors.Errorf("GetChildValue needs a non-nil pointer, not %T", ptr)
+	}
+	child, err := GetChild(node, name)
+	if err != nil {
+		return err
+	}
+	v, ok := child.(Value)
+	if !ok {
+		return WithNodePath(child, errors.Errorf(
+			"Node is not a Value (type: %T)", child))
+	}
+	value, err := Coerce(v.Value(), out.Type().Elem())
+	if err != nil {
+		return WithNodePath(child, err)
+	}
+	out.Elem().Set(value)
+	return nil
+}
+
+// SetChildValue sets the value of node's child named name (see GetChild),
+// which must be a ValueSetter.  value is first coerced into the type of the
+// child's current value (see Coerce), e.g. ints into strings for
+// StringNodes.
+func SetChildValue(node Node, name string, value interface{}) error {
+	child, err := GetChild(node, name)
+	if err != nil {
+		return err
+	}
+	setter, ok := child.(ValueSetter)
+	if !ok {
+		return WithNodePath(child, errors.Errorf(
+			"Node's value cannot be set (type: %T)", child))
+	}
+	if current := setter.Value(); current != nil {
+		coerced, err := Coerce(value, reflect.TypeOf(current))
+		if err != nil {
+			return WithNodePath(child, err)
+		}
+		value = coerced.Interface()
+	}
+	return WithNodePath(child, setter.SetValue(value))
+}
+
+// CheckNodeClass checks that node's Class is (or is derived from) the Class
+// registered under classURI.
+func CheckNodeClass(node Node, classURI string) error {
+	uri, err := url.Parse(classURI)
+	if err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"invalid Class URI %q: %v",
+			classURI, err)
+	}
+	cls, err := GetClassByURI(uri)
+	if err != nil {
+		return err
+	}
+	if !IsSubclass(node.Class(), cls) {
+		return WithNodePath(node, errors.Errorf(
+			"Node is a %v, not a %v", node.Class().Name(), cls.Name()))
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+package skink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/skillian/errors"
+)
+
+// AccessorSchema describes the typed accessors that GenerateAccessors
+// generates.  It can be read from a JSON schema file (see ReadAccessorSchema)
+// or made from the registered Classes (see ClassAccessorSchema):
+//
+//	{
+//	  "package": "config",
+//	  "types": [
+//	    {"name": "App", "class": "import:acme#App", "fields": [
+//	      {"name": "db", "type": "DB"},
+//	      {"name": "port", "type": "int"},
+//	      {"name": "timeout", "type": "time.Duration", "readOnly": true}
+//	    ]},
+//	    {"name": "DB", "fields": [{"name": "url", "type": "string"}]}
+//	  ]
+//	}
+type AccessorSchema struct {
+	// Package is the name of the generated code's package.
+	Package string `json:"package"`
+
+	// Imports are the import paths of the packages of the fields' Go
+	// types.  "time" is imported automatically when it's used.
+	Imports []string `json:"imports,omitempty"`
+
+	Types []AccessorType `json:"types"`
+}
+
+// AccessorType is a Go type generated by GenerateAccessors that wraps a Node
+// with typed accessors of its children.
+type AccessorType struct {
+	// Name is the Go type's name, which must be exported.
+	Name string `json:"name"`
+
+	// Class, if not empty, is the URI of the Class that the wrapped Nodes
+	// must be (see CheckNodeClass).
+	Class string `json:"class,omitempty"`
+
+	Fields []AccessorField `json:"fields"`
+}
+
+// AccessorField is a child of the Nodes wrapped by an AccessorType.
+type AccessorField struct {
+	// Name is the child's name.  The accessors' names are made from it
+	// (e.g. "max-conns" becomes MaxConns and SetMaxConns).
+	Name string `json:"name"`
+
+	// Type is either the Name of another AccessorType, whose wrapper is
+	// returned, "skink.Node", for the child itself, or the Go type that the
+	// child's value is coerced into (see GetChildValue).
+	Type string `json:"type"`
+
+	// ReadOnly omits the setter of a value's accessors.
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// ReadAccessorSchema reads an AccessorSchema from JSON.
+func ReadAccessorSchema(r io.Reader) (*AccessorSchema, error) {
+	schema := new(AccessorSchema)
+	if err := json.NewDecoder(r).Decode(schema); err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"failed to decode accessor schema: %v",
+			err)
+	}
+	return schema, nil
+}
+
+// ClassAccessorSchema makes an AccessorSchema of the Classes registered
+// under the given namespace (like WriteXSD).  Each Class becomes a type
+// named after its URI's fragment whose fields are the Class's TypeAttrs
+// (see TypeAttrMapper): String attributes are strings, attributes of other
+// Classes in the namespace are those Classes' types and the rest are
+// skink.Nodes.  Attributes without Setters are read-only.
+func ClassAccessorSchema(pkg, namespace string) *AccessorSchema {
+	schema := &AccessorSchema{Package: pkg}
+	uris := sortedClassURIs(namespace)
+	names := make(map[Class]string, len(uris))
+	for _, uri := range uris {
+		if cls, err := GetClassByURI(uri); err == nil {
+			names[cls] = accessorIdent(uri.Fragment)
+		}
+	}
+	for _, uri := range uris {
+		cls, err := GetClassByURI(uri)
+		if err != nil {
+			continue
+		}
+		t := AccessorType{Name: names[cls], Class: uri.String()}
+		if mapper, ok := cls.(TypeAttrMapper); ok {
+			for _, attr := range mapper.TypeAttrMap().TypeAttrs() {
+				field := AccessorField{
+					Name:     attr.Name.String(),
+					Type:     "skink.Node",
+					ReadOnly: attr.Setter == nil,
+				}
+				switch {
+				case attr.Class == StringClass:
+					field.Type = "string"
+				case comparableClass(attr.Class):
+					if name, ok := names[attr.Class]; ok {
+						field.Type = name
+					}
+				}
+				t.Fields = append(t.Fields, field)
+			}
+		}
+		schema.Types = append(schema.Types, t)
+	}
+	return schema
+}
+
+// GenerateAccessors writes gofmt'ed Go source of the schema's types to w.
+// Each type wraps a Node:
+//
+//	type App struct{ node skink.Node }
+//
+//	func AsApp(node skink.Node) (App, error)  // checks the Class, if any
+//	func (x App) Node() skink.Node
+//	func (x App) DB() (DB, error)             // another type's wrapper
+//	func (x App) Port() (int, error)          // see GetChildValue
+//	func (x App) SetPort(value int) error     // see SetChildValue
+//
+// so that code that uses a configuration is checked by the compiler instead
+// of looking its Nodes up by name.  It's meant to be run by go:generate
+// through the skink command's gen subcommand.
+func GenerateAccessors(w io.Writer, schema *AccessorSchema) error {
+	if schema.Package == "" {
+		return errors.Errorf("accessor schema has no package")
+	}
+	types := make(map[string]bool, len(schema.Types))
+	for _, t := range schema.Types {
+		if !isAccessorIdent(t.Name) {
+			return errors.Errorf("invalid accessor type name %q", t.Name)
+		}
+		if types[t.Name] {
+			return errors.Errorf("accessor type %v is defined twice", t.Name)
+		}
+		types[t.Name] = true
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by skink gen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", schema.Package)
+	fmt.Fprintf(&b, "import (\n")
+	std := true
+	for _, path := range accessorImports(schema) {
+		if std && strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			std = false
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "\t%q\n", path)
+	}
+	fmt.Fprintf(&b, ")\n")
+	for _, t := range schema.Types {
+		if err := writeAccessorType(&b, t, types); err != nil {
+			return err
+		}
+	}
+	source, err := format.Source(b.Bytes())
+	if err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"failed to format generated accessors: %v",
+			err)
+	}
+	_, err = w.Write(source)
+	return err
+}
+
+// accessorImports gets the sorted import paths of the generated source with
+// the standard library's first.
+func accessorImports(schema *AccessorSchema) []string {
+	paths := map[string]bool{"github.com/skillian/skink": true}
+	for _, path := range schema.Imports {
+		paths[path] = true
+	}
+	for _, t := range schema.Types {
+		for _, f := range t.Fields {
+			if strings.Contains(f.Type, "time.") {
+				paths["time"] = true
+			}
+		}
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		iStd := !strings.Contains(strings.SplitN(sorted[i], "/", 2)[0], ".")
+		jStd := !strings.Contains(strings.SplitN(sorted[j], "/", 2)[0], ".")
+		if iStd != jStd {
+			return iStd
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
+func writeAccessorType(b *bytes.Buffer, t AccessorType, types map[string]bool) error {
+	fmt.Fprintf(b, "\n// %s wraps a Node", t.Name)
+	if t.Class != "" {
+		fmt.Fprintf(b, " of Class %s", t.Class)
+	}
+	fmt.Fprintf(b, " with typed accessors.\ntype %s struct{ node skink.Node }\n\n", t.Name)
+	article := "a"
+	if strings.ContainsRune("AEIOU", rune(t.Name[0])) {
+		article = "an"
+	}
+	fmt.Fprintf(b, "// As%s wraps node in %s %s", t.Name, article, t.Name)
+	if t.Class != "" {
+		fmt.Fprintf(b, " after checking its Class.\n")
+	} else {
+		fmt.Fprintf(b, ".\n")
+	}
+	fmt.Fprintf(b, "func As%s(node skink.Node) (%s, error) {\n", t.Name, t.Name)
+	if t.Class != "" {
+		fmt.Fprintf(b, "\tif err := skink.CheckNodeClass(node, %q); err != nil {\n", t.Class)
+		fmt.Fprintf(b, "\t\treturn %s{}, err\n\t}\n", t.Name)
+	}
+	fmt.Fprintf(b, "\treturn %s{node: node}, nil\n}\n\n", t.Name)
+	fmt.Fprintf(b, "// Node gets the wrapped Node.\n")
+	fmt.Fprintf(b, "func (x %s) Node() skink.Node { return x.node }\n", t.Name)
+	methods := map[string]bool{"Node": true}
+	for _, f := range t.Fields {
+		ident := accessorIdent(f.Name)
+		setter := "Set" + ident
+		if methods[ident] || methods[setter] {
+			return errors.Errorf(
+				"field %q of accessor type %v is defined twice (or its "+
+					"accessors are named like another field's)",
+				f.Name, t.Name)
+		}
+		methods[ident] = true
+		methods[setter] = true
+		switch {
+		case types[f.Type]:
+			fmt.Fprintf(b, "\n// %s gets the %q child.\n", ident, f.Name)
+			fmt.Fprintf(b, "func (x %s) %s() (%s, error) {\n", t.Name, ident, f.Type)
+			fmt.Fprintf(b, "\tchild, err := skink.GetChild(x.node, %q)\n", f.Name)
+			fmt.Fprintf(b, "\tif err != nil {\n\t\treturn %s{}, err\n\t}\n", f.Type)
+			fmt.Fprintf(b, "\treturn As%s(child)\n}\n", f.Type)
+		case f.Type == "skink.Node":
+			fmt.Fprintf(b, "\n// %s gets the %q child.\n", ident, f.Name)
+			fmt.Fprintf(b, "func (x %s) %s() (skink.Node, error) {\n", t.Name, ident)
+			fmt.Fprintf(b, "\treturn skink.GetChild(x.node, %q)\n}\n", f.Name)
+		case f.Type == "":
+			return errors.Errorf(
+				"field %q of accessor type %v has no type", f.Name, t.Name)
+		default:
+			fmt.Fprintf(b, "\n// %s gets the value of the %q child.\n", ident, f.Name)
+			fmt.Fprintf(b, "func (x %s) %s() (value %s, err error) {\n", t.Name, ident, f.Type)
+			fmt.Fprintf(b, "\terr = skink.GetChildValue(x.node, %q, &value)\n", f.Name)
+			fmt.Fprintf(b, "\treturn value, err\n}\n")
+			if f.ReadOnly {
+				continue
+			}
+			fmt.Fprintf(b, "\n// %s sets the value of the %q child.\n", setter, f.Name)
+			fmt.Fprintf(b, "func (x %s) %s(value %s) error {\n", t.Name, setter, f.Type)
+			fmt.Fprintf(b, "\treturn skink.SetChildValue(x.node, %q, value)\n}\n", f.Name)
+		}
+	}
+	return nil
+}
+
+// accessorInitialisms are the words that accessorIdent upper-cases, like
+// golint's.
+var accessorInitialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DB": true,
+	"DNS": true, "EOF": true, "GUID": true, "HTML": true, "HTTP": true,
+	"HTTPS": true, "ID": true, "IP": true, "JSON": true, "QPS": true,
+	"RAM": true, "RPC": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true,
+	"UUID": true, "URI": true, "URL": true, "UTF8": true, "VM": true,
+	"XML": true,
+}
+
+// accessorIdent makes an exported Go identifier of a Node name by
+// capitalizing each of its words (or upper-casing initialisms), e.g.
+// "max-conns" becomes "MaxConns" and "db_url" becomes "DBURL".
+func accessorIdent(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, word := range words {
+		if upper := strings.ToUpper(word); accessorInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	ident := b.String()
+	if ident == "" || !unicode.IsLetter([]rune(ident)[0]) {
+		ident = "X" + ident
+	}
+	return ident
+}
+
+// isAccessorIdent checks if name is an exported Go identifier.  Unlike
+// accessorIdent's results, it doesn't need to follow Go's initialism
+// conventions (e.g. "Id" is accepted as well as "ID").
+func isAccessorIdent(name string) bool {
+	return token.IsIdentifier(name) && token.IsExported(name)
+}
@@ -0,0 +1,282 @@
+package skink
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/skillian/errors"
+)
+
+// AdminHandler is an http.Handler of an admin API for inspecting and
+// reloading a running Skink context:
+//
+//	GET  /tree          the context's Snapshot as JSON (see WriteJSON)
+//	GET  /node/<path>   the Node at a full path (see (*Skink).ResolvePath)
+//	GET  /health        {"status": "ok"} and counts of roots and Warnings
+//	POST /reload        Reload the context and list the Changes
+//
+// Secret values (see IsSecret) are redacted.  Errors are written as the
+// "error" of a JSON object.
+type AdminHandler struct {
+	// Authorize, if not nil, is called with every request before it's
+	// handled.  Requests that it returns an error for are refused with 403
+	// Forbidden and the error is logged.
+	Authorize func(r *http.Request) error
+
+	sk  *Skink
+	mux *http.ServeMux
+}
+
+// NewAdminHandler creates an AdminHandler of sk that's authorized by sk's
+// AdminAuthorize.
+func NewAdminHandler(sk *Skink) *AdminHandler {
+	h := &AdminHandler{
+		Authorize: sk.AdminAuthorize,
+		sk:        sk,
+		mux:       http.NewServeMux(),
+	}
+	h.mux.HandleFunc("/tree", h.tree)
+	h.mux.HandleFunc("/node/", h.node)
+	h.mux.HandleFunc("/health", h.health)
+	h.mux.HandleFunc("/reload", h.reload)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Authorize != nil {
+		if err := h.Authorize(r); err != nil {
+			h.sk.Warn2("Refused admin API request from %v: %v", r.RemoteAddr, err)
+			writeJSONError(w, http.StatusForbidden, errors.Errorf("forbidden"))
+			return
+		}
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// allowMethod checks that the request's method is method and writes an error
+// response if it isn't.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeJSONError(w, http.StatusMethodNotAllowed, errors.Errorf(
+		"method %v is not allowed", r.Method))
+	return false
+}
+
+func (h *AdminHandler) tree(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	classuri, _ := GetClassURI(NodeClass)
+	snapshot := NewNodeDef(snapshotName, nil, classuri)
+	for _, root := range h.sk.Roots() {
+		def, err := exportRedactedNodeDef(root, snapshot)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		snapshot.Children = append(snapshot.Children, def)
+	}
+	writeJSONResponse(w, http.StatusOK, snapshot)
+}
+
+func (h *AdminHandler) node(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/node/")
+	if path == "" {
+		writeJSONError(w, http.StatusNotFound, errors.Errorf(
+			"no Node path in %q", r.URL.Path))
+		return
+	}
+	node, err := h.sk.ResolvePath(path)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err)
+		return
+	}
+	def, err := exportRedactedNodeDef(node, nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, def)
+}
+
+// adminHealth is the JSON body of GET /health.
+type adminHealth struct {
+	Status   string `json:"status"`
+	Roots    int    `json:"roots"`
+	Warnings int    `json:"warnings"`
+}
+
+func (h *AdminHandler) health(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, adminHealth{
+		Status:   "ok",
+		Roots:    len(h.sk.Roots()),
+		Warnings: len(h.sk.Warnings()),
+	})
+}
+
+// adminReload is the JSON body of POST /reload.
+type adminReload struct {
+	Changes []string `json:"changes"`
+}
+
+func (h *AdminHandler) reload(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	changes, err := h.sk.Reload()
+	if err != nil {
+		h.sk.Warn1("Reload requested through the admin API failed: %v", err)
+		writeJSONResponse(w, http.StatusInternalServerError, MakeErrorReport(err))
+		return
+	}
+	body := adminReload{Changes: make([]string, len(changes))}
+	for i, c := range changes {
+		body.Changes[i] = c.String()
+	}
+	writeJSONResponse(w, http.StatusOK, body)
+}
+
+// exportRedactedNodeDef exports node like ExportNodeDef but redacts secret
+// values.
+func exportRedactedNodeDef(node Node, parent *NodeDef) (*NodeDef, error) {
+	def, err := exportNodeDef(node, parent)
+	if err != nil {
+		return nil, err
+	}
+	redactNodeDef(node, def)
+	return def, nil
+}
+
+// redactNodeDef redacts the secret values in def, which was exported from
+// node.
+func redactNodeDef(node Node, def *NodeDef) {
+	if _, ok := node.(Value); ok && IsSecret(node) {
+		def.Value = RedactedValue
+	}
+	for i, child := range childNodes(node) {
+		redactNodeDef(child, def.Children[i])
+	}
+}
+
+var (
+	adminServerClassValue = nodeclass{
+		name:        MakeString("AdminServer"),
+		base:        &nodeClassValue,
+		allocator:   allocAdminServerNode,
+		initializer: initAdminServerNode,
+	}
+
+	// AdminServerClass is the Class of AdminServerNodes.
+	AdminServerClass = MustRegisterClassString(
+		"import:nodes#AdminServer", &adminServerClassValue)
+
+	// adminServers are the servers started by AdminServerNodes by their
+	// addresses.
+	adminServers      = make(map[string]*adminServer)
+	adminServersMutex sync.Mutex
+)
+
+// adminServer is an HTTP server of an AdminHandler.
+type adminServer struct {
+	sk     *Skink
+	server *http.Server
+}
+
+// AdminServerNode serves an AdminHandler of the Skink context that starts it
+// on the address in its NodeDef's Value (e.g. "localhost:8081").  Unless the
+// context has an AdminAuthorize hook, the address must be a loopback address
+// (such as "localhost" or "127.0.0.1") so that the admin API isn't exposed
+// to the network without authorization.  The server keeps running when the
+// context is reloaded (see Reload), so only the first AdminServerNode started
+// on an address for a context actually listens on it.
+type AdminServerNode struct {
+	BasicNode
+	addr string
+}
+
+func allocAdminServerNode(nodeDef *NodeDef) (Node, error) {
+	return new(AdminServerNode), nil
+}
+
+func initAdminServerNode(self, parent Node, nodeDef *NodeDef) error {
+	a, ok := self.(*AdminServerNode)
+	if !ok {
+		return errors.Errorf(
+			"AdminServerClass cannot init %T, only *AdminServerNode.", self)
+	}
+	if nodeDef.Value == "" {
+		return errors.Errorf("AdminServer %v has no address", nodeDef.Name)
+	}
+	a.addr = nodeDef.Value
+	return initBasicNode(&a.BasicNode, parent, nodeDef)
+}
+
+// Addr gets the address that the AdminServerNode serves on.
+func (a *AdminServerNode) Addr() string { return a.addr }
+
+// StartNode implements StartNoder.  The address is listened on before
+// StartNode returns so that errors such as the address being in use are
+// returned.
+func (a *AdminServerNode) StartNode(sk *Skink, root Node) error {
+	adminServersMutex.Lock()
+	defer adminServersMutex.Unlock()
+	if running, ok := adminServers[a.addr]; ok {
+		if running.sk == sk {
+			return nil
+		}
+		return errors.Errorf(
+			"another Skink context's admin server is using %v", a.addr)
+	}
+	if sk.AdminAuthorize == nil && !isLoopbackAddr(a.addr) {
+		return errors.Errorf(
+			"admin server address %v is not a loopback address and the "+
+				"Skink context has no AdminAuthorize hook", a.addr)
+	}
+	listener, err := net.Listen("tcp", a.addr)
+	if err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"failed to listen on %v: %v",
+			a.addr, err)
+	}
+	server := &adminServer{
+		sk:     sk,
+		server: &http.Server{Handler: NewAdminHandler(sk)},
+	}
+	adminServers[a.addr] = server
+	go func() {
+		err := server.server.Serve(listener)
+		sk.Warn2("Admin server on %v stopped: %v", a.addr, err)
+		adminServersMutex.Lock()
+		delete(adminServers, a.addr)
+		adminServersMutex.Unlock()
+	}()
+	sk.Info1("Serving the admin API on %v", listener.Addr())
+	return nil
+}
+
+// isLoopbackAddr checks if the host of a "host:port" address is localhost or
+// a loopback IP address.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,287 @@
+package skink
+
+import (
+	"sync"
+
+	"github.com/skillian/errors"
+)
+
+// Event is published to the subscribers of a topic on a Skink context's
+// event bus (see Publish).
+type Event struct {
+	// Topic is the topic the Event was published to.
+	Topic string
+
+	// Source is the Node that published the Event.  It can be nil.
+	Source Node
+
+	// Payload is the Event's data.  It can be nil.
+	Payload Node
+}
+
+// EventHandler handles the Events published to the topics it's subscribed
+// to.
+type EventHandler func(e Event) error
+
+// EventReceiver is implemented by Nodes that receive the Events of their
+// Subscriber children (see SubscriberClass).
+type EventReceiver interface {
+	ReceiveEvent(e Event) error
+}
+
+// subscription is a handler subscribed to a topic pattern.
+type subscription struct {
+	pattern String
+	handler EventHandler
+}
+
+// NodeTopic gets the topic of an event named event that's published by node:
+// the event's name is appended to the node's path, so the "changed" event of
+// "app.db" is published to "app.db.changed".
+func NodeTopic(node Node, event string) string {
+	return GetPath(node) + NodePathSeparator + EscapeNodeName(event)
+}
+
+// Subscribe subscribes handler to the topics that match pattern (see
+// String.MatchGlob), so topics are case-insensitive and "app.db.*" matches
+// every event published by "app.db" with NodeTopic.  The returned function
+// unsubscribes handler.
+func (sk *Skink) Subscribe(pattern string, handler EventHandler) (unsubscribe func()) {
+	sub := &subscription{pattern: MakeString(pattern), handler: handler}
+	sk.mutex.Lock()
+	sk.subscriptions = append(sk.subscriptions, sub)
+	sk.mutex.Unlock()
+	return func() {
+		sk.mutex.Lock()
+		defer sk.mutex.Unlock()
+		for i, s := range sk.subscriptions {
+			if s == sub {
+				sk.subscriptions = append(
+					sk.subscriptions[:i:i], sk.subscriptions[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish publishes an Event to the handlers subscribed to topic in this
+// Skink context.  The handlers are called synchronously, one after the
+// other, by the publishing goroutine.  Their errors (and panics, as
+// PanicErrors) are returned together in a *ConcurrentErrors.
+func (sk *Skink) Publish(topic string, source, payload Node) error {
+	e := Event{Topic: topic, Source: source, Payload: payload}
+	name := MakeString(topic)
+	sk.mutex.RLock()
+	handlers := make([]EventHandler, 0, len(sk.subscriptions))
+	for _, sub := range sk.subscriptions {
+		if ok, err := name.MatchGlob(sub.pattern); err != nil {
+			logger.Warn2(
+				"invalid event subscription pattern %q: %v",
+				sub.pattern, err)
+		} else if ok {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	sk.mutex.RUnlock()
+	logger.Debug2("Publishing %v to %d handlers", topic, len(handlers))
+	ce := NewConcurrentErrors()
+	for _, handler := range handlers {
+		if err := Safely(func() error { return handler(e) }); err != nil {
+			ce.Add(errors.ErrorfWithCause(
+				err,
+				"failed to handle %v event: %v",
+				topic, err))
+		}
+	}
+	if ce.Len() == 0 {
+		return nil
+	}
+	return ce
+}
+
+var (
+	publisherClassValue = nodeclass{
+		name:        MakeString("Publisher"),
+		base:        &nodeClassValue,
+		allocator:   allocPublisherNode,
+		initializer: initPublisherNode,
+	}
+
+	// PublisherClass is the Class of PublisherNodes.
+	PublisherClass = MustRegisterClassString(
+		"import:nodes#Publisher", &publisherClassValue)
+
+	subscriberClassValue = nodeclass{
+		name:        MakeString("Subscriber"),
+		base:        &nodeClassValue,
+		allocator:   allocSubscriberNode,
+		initializer: initSubscriberNode,
+	}
+
+	// SubscriberClass is the Class of SubscriberNodes.
+	SubscriberClass = MustRegisterClassString(
+		"import:nodes#Subscriber", &subscriberClassValue)
+)
+
+// PublisherNode publishes Events for its parent Node once it's started.  Its
+// topic is its NodeDef's Value or, if that's empty, the NodeTopic of its
+// parent and its own name.  A PublisherNode is also a Caller that publishes
+// its first argument as the payload and returns it.
+type PublisherNode struct {
+	BasicNode
+	topic string
+
+	mutex sync.RWMutex
+	sk    *Skink
+}
+
+func allocPublisherNode(nodeDef *NodeDef) (Node, error) {
+	return new(PublisherNode), nil
+}
+
+func initPublisherNode(self, parent Node, nodeDef *NodeDef) error {
+	p, ok := self.(*PublisherNode)
+	if !ok {
+		return errors.Errorf(
+			"PublisherClass cannot init %T, only *PublisherNode.", self)
+	}
+	if err := initBasicNode(&p.BasicNode, parent, nodeDef); err != nil {
+		return err
+	}
+	p.topic = nodeDef.Value
+	if p.topic == "" && parent != nil {
+		p.topic = NodeTopic(parent, nodeDef.Name.String())
+	}
+	return nil
+}
+
+// Topic gets the topic that the PublisherNode publishes to.
+func (p *PublisherNode) Topic() string { return p.topic }
+
+// StartNode implements StartNoder.
+func (p *PublisherNode) StartNode(sk *Skink, root Node) error {
+	p.mutex.Lock()
+	p.sk = sk
+	p.mutex.Unlock()
+	return nil
+}
+
+// Publish publishes an Event with the given payload from the PublisherNode's
+// parent.
+func (p *PublisherNode) Publish(payload Node) error {
+	p.mutex.RLock()
+	sk := p.sk
+	p.mutex.RUnlock()
+	if sk == nil {
+		return errors.Errorf("Publisher %v is not started", GetPath(p))
+	}
+	return sk.Publish(p.topic, p.Parent(), payload)
+}
+
+// Call implements Caller.
+func (p *PublisherNode) Call(args NodeMap) (Node, error) {
+	var payload Node
+	if args != nil && args.Len() > 0 {
+		payload, _ = args.GetIndex(0)
+	}
+	if err := p.Publish(UnwrapArg(payload)); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// SubscriberNode subscribes to the topics matching its NodeDef's Value (see
+// Subscribe) when it's started and passes the Events to its parent, if it's
+// an EventReceiver, and to the handlers added with OnEvent.  It unsubscribes
+// when it's stopped (or started again).
+type SubscriberNode struct {
+	BasicNode
+	pattern string
+
+	mutex       sync.RWMutex
+	handlers    []*EventHandler
+	unsubscribe func()
+}
+
+func allocSubscriberNode(nodeDef *NodeDef) (Node, error) {
+	return new(SubscriberNode), nil
+}
+
+func initSubscriberNode(self, parent Node, nodeDef *NodeDef) error {
+	s, ok := self.(*SubscriberNode)
+	if !ok {
+		return errors.Errorf(
+			"SubscriberClass cannot init %T, only *SubscriberNode.", self)
+	}
+	if nodeDef.Value == "" {
+		return errors.Errorf(
+			"Subscriber %v has no topic pattern", nodeDef.Name)
+	}
+	s.pattern = nodeDef.Value
+	return initBasicNode(&s.BasicNode, parent, nodeDef)
+}
+
+// Pattern gets the SubscriberNode's topic pattern.
+func (s *SubscriberNode) Pattern() string { return s.pattern }
+
+// StartNode implements StartNoder.
+func (s *SubscriberNode) StartNode(sk *Skink, root Node) error {
+	unsubscribe := sk.Subscribe(s.pattern, s.receive)
+	s.mutex.Lock()
+	previous := s.unsubscribe
+	s.unsubscribe = unsubscribe
+	s.mutex.Unlock()
+	if previous != nil {
+		previous()
+	}
+	return nil
+}
+
+// StopNode implements StopNoder.
+func (s *SubscriberNode) StopNode(sk *Skink) error {
+	s.mutex.Lock()
+	unsubscribe := s.unsubscribe
+	s.unsubscribe = nil
+	s.mutex.Unlock()
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+	return nil
+}
+
+// OnEvent adds a handler of the SubscriberNode's Events.  The returned
+// function removes it.
+func (s *SubscriberNode) OnEvent(handler EventHandler) (remove func()) {
+	p := &handler
+	s.mutex.Lock()
+	s.handlers = append(s.handlers, p)
+	s.mutex.Unlock()
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for i, h := range s.handlers {
+			if h == p {
+				s.handlers = append(s.handlers[:i:i], s.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (s *SubscriberNode) receive(e Event) error {
+	if r, ok := s.Parent().(EventReceiver); ok {
+		if err := r.ReceiveEvent(e); err != nil {
+			return err
+		}
+	}
+	s.mutex.RLock()
+	handlers := make([]*EventHandler, len(s.handlers))
+	copy(handlers, s.handlers)
+	s.mutex.RUnlock()
+	for _, h := range handlers {
+		if err := (*h)(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package skink
+
+import (
+	"sync"
+)
+
+// PathCache caches the results of GetChildByPath lookups from a root Node.
+// Paths are cached exactly as they're given, so paths that only differ in
+// case are cached separately.  The cache watches the NodeMaps along each
+// cached path (see NodeMap.OnAdd and NodeMap.OnRemove) and is flushed when
+// any of them changes.  Mutations of NodeMaps that don't call their hooks
+// aren't detected; call Invalidate after making them.
+type PathCache struct {
+	root Node
+
+	mutex sync.RWMutex
+	nodes map[string]Node
+
+	// unwatch removes the hooks registered on the NodeMaps of the Nodes
+	// along the cached paths by their Nodes.
+	unwatch map[Node]func()
+}
+
+// NewPathCache creates a PathCache of lookups from root.
+func NewPathCache(root Node) *PathCache {
+	return &PathCache{
+		root:    root,
+		nodes:   make(map[string]Node, DefaultNodeMapCapacity),
+		unwatch: make(map[Node]func(), DefaultNodeMapCapacity),
+	}
+}
+
+// Root gets the Node that the cached paths are relative to.
+func (c *PathCache) Root() Node {
+	return c.root
+}
+
+// GetChildByPath gets the child of the cache's root at the given path.  See
+// the GetChildByPath function for the path syntax.  Failed lookups are not
+// cached.
+func (c *PathCache) GetChildByPath(path string) (Node, error) {
+	c.mutex.RLock()
+	node, ok := c.nodes[path]
+	c.mutex.RUnlock()
+	if ok {
+		return node, nil
+	}
+	node, err := GetChildByPath(c.root, path)
+	if err != nil {
+		return nil, err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for parent := node.Parent(); parent != nil; parent = parent.Parent() {
+		c.watch(parent)
+		if parent == c.root {
+			break
+		}
+	}
+	c.nodes[path] = node
+	return node, nil
+}
+
+// watch registers hooks that invalidate the cache on node's NodeMap if they
+// aren't registered yet.  c.mutex must be locked.
+func (c *PathCache) watch(node Node) {
+	if _, ok := c.unwatch[node]; ok {
+		return
+	}
+	children := node.Children()
+	if children == nil {
+		return
+	}
+	invalidate := func(NodeMap, Node) { c.Invalidate() }
+	removeAdd := children.OnAdd(invalidate)
+	removeRemove := children.OnRemove(invalidate)
+	c.unwatch[node] = func() {
+		removeAdd()
+		removeRemove()
+	}
+}
+
+// Invalidate flushes the cache.
+func (c *PathCache) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, unwatch := range c.unwatch {
+		unwatch()
+	}
+	c.nodes = make(map[string]Node, DefaultNodeMapCapacity)
+	c.unwatch = make(map[Node]func(), DefaultNodeMapCapacity)
+}
+
+// pathIndex maps the full paths of the Nodes under roots to the Nodes.  It's
+// built the first time a root is looked up and then kept up to date through
+// the hooks of the indexed NodeMaps (see NodeMap.OnAdd and NodeMap.OnRemove).
+// Only the children of NodeMaps keyed like NewNodeMap's are indexed (see
+// isDefaultKeyedNodeMap); lookups of other paths fall back to GetChildByPath.
+type pathIndex struct {
+	mutex sync.RWMutex
+	roots map[Node]*rootPathIndex
+}
+
+// rootPathIndex is the index of the Nodes under one root.
+type rootPathIndex struct {
+	// paths are keyed by the paths of the Nodes relative to the root with
+	// each name's key in its NodeMap (see pathIndexKey).
+	paths map[string]Node
+
+	// unwatch removes the hooks registered on the indexed NodeMaps by the
+	// Nodes whose children they are.
+	unwatch map[Node]func()
+}
+
+func newPathIndex() *pathIndex {
+	return &pathIndex{roots: make(map[Node]*rootPathIndex)}
+}
+
+// get looks up a path under root in the index, building root's index if
+// necessary.
+func (x *pathIndex) get(root Node, path string) (Node, bool) {
+	key, ok := pathIndexKey(path)
+	if !ok {
+		return nil, false
+	}
+	x.mutex.RLock()
+	index, ok := x.roots[root]
+	if ok {
+		node, ok := index.paths[key]
+		x.mutex.RUnlock()
+		return node, ok
+	}
+	x.mutex.RUnlock()
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	if index, ok = x.roots[root]; !ok {
+		index = &rootPathIndex{
+			paths:   make(map[string]Node, DefaultNodeMapCapacity),
+			unwatch: make(map[Node]func()),
+		}
+		x.add(index, root, "")
+		x.roots[root] = index
+	}
+	node, ok := index.paths[key]
+	return node, ok
+}
+
+// add indexes node at path and then its descendants.  x.mutex must be
+// locked.
+func (x *pathIndex) add(index *rootPathIndex, node Node, path string) {
+	if path != "" {
+		if _, ok := index.paths[path]; ok {
+			// Keep the first Node, like GetChildByPath.
+			return
+		}
+		index.paths[path] = node
+	}
+	children := node.Children()
+	if children == nil || !isDefaultKeyedNodeMap(children) {
+		return
+	}
+	// The children are gotten before the hooks are registered so that
+	// loading lazy children (see NewLazyNodeMap) doesn't call them.
+	nodes := children.Nodes()
+	removeAdd := children.OnAdd(func(_ NodeMap, child Node) {
+		x.mutex.Lock()
+		defer x.mutex.Unlock()
+		x.add(index, child, joinPathIndexKey(path, child.Name()))
+	})
+	removeRemove := children.OnRemove(func(_ NodeMap, child Node) {
+		x.mutex.Lock()
+		defer x.mutex.Unlock()
+		x.remove(index, child, joinPathIndexKey(path, child.Name()))
+	})
+	index.unwatch[node] = func() {
+		removeAdd()
+		removeRemove()
+	}
+	for _, child := range nodes {
+		x.add(index, child, joinPathIndexKey(path, child.Name()))
+	}
+}
+
+// remove removes node at path and its descendants from the index.  x.mutex
+// must be locked.
+func (x *pathIndex) remove(index *rootPathIndex, node Node, path string) {
+	if index.paths[path] != node {
+		return
+	}
+	delete(index.paths, path)
+	unwatch, ok := index.unwatch[node]
+	if !ok {
+		return
+	}
+	unwatch()
+	delete(index.unwatch, node)
+	node.Children().Range(func(name String, child Node) bool {
+		x.remove(index, child, joinPathIndexKey(path, name))
+		return true
+	})
+}
+
+// isDefaultKeyedNodeMap checks if m keys its Nodes like NewNodeMap's: by
+// their names' folded values (see String.Lower) without duplicates.  The
+// path index can only key the paths of those Nodes the same way as their
+// NodeMaps.
+func isDefaultKeyedNodeMap(m NodeMap) bool {
+	switch m := m.(type) {
+	case *nodemap:
+		return !m.caseSensitive && !m.allowDuplicates
+	case *sortedNodeMap:
+		return !m.caseSensitive && !m.allowDuplicates
+	case lazyNodeMap:
+		return isDefaultKeyedNodeMap(m.m)
+	case NodeAttrMap:
+		return isDefaultKeyedNodeMap(m.dynamic)
+	}
+	return false
+}
+
+// joinPathIndexKey appends the key of name to the key of a parent path.
+func joinPathIndexKey(parent string, name String) string {
+	key := EscapeNodeName(name.Lower())
+	if parent == "" {
+		return key
+	}
+	return parent + NodePathSeparator + key
+}
+
+// pathIndexKey gets the key of a path in the index.  Relative and invalid
+// paths can't be indexed.
+func pathIndexKey(path string) (string, bool) {
+	names, err := SplitPath(path)
+	if err != nil {
+		return "", false
+	}
+	key := ""
+	for _, name := range names {
+		key = joinPathIndexKey(key, name)
+	}
+	return key, true
+}
+
+// EnablePathIndex makes the Skink context maintain an index of the full paths
+// of the Nodes under the roots passed to GetNodeByPath so that lookups are a
+// single map access instead of a descent through each path segment.  A
+// root's index is built by its first lookup and then kept up to date through
+// the hooks of its NodeMaps.
+func (sk *Skink) EnablePathIndex() {
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	if sk.pathIndex == nil {
+		sk.pathIndex = newPathIndex()
+	}
+}
+
+// GetNodeByPath gets the descendant of root at the given path.  If the path
+// index is enabled (see EnablePathIndex), the index is used, otherwise (or if
+// the path is relative or not in its canonical form) this is the same as
+// GetChildByPath.
+func (sk *Skink) GetNodeByPath(root Node, path string) (Node, error) {
+	sk.mutex.RLock()
+	index := sk.pathIndex
+	sk.mutex.RUnlock()
+	if index != nil {
+		if node, ok := index.get(root, path); ok {
+			return node, nil
+		}
+	}
+	return GetChildByPath(root, path)
+}
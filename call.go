@@ -1,9 +1,322 @@
 package skink
 
+import (
+	"strconv"
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+// Caller is implemented by Nodes that can be invoked like functions.  The
+// arguments are passed as Nodes in a NodeMap and the result is a Node, too.
 type Caller interface {
 	Call(args NodeMap) (Node, error)
 }
 
-func Call(c Caller, args... Node) (Node, error) {
-	
-}
\ No newline at end of file
+// Call calls c with the given argument Nodes.  Arguments made with Arg are
+// passed by their names and the rest are positional arguments named "0",
+// "1", etc. in order.  Callers bind the arguments to their parameters with
+// BindArgs.
+func Call(c Caller, args ...Node) (Node, error) {
+	m := NewNodeMap(len(args))
+	position := 0
+	for _, arg := range args {
+		if !isNamedArg(arg) {
+			arg = newArgNode(MakeString(strconv.Itoa(position)), arg, false)
+			position++
+		}
+		if err := m.AddNode(arg, false); err != nil {
+			return nil, errors.ErrorfWithCause(
+				err,
+				"failed to bind argument %v: %v",
+				arg.Name(), err)
+		}
+	}
+	return c.Call(m)
+}
+
+// Arg names an argument Node for Call.  The Node itself isn't renamed; the
+// Caller gets a Node with the given name that forwards everything else to
+// node (see UnwrapArg).
+func Arg(name string, node Node) Node {
+	return newArgNode(MakeString(name), node, true)
+}
+
+// UnwrapArg gets the Node that was passed to Call (or Arg) from the
+// argument Node that a Caller gets.  Other Nodes are returned as-is.
+func UnwrapArg(node Node) Node {
+	switch a := node.(type) {
+	case argNode:
+		return a.Node
+	case argValue:
+		return a.Node
+	}
+	return node
+}
+
+// isNamedArg checks if node was made with Arg.
+func isNamedArg(node Node) bool {
+	switch a := node.(type) {
+	case argNode:
+		return a.named
+	case argValue:
+		return a.named
+	}
+	return false
+}
+
+// argNode renames an argument Node.
+type argNode struct {
+	Node
+	name  String
+	named bool
+}
+
+// argValue is an argNode of a Value.
+type argValue struct {
+	argNode
+}
+
+func newArgNode(name String, node Node, named bool) Node {
+	a := argNode{Node: UnwrapArg(node), name: name, named: named}
+	if _, ok := a.Node.(Value); ok {
+		return argValue{a}
+	}
+	return a
+}
+
+func (a argNode) Name() String { return a.name }
+
+func (a argValue) Value() interface{} { return a.Node.(Value).Value() }
+
+// Param declares a parameter of a Caller for BindArgs.
+type Param struct {
+	Name String
+
+	// Default is the argument bound to the parameter when the caller
+	// doesn't pass one.  If it's nil, the parameter is required.
+	Default Node
+}
+
+// MissingArgumentError is returned by BindArgs when a required parameter
+// isn't bound to an argument.
+type MissingArgumentError struct {
+	// Param is the name of the missing parameter.
+	Param String
+
+	// Path is the path of the Node that was called.
+	Path string
+}
+
+func (e MissingArgumentError) Error() string {
+	return "missing required argument " + e.Param.String() + " of " + e.Path
+}
+
+// ErrorKind implements ErrorKinder.
+func (e MissingArgumentError) ErrorKind() ErrorKind {
+	return ArgumentErrorKind
+}
+
+// BindArgs binds the arguments passed to the callee Node to its parameters:
+// the i-th parameter is bound to the argument with the parameter's name or,
+// if there isn't one, the positional argument named strconv.Itoa(i).
+// Parameters without arguments get their Defaults and a
+// MissingArgumentError is returned for the first one without either.  The
+// returned NodeMap has the bound arguments under their parameters' names,
+// followed by the rest of args, which are passed through unchanged.
+func BindArgs(callee Node, params []Param, args NodeMap) (NodeMap, error) {
+	bound := NewNodeMap(len(params))
+	used := make(map[string]bool, len(params))
+	for i, param := range params {
+		var arg Node
+		if args != nil {
+			for _, name := range []String{param.Name, MakeString(strconv.Itoa(i))} {
+				if a, err := args.GetName(name); err == nil {
+					arg = a
+					used[name.Lower()] = true
+					break
+				}
+			}
+		}
+		if arg != nil {
+			arg = newArgNode(param.Name, arg, true)
+		} else if param.Default != nil {
+			arg = param.Default
+		} else {
+			return nil, MissingArgumentError{
+				Param: param.Name,
+				Path:  GetPath(callee),
+			}
+		}
+		if err := bound.AddNode(arg, false); err != nil {
+			return nil, errors.ErrorfWithCause(
+				err,
+				"failed to bind parameter %v of %v: %v",
+				param.Name, GetPath(callee), err)
+		}
+	}
+	if args == nil {
+		return bound, nil
+	}
+	var err error
+	args.Range(func(name String, arg Node) bool {
+		if used[name.Lower()] {
+			return true
+		}
+		if err = bound.AddNode(arg, false); err != nil {
+			err = errors.ErrorfWithCause(
+				err,
+				"failed to pass argument %v to %v: %v",
+				name, GetPath(callee), err)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bound, nil
+}
+
+// MethodCaller is implemented by Nodes whose Method child Nodes (see
+// MethodClass) can be called.
+type MethodCaller interface {
+	CallMethod(name String, args NodeMap) (Node, error)
+}
+
+var (
+	methodClassValue = nodeclass{
+		name:        MakeString("Method"),
+		base:        &nodeClassValue,
+		allocator:   allocMethodNode,
+		initializer: initMethodNode,
+	}
+
+	// MethodClass is the Class of MethodNodes, which declare named
+	// operations on their parent Nodes.
+	MethodClass = MustRegisterClassString(
+		"import:nodes#Method", &methodClassValue)
+)
+
+// MethodNode is a Caller that calls the method of its parent Node (which must
+// be a MethodCaller) with the MethodNode's name.  The MethodNode's children
+// declare the method's parameters (see BindArgs): a child whose NodeDef has
+// neither a Value nor children is a required parameter and the rest are the
+// defaults of optional parameters.
+type MethodNode struct {
+	BasicNode
+
+	// required holds whether each parameter is required, by name.
+	required map[string]bool
+}
+
+func allocMethodNode(nodeDef *NodeDef) (Node, error) {
+	return new(MethodNode), nil
+}
+
+func initMethodNode(self, parent Node, nodeDef *NodeDef) error {
+	m, ok := self.(*MethodNode)
+	if !ok {
+		return errors.Errorf(
+			"MethodClass cannot init %T, only *MethodNode.", self)
+	}
+	m.required = make(map[string]bool, len(nodeDef.Children))
+	for _, param := range nodeDef.Children {
+		m.required[param.Name.Lower()] = param.Value == "" &&
+			len(param.Children) == 0
+	}
+	return initBasicNode(&m.BasicNode, parent, nodeDef)
+}
+
+// Params gets the MethodNode's parameters in the order they were declared.
+func (m *MethodNode) Params() []Param {
+	children := m.Children()
+	if children == nil {
+		return nil
+	}
+	params := make([]Param, 0, children.Len())
+	children.Range(func(name String, child Node) bool {
+		param := Param{Name: name}
+		if !m.required[name.Lower()] {
+			param.Default = child
+		}
+		params = append(params, param)
+		return true
+	})
+	return params
+}
+
+// Call implements Caller.  args are bound to the MethodNode's Params.
+func (m *MethodNode) Call(args NodeMap) (Node, error) {
+	target, ok := m.Parent().(MethodCaller)
+	if !ok {
+		return nil, errors.Errorf(
+			"Node %v with method %v is not a MethodCaller (type: %T)",
+			GetPath(m.Parent()), m.Name(), m.Parent())
+	}
+	bound, err := BindArgs(m, m.Params(), args)
+	if err != nil {
+		return nil, err
+	}
+	return target.CallMethod(m.Name(), bound)
+}
+
+// ResolveCaller resolves the Caller at path.  Like the paths interpolated into
+// NodeDef Values (see InterpolateNodeDef), paths that start with a
+// NodePathSeparator are relative to from (see GetChildByPath) and other
+// paths are relative to from's root.
+func ResolveCaller(from Node, path string) (Caller, error) {
+	node, err := resolveNode(from, path)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := node.(Caller)
+	if !ok {
+		return nil, errors.Errorf(
+			"Node %v is not a Caller (type: %T)",
+			GetPath(node), node)
+	}
+	return c, nil
+}
+
+// resolveNode resolves path from from like ResolveCaller.
+func resolveNode(from Node, path string) (Node, error) {
+	base := from
+	if !strings.HasPrefix(path, NodePathSeparator) {
+		for base.Parent() != nil {
+			base = base.Parent()
+		}
+	}
+	node, err := GetChildByPath(base, path)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"failed to resolve %q from %v: %v",
+			path, GetPath(from), err)
+	}
+	return node, nil
+}
+
+// CallPath resolves the Node at path from root (see GetChildByPath) and calls
+// it with args through the Skink context's Interceptors (see Intercept).  The
+// Node must be a Caller (e.g. a MethodNode).
+func (sk *Skink) CallPath(root Node, path string, args ...Node) (Node, error) {
+	node, err := GetChildByPath(root, path)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"failed to resolve %q from %v: %v",
+			path, GetPath(root), err)
+	}
+	c, ok := node.(Caller)
+	if !ok {
+		return nil, errors.Errorf(
+			"Node %v is not a Caller (type: %T)",
+			GetPath(node), node)
+	}
+	result, err := sk.Call(c, args...)
+	if err != nil {
+		return nil, WithNodePath(node, err)
+	}
+	return result, nil
+}
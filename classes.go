@@ -2,6 +2,7 @@ package skink
 
 import (
 	"net/url"
+	"reflect"
 	"strings"
 	"sync"
 
@@ -20,14 +21,89 @@ var (
 	// indirectly reference as a base.
 	NodeClass Class = &nodeClassValue
 
+	sortedNodeClassValue = nodeclass{
+		name:         MakeString("SortedNode"),
+		base:         &nodeClassValue,
+		allocator:    allocBasicNode,
+		initializer:  initBasicNode,
+		nodemapmaker: NewSortedNodeMap,
+	}
+
+	// SortedNodeClass is a Class of Nodes whose children are kept sorted by
+	// name (see NewSortedNodeMap).  Classes derived from it inherit their
+	// sorted children.
+	SortedNodeClass Class = &sortedNodeClassValue
+
+	caseSensitiveNodeClassValue = nodeclass{
+		name:         MakeString("CaseSensitiveNode"),
+		base:         &nodeClassValue,
+		allocator:    allocBasicNode,
+		initializer:  initBasicNode,
+		nodemapmaker: NewCaseSensitiveNodeMap,
+	}
+
+	// CaseSensitiveNodeClass is a Class of Nodes whose children's names are
+	// case-sensitive (see NewCaseSensitiveNodeMap).
+	CaseSensitiveNodeClass Class = &caseSensitiveNodeClassValue
+
+	multiNodeClassValue = nodeclass{
+		name:         MakeString("MultiNode"),
+		base:         &nodeClassValue,
+		allocator:    allocBasicNode,
+		initializer:  initBasicNode,
+		nodemapmaker: NewMultiNodeMap,
+	}
+
+	// MultiNodeClass is a Class of Nodes that can have more than one child
+	// with the same name (see NewMultiNodeMap).
+	MultiNodeClass Class = &multiNodeClassValue
+
+	lazyNodeClassValue = nodeclass{
+		name:        MakeString("LazyNode"),
+		base:        &nodeClassValue,
+		allocator:   allocBasicNode,
+		initializer: initBasicNode,
+	}
+
+	// LazyNodeClass is a Class of Nodes whose children aren't created by
+	// CreateNode until they're first accessed (see NewNodeDefNodeMap).
+	LazyNodeClass Class = &lazyNodeClassValue
+
 	classRegistryMutex = sync.RWMutex{}
 
-	classRegistry map[string]Class
+	// classRegistry is initialized here instead of in init so that Classes
+	// can be registered in other files' package-level var blocks (see
+	// MustRegisterClassString).
+	classRegistry = map[string]Class{}
+
+	// classRegistryExact is keyed by the exact URIs the Classes were
+	// registered under.  It's used instead of classRegistry when Skink is
+	// case-sensitive (see SetCaseSensitive).
+	classRegistryExact = map[string]Class{}
+
+	// classURIRegistry maps registered Classes back to the URIs they were
+	// registered under.  Only comparable Classes can be registered (see
+	// comparableClass) so that they can be its keys.
+	classURIRegistry = map[Class]string{}
 )
 
 func init() {
-	classRegistry = map[string]Class{
-		"import:nodes#node": &nodeClassValue,
+	for key, cls := range map[string]Class{
+		"import:nodes#node":              &nodeClassValue,
+		"import:nodes#sortednode":        &sortedNodeClassValue,
+		"import:nodes#casesensitivenode": &caseSensitiveNodeClassValue,
+		"import:nodes#multinode":         &multiNodeClassValue,
+		"import:nodes#lazynode":          &lazyNodeClassValue,
+	} {
+		classRegistry[key] = cls
+	}
+	classURIRegistry[&nodeClassValue] = "import:nodes#Node"
+	classURIRegistry[&sortedNodeClassValue] = "import:nodes#SortedNode"
+	classURIRegistry[&caseSensitiveNodeClassValue] = "import:nodes#CaseSensitiveNode"
+	classURIRegistry[&multiNodeClassValue] = "import:nodes#MultiNode"
+	classURIRegistry[&lazyNodeClassValue] = "import:nodes#LazyNode"
+	for cls, uri := range classURIRegistry {
+		classRegistryExact[uri] = cls
 	}
 }
 
@@ -46,6 +122,7 @@ func CreateDynamicClass(uri *url.URL) (Class, error) {
 		base:        base,
 		allocator:   base.Alloc,
 		initializer: base.Init,
+		dynamic:     true,
 	}
 	err = RegisterClass(uri, cls)
 	if err != nil {
@@ -82,30 +159,90 @@ func GetBaseClassFromURI(uri *url.URL) Class {
 func GetClassByURI(uri *url.URL) (Class, error) {
 	classRegistryMutex.RLock()
 	defer classRegistryMutex.RUnlock()
-	key := strings.ToLower(uri.String())
-	cls, ok := classRegistry[key]
+	var cls Class
+	var ok bool
+	if CaseSensitive() {
+		cls, ok = classRegistryExact[uri.String()]
+	} else {
+		cls, ok = classRegistry[strings.ToLower(uri.String())]
+	}
 	if !ok {
 		return nil, ClassNotFound{URL: uri}
 	}
 	return cls, nil
 }
 
+// GetClassURI gets the URI that a Class was (first) registered under.  If the
+// Class is not registered, false is returned.
+func GetClassURI(cls Class) (*url.URL, bool) {
+	if !comparableClass(cls) {
+		return nil, false
+	}
+	classRegistryMutex.RLock()
+	uri, ok := classURIRegistry[cls]
+	classRegistryMutex.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, false
+	}
+	return u, true
+}
+
+// RegisteredClasses gets all of the registered Classes keyed by the URI
+// they were (first) registered under.
+func RegisteredClasses() map[string]Class {
+	classRegistryMutex.RLock()
+	defer classRegistryMutex.RUnlock()
+	classes := make(map[string]Class, len(classURIRegistry))
+	for cls, uri := range classURIRegistry {
+		classes[uri] = cls
+	}
+	return classes
+}
+
 // RegisterClass registers a class by its URI in the global class registry.
 func RegisterClass(uri *url.URL, cls Class) error {
 	return RegisterClassString(uri.String(), cls)
 }
 
 // RegisterClassString registers a class with a URI that is already in string
-// form.
+// form.  The Class must be comparable (e.g. a pointer) so that it can be
+// looked up by GetClassURI.
 func RegisterClassString(uri string, cls Class) error {
+	if !comparableClass(cls) {
+		return errors.Errorf(
+			"Class %T registered under URI %v is not comparable", cls, uri)
+	}
 	classRegistryMutex.Lock()
 	defer classRegistryMutex.Unlock()
 	key := strings.ToLower(uri)
-	existing, ok := classRegistry[key]
-	if ok {
-		return errors.Errorf("Class %v is already registered under URI %v", existing, uri)
+	if CaseSensitive() {
+		// URIs that only differ in case are different Classes, so only
+		// the exact URI has to be new.  The first Class registered under
+		// a URI stays the one found case-insensitively.
+		if existing, ok := classRegistryExact[uri]; ok {
+			return errors.Errorf("Class %v is already registered under URI %v", existing, uri)
+		}
+		classRegistryExact[uri] = cls
+		if _, ok := classRegistry[key]; !ok {
+			classRegistry[key] = cls
+		}
+	} else {
+		existing, ok := classRegistry[key]
+		if ok {
+			return errors.Errorf("Class %v is already registered under URI %v", existing, uri)
+		}
+		classRegistry[key] = cls
+		if _, ok := classRegistryExact[uri]; !ok {
+			classRegistryExact[uri] = cls
+		}
+	}
+	if _, ok := classURIRegistry[cls]; !ok {
+		classURIRegistry[cls] = uri
 	}
-	classRegistry[key] = cls
 	logger.Debug2("Registered class %v under URI %v", cls, uri)
 	return nil
 }
@@ -119,11 +256,47 @@ func MustRegisterClassString(uri string, cls Class) Class {
 	return cls
 }
 
+// classURIsEqual checks if two Class URIs refer to the same Class: exactly if
+// Skink is case-sensitive (see SetCaseSensitive) and ignoring case otherwise.
+func classURIsEqual(a, b *url.URL) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if CaseSensitive() {
+		return a.String() == b.String()
+	}
+	return strings.EqualFold(a.String(), b.String())
+}
+
+// comparableClass checks if cls can be compared with == and used as a map
+// key without panicking.
+func comparableClass(cls Class) bool {
+	return cls == nil || reflect.TypeOf(cls).Comparable()
+}
+
+// IsSubclass checks if cls is base or if base is anywhere in cls's chain of
+// base classes.
+func IsSubclass(cls, base Class) bool {
+	for ; cls != nil; cls = cls.Base() {
+		if cls == base {
+			return true
+		}
+	}
+	return false
+}
+
 type nodeclass struct {
 	name        String
 	base        Class
 	allocator   func(nodeDef *NodeDef) (Node, error)
 	initializer func(self, parent Node, nodeDef *NodeDef) error
+
+	// nodemapmaker, if not nil, creates the NodeMaps of the class's Nodes'
+	// children.
+	nodemapmaker func(capacity int) NodeMap
+
+	// dynamic is true for Classes made by CreateDynamicClass.
+	dynamic bool
 }
 
 func (cls *nodeclass) Name() String {
@@ -141,3 +314,31 @@ func (cls *nodeclass) Alloc(nodedef *NodeDef) (Node, error) {
 func (cls *nodeclass) Init(self, parent Node, nodedef *NodeDef) error {
 	return cls.initializer(self, parent, nodedef)
 }
+
+func (cls *nodeclass) MakeNodeMap(capacity int) NodeMap {
+	if cls.nodemapmaker == nil {
+		return nil
+	}
+	return cls.nodemapmaker(capacity)
+}
+
+// NodeMapMaker is implemented by Classes that choose the NodeMap
+// implementation of their Nodes' children.  MakeNodeMap can return nil to
+// defer to the Class's Base.
+type NodeMapMaker interface {
+	MakeNodeMap(capacity int) NodeMap
+}
+
+// MakeChildNodeMap makes a NodeMap for the children of a Node of the given
+// Class with the first NodeMapMaker in the Class's hierarchy that makes one.
+// If none do, NewNodeMap is used.
+func MakeChildNodeMap(cls Class, capacity int) NodeMap {
+	for ; cls != nil; cls = cls.Base() {
+		if maker, ok := cls.(NodeMapMaker); ok {
+			if m := maker.MakeNodeMap(capacity); m != nil {
+				return m
+			}
+		}
+	}
+	return NewNodeMap(capacity)
+}
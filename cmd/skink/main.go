@@ -0,0 +1,332 @@
+// Command skink loads Skink configurations without a Go main of their own:
+//
+//	skink validate [-strict] <uri>
+//	skink dump [-format text|json|yaml] <uri>
+//	skink run <uri>...
+//	skink shell <uri>...
+//	skink profile [-folded] <uri>...
+//	skink gen [-package name] [-o file] (-schema file | -namespace uri)
+//
+// URIs without a scheme are treated as file paths.  Plugins with more Classes
+// (see skinkplugin.Load) are loaded before the command with -plugin,
+// which can be repeated and can be a glob pattern:
+//
+//	skink -plugin 'plugins/*.so' run app.xml
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/skillian/skink"
+	"github.com/skillian/skink/skinkplugin"
+)
+
+// command is one of skink's subcommands.
+type command struct {
+	usage string
+	run   func(sk *skink.Skink, args []string) error
+}
+
+const (
+	validateUsage = "validate [-strict] <uri>"
+	dumpUsage     = "dump [-format text|json|yaml] <uri>"
+	runUsage      = "run <uri>..."
+	shellUsage    = "shell <uri>..."
+	profileUsage  = "profile [-folded] <uri>..."
+	genUsage      = "gen [-package name] [-o file] (-schema file | -namespace uri)"
+)
+
+var commands = map[string]command{
+	"validate": {validateUsage, validate},
+	"dump":     {dumpUsage, dump},
+	"run":      {runUsage, run},
+	"shell":    {shellUsage, shell},
+	"profile":  {profileUsage, profile},
+	"gen":      {genUsage, gen},
+}
+
+// usageError is returned by commands that were called with the wrong
+// arguments.
+type usageError struct {
+	usage string
+}
+
+func (e usageError) Error() string {
+	return "usage: skink " + e.usage
+}
+
+// stringsFlag is a flag that can be repeated.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	var plugins stringsFlag
+	flags := flag.NewFlagSet("skink", flag.ExitOnError)
+	flags.Var(&plugins, "plugin", "load a plugin (or the plugins matching a glob pattern)")
+	flags.Usage = usage
+	flags.Parse(os.Args[1:])
+	if flags.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+	name := flags.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "skink: unknown command %q\n", name)
+		usage()
+		os.Exit(2)
+	}
+	err := loadPlugins(skink.GlobalSkink, plugins)
+	if err == nil {
+		err = cmd.run(skink.GlobalSkink, flags.Args()[1:])
+	}
+	if err != nil {
+		if _, ok := err.(usageError); ok {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "skink:", name, "failed:")
+		skink.MakeErrorReport(err).WriteText(os.Stderr)
+		os.Exit(1)
+	}
+}
+
+// loadPlugins loads the plugins that match the -plugin patterns.
+func loadPlugins(sk *skink.Skink, patterns []string) error {
+	for _, pattern := range patterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no plugins match %v", pattern)
+		}
+		for _, path := range paths {
+			if err = skinkplugin.Load(sk, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "usage:")
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  skink [-plugin path]...", commands[name].usage)
+	}
+}
+
+// parseURI parses a command-line URI.  Arguments without a scheme (or with a
+// single-letter scheme, i.e. a Windows drive) are file paths.
+func parseURI(arg string) (*url.URL, error) {
+	uri, err := url.Parse(arg)
+	if err == nil && len(uri.Scheme) > 1 {
+		return uri, nil
+	}
+	path, err := filepath.Abs(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{Scheme: "file", Path: filepath.ToSlash(path)}, nil
+}
+
+// loadNodeDef loads the effective NodeDef tree of a command's only URI
+// argument.
+func loadNodeDef(sk *skink.Skink, flags *flag.FlagSet, usage string) (*skink.NodeDef, error) {
+	if flags.NArg() != 1 {
+		return nil, usageError{usage}
+	}
+	uri, err := parseURI(flags.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+	return sk.CreateNodeDef(uri)
+}
+
+// printWarnings prints the Warnings that the Skink context collected.
+func printWarnings(sk *skink.Skink) {
+	for _, w := range sk.Warnings() {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+}
+
+// validate loads a URI and runs the validation pass (including the lint
+// rules) over it without creating any Nodes.
+func validate(sk *skink.Skink, args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	strict := flags.Bool("strict", false, "fail if there are any warnings")
+	flags.Parse(args)
+	def, err := loadNodeDef(sk, flags, validateUsage)
+	if err != nil {
+		return err
+	}
+	err = sk.ValidateNodeDef(def)
+	printWarnings(sk)
+	if err != nil {
+		return err
+	}
+	if n := len(sk.Warnings()); *strict && n > 0 {
+		return fmt.Errorf("%d warnings", n)
+	}
+	fmt.Println(flags.Arg(0), "is valid")
+	return nil
+}
+
+// dump prints the effective tree of a URI.  The text format is the created
+// Node tree (see skink.Dump) and the others are the NodeDef tree.  Secret
+// values are redacted in every format (see skink.RedactNodeDef).
+func dump(sk *skink.Skink, args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text, json or yaml")
+	flags.Parse(args)
+	def, err := loadNodeDef(sk, flags, dumpUsage)
+	if err != nil {
+		return err
+	}
+	defer printWarnings(sk)
+	switch *format {
+	case "text":
+		root, err := sk.CreateNode(nil, def)
+		if err != nil {
+			return err
+		}
+		return skink.Dump(os.Stdout, root)
+	case "json":
+		return skink.WriteJSON(os.Stdout, skink.RedactNodeDef(def))
+	case "yaml":
+		return skink.WriteYAML(os.Stdout, skink.RedactNodeDef(def))
+	}
+	return usageError{dumpUsage}
+}
+
+// run loads, initializes and starts the URIs and then waits to be
+// interrupted (see (*skink.Skink).Run).
+func run(sk *skink.Skink, args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	flags.Parse(args)
+	uris, err := uriStrings(flags, runUsage)
+	if err != nil {
+		return err
+	}
+	return sk.Run(uris...)
+}
+
+// shell loads, initializes and starts the URIs and then browses them with a
+// skink.Shell on the terminal.
+func shell(sk *skink.Skink, args []string) error {
+	flags := flag.NewFlagSet("shell", flag.ExitOnError)
+	flags.Parse(args)
+	uris, err := uriStrings(flags, shellUsage)
+	if err != nil {
+		return err
+	}
+	if err = sk.StartURIStrings(uris...); err != nil {
+		return err
+	}
+	printWarnings(sk)
+	return skink.NewShell(sk, os.Stdin, os.Stdout).Run()
+}
+
+// profile loads, initializes and starts the URIs and then prints how long
+// each Node took (see (*skink.Skink).StartupProfile) as a flame-style text
+// report or, with -folded, as folded stacks for flame graph tools.
+func profile(sk *skink.Skink, args []string) error {
+	flags := flag.NewFlagSet("profile", flag.ExitOnError)
+	folded := flags.Bool("folded", false, "write folded stacks instead of the text report")
+	flags.Parse(args)
+	uris, err := uriStrings(flags, profileUsage)
+	if err != nil {
+		return err
+	}
+	err = sk.StartURIStrings(uris...)
+	printWarnings(sk)
+	if err != nil {
+		return err
+	}
+	if *folded {
+		return sk.StartupProfile().WriteFolded(os.Stdout)
+	}
+	return sk.StartupProfile().WriteText(os.Stdout)
+}
+
+// gen generates typed accessors (see skink.GenerateAccessors) from a JSON
+// schema file or from the Classes registered under a namespace, which can
+// come from plugins.  The package name defaults to $GOPACKAGE, which
+// go:generate sets, and otherwise to the schema file's package.  It's meant
+// to be run by go:generate:
+//
+//	//go:generate skink gen -package config -schema config.schema.json -o accessors.go
+func gen(sk *skink.Skink, args []string) error {
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	pkg := flags.String("package", os.Getenv("GOPACKAGE"), "package name of the generated code")
+	out := flags.String("o", "", "output file (default: standard output)")
+	schemaPath := flags.String("schema", "", "JSON accessor schema file")
+	namespace := flags.String("namespace", "", "namespace of the registered Classes")
+	flags.Parse(args)
+	if flags.NArg() != 0 || (*schemaPath == "") == (*namespace == "") ||
+		(*namespace != "" && *pkg == "") {
+		return usageError{genUsage}
+	}
+	var schema *skink.AccessorSchema
+	if *schemaPath != "" {
+		f, err := os.Open(*schemaPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if schema, err = skink.ReadAccessorSchema(f); err != nil {
+			return err
+		}
+		if *pkg != "" {
+			schema.Package = *pkg
+		}
+	} else {
+		schema = skink.ClassAccessorSchema(*pkg, *namespace)
+	}
+	var b bytes.Buffer
+	if err := skink.GenerateAccessors(&b, schema); err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err := os.Stdout.Write(b.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(*out, b.Bytes(), 0644)
+}
+
+// uriStrings parses a command's URI arguments (see parseURI).  At least one
+// is required.
+func uriStrings(flags *flag.FlagSet, usage string) ([]string, error) {
+	if flags.NArg() == 0 {
+		return nil, usageError{usage}
+	}
+	uris := make([]string, flags.NArg())
+	for i, arg := range flags.Args() {
+		uri, err := parseURI(arg)
+		if err != nil {
+			return nil, err
+		}
+		uris[i] = uri.String()
+	}
+	return uris, nil
+}
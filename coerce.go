@@ -0,0 +1,109 @@
+package skink
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/skillian/errors"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Coerce converts value to typ:
+//
+//   - Values are first replaced by their Value() unless the Value itself is
+//     assignable to typ (e.g. typ is Node).
+//   - Values assignable to typ are used as-is and Go's numeric conversions
+//     are allowed between numbers.
+//   - Strings (and Strings) are parsed into numbers, bools and
+//     time.Durations with the strconv and time packages.
+//   - Anything can be coerced into a string with fmt.Sprint.
+func Coerce(value interface{}, typ reflect.Type) (reflect.Value, error) {
+	if v, ok := value.(Value); ok && !reflect.TypeOf(v).AssignableTo(typ) {
+		value = v.Value()
+	}
+	if s, ok := value.(String); ok && typ != reflect.TypeOf(s) {
+		value = s.String()
+	}
+	if value == nil {
+		switch typ.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice,
+			reflect.Func, reflect.Chan:
+			return reflect.Zero(typ), nil
+		}
+		return reflect.Value{}, errors.Errorf("cannot coerce nil to %v", typ)
+	}
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(typ) {
+		out := reflect.New(typ).Elem()
+		out.Set(v)
+		return out, nil
+	}
+	if typ.Kind() == reflect.String {
+		return reflect.ValueOf(fmt.Sprint(value)).Convert(typ), nil
+	}
+	if isNumberKind(v.Kind()) && isNumberKind(typ.Kind()) {
+		return v.Convert(typ), nil
+	}
+	if v.Kind() == reflect.String {
+		out, err := parseCoerce(v.String(), typ)
+		if err != nil {
+			return reflect.Value{}, errors.ErrorfWithCause(
+				err,
+				"cannot coerce %q to %v: %v",
+				v.String(), typ, err)
+		}
+		return out, nil
+	}
+	return reflect.Value{}, errors.Errorf(
+		"cannot coerce %T to %v", value, typ)
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+// parseCoerce parses s into a value of typ.
+func parseCoerce(s string, typ reflect.Type) (reflect.Value, error) {
+	out := reflect.New(typ).Elem()
+	if typ == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out.SetInt(int64(d))
+		return out, nil
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, typ.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 0, typ.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, typ.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out.SetFloat(f)
+	default:
+		return reflect.Value{}, errors.Errorf("unsupported type")
+	}
+	return out, nil
+}
@@ -0,0 +1,124 @@
+package skink
+
+import (
+	"sync"
+
+	"github.com/skillian/errors"
+)
+
+var (
+	connectClassValue = nodeclass{
+		name:        MakeString("Connect"),
+		base:        &nodeClassValue,
+		allocator:   allocConnectNode,
+		initializer: initConnectNode,
+	}
+
+	// ConnectClass is the Class of ConnectNodes.
+	ConnectClass = MustRegisterClassString(
+		"import:nodes#Connect", &connectClassValue)
+
+	connectSourceName = MakeString("source")
+	connectEventName  = MakeString("event")
+	connectTargetName = MakeString("target")
+)
+
+// ConnectNode connects an event to a Caller in configuration: when it's
+// started, it subscribes to the event (see NodeTopic) and calls the target
+// with the payload of every Event published to it.  Its settings are the
+// Values of its children:
+//
+//   - "source" is the path of the Node that publishes the event (e.g. the
+//     parent of a Publisher).
+//   - "event" is the name of the event.
+//   - "target" is the path of the Caller to call, such as a Method.
+//
+// The paths are resolved like ResolveCaller's.  The target is called with
+// the Event's Payload as its only positional argument or with no arguments
+// if there's no Payload.  The ConnectNode unsubscribes when it's stopped (or
+// started again).
+type ConnectNode struct {
+	BasicNode
+	source string
+	event  string
+	target string
+
+	mutex       sync.Mutex
+	unsubscribe func()
+}
+
+func allocConnectNode(nodeDef *NodeDef) (Node, error) {
+	return new(ConnectNode), nil
+}
+
+func initConnectNode(self, parent Node, nodeDef *NodeDef) error {
+	c, ok := self.(*ConnectNode)
+	if !ok {
+		return errors.Errorf(
+			"ConnectClass cannot init %T, only *ConnectNode.", self)
+	}
+	for _, setting := range []struct {
+		name  String
+		value *string
+	}{
+		{connectSourceName, &c.source},
+		{connectEventName, &c.event},
+		{connectTargetName, &c.target},
+	} {
+		child := nodeDef.FindChild(setting.name)
+		if child == nil || child.Value == "" {
+			return errors.Errorf(
+				"Connect %v has no %v", nodeDef.Name, setting.name)
+		}
+		*setting.value = child.Value
+	}
+	return initBasicNode(&c.BasicNode, parent, nodeDef)
+}
+
+// StartNode implements StartNoder.  The source and target are resolved when
+// the ConnectNode is started so that they can be anywhere in the tree.
+func (c *ConnectNode) StartNode(sk *Skink, root Node) error {
+	source, err := resolveNode(c, c.source)
+	if err != nil {
+		return err
+	}
+	target, err := ResolveCaller(c, c.target)
+	if err != nil {
+		return err
+	}
+	topic := NodeTopic(source, c.event)
+	unsubscribe := sk.Subscribe(EscapeGlob(topic), func(e Event) error {
+		var args []Node
+		if e.Payload != nil {
+			args = append(args, e.Payload)
+		}
+		if _, err := sk.Call(target, args...); err != nil {
+			return WithNodePath(c, errors.ErrorfWithCause(
+				err,
+				"failed to call %v: %v",
+				c.target, err))
+		}
+		return nil
+	})
+	c.mutex.Lock()
+	previous := c.unsubscribe
+	c.unsubscribe = unsubscribe
+	c.mutex.Unlock()
+	if previous != nil {
+		previous()
+	}
+	logger.Debug3("Connected %v to %v from %v", topic, c.target, GetPath(c))
+	return nil
+}
+
+// StopNode implements StopNoder.
+func (c *ConnectNode) StopNode(sk *Skink) error {
+	c.mutex.Lock()
+	unsubscribe := c.unsubscribe
+	c.unsubscribe = nil
+	c.mutex.Unlock()
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+	return nil
+}
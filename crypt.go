@@ -0,0 +1,180 @@
+package skink
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+const (
+	// EncryptedFileExt is the extension of encrypted configuration files.
+	// It follows the extension of the document that was encrypted (e.g.
+	// "config.xml.enc").
+	EncryptedFileExt = ".enc"
+
+	// encryptedMagic prefixes every encrypted document so that the wrong
+	// kind of file (or a future format) is recognized before decryption
+	// fails.
+	encryptedMagic = "skink-aesgcm-1\n"
+)
+
+// SetEncryptionKey sets the AES key (which must be 16, 24 or 32 bytes long)
+// used to load and write encrypted documents (see EncryptedFileExt) in this
+// Skink context and its children.
+func (sk *Skink) SetEncryptionKey(key []byte) error {
+	if _, err := aes.NewCipher(key); err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"invalid encryption key: %v",
+			err)
+	}
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	sk.encryptionKey = append([]byte(nil), key...)
+	return nil
+}
+
+// getEncryptionKey gets the encryption key of the nearest Skink context that
+// has one.
+func (sk *Skink) getEncryptionKey() ([]byte, error) {
+	parents := sk.Parents()
+	for ctx, ok := parents(); ok; ctx, ok = parents() {
+		ctx.mutex.RLock()
+		key := ctx.encryptionKey
+		ctx.mutex.RUnlock()
+		if key != nil {
+			return key, nil
+		}
+	}
+	return nil, errors.Errorf(
+		"no encryption key set in Skink context %v", sk.Package)
+}
+
+// Encrypt encrypts plaintext with AES-GCM under key.  The result starts with
+// a header and a random nonce that Decrypt uses.
+func Encrypt(key, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"failed to generate nonce: %v",
+			err)
+	}
+	out := make([]byte, 0, len(encryptedMagic)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, []byte(encryptedMagic)), nil
+}
+
+// Decrypt decrypts a document encrypted with Encrypt.
+func Decrypt(key, ciphertext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(ciphertext, []byte(encryptedMagic)) {
+		return nil, errors.Errorf("not an encrypted Skink document")
+	}
+	ciphertext = ciphertext[len(encryptedMagic):]
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.Errorf("encrypted document is truncated")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(encryptedMagic))
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"failed to decrypt document (wrong key?): %v",
+			err)
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"invalid encryption key: %v",
+			err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// CanLoadEncryptedXMLFile checks if the URI is a file URI of an encrypted XML
+// document (i.e. its path ends with ".xml.enc").
+func CanLoadEncryptedXMLFile(uri *url.URL) bool {
+	return uri.Scheme == "file" &&
+		strings.HasSuffix(strings.ToLower(GetURIPath(uri)), ".xml"+EncryptedFileExt)
+}
+
+// LoadEncryptedXMLFile decrypts an encrypted XML file with the Skink
+// context's encryption key (see SetEncryptionKey) and loads it just like
+// LoadXMLFile.  The plaintext is never written to disk.
+func (sk *Skink) LoadEncryptedXMLFile(uri *url.URL) (*NodeDef, error) {
+	if !CanLoadEncryptedXMLFile(uri) {
+		return nil, errors.Errorf("cannot load URI %v", uri)
+	}
+	key, err := sk.getEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := ioutil.ReadFile(GetURIPath(uri))
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"failed to read file %v: %v",
+			uri.Path, err)
+	}
+	plaintext, err := Decrypt(key, ciphertext)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"failed to load URI %v: %v",
+			uri, err)
+	}
+	nodedef, err := LoadXML(bytes.NewReader(plaintext), XMLOptions{})
+	if err != nil {
+		return nil, wrapLoadError(uri, err)
+	}
+	return nodedef, nil
+}
+
+// WriteEncryptedXMLFile writes a NodeDef tree with WriteXML and encrypts it
+// with the Skink context's encryption key into the file at the URI's path,
+// which must end with ".xml.enc".
+func (sk *Skink) WriteEncryptedXMLFile(uri *url.URL, nodedef *NodeDef) error {
+	if !CanLoadEncryptedXMLFile(uri) {
+		return errors.Errorf("cannot write URI %v", uri)
+	}
+	key, err := sk.getEncryptionKey()
+	if err != nil {
+		return err
+	}
+	plaintext := bytes.Buffer{}
+	if err := WriteXML(&plaintext, nodedef); err != nil {
+		return err
+	}
+	ciphertext, err := Encrypt(key, plaintext.Bytes())
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(GetURIPath(uri), ciphertext, 0600); err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"failed to write file %v: %v",
+			uri.Path, err)
+	}
+	return nil
+}
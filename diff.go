@@ -0,0 +1,243 @@
+package skink
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ChangeKind describes what changed about a Node between two trees.
+type ChangeKind int
+
+const (
+	// NodeAdded means the Node exists only in the new tree.
+	NodeAdded ChangeKind = iota
+
+	// NodeRemoved means the Node exists only in the old tree.
+	NodeRemoved
+
+	// NodeRenamed means a Node was removed from its parent and an identical
+	// Node (same Class, Value and children) was added under the same
+	// parent with a different name.
+	NodeRenamed
+
+	// NodeValueChanged means the Value of a Node that implements the Value
+	// interface is different.
+	NodeValueChanged
+
+	// NodeClassChanged means the Node at the same path is of a different
+	// Class.  Its children are still compared.
+	NodeClassChanged
+)
+
+// String implements fmt.Stringer.
+func (k ChangeKind) String() string {
+	switch k {
+	case NodeAdded:
+		return "Added"
+	case NodeRemoved:
+		return "Removed"
+	case NodeRenamed:
+		return "Renamed"
+	case NodeValueChanged:
+		return "ValueChanged"
+	case NodeClassChanged:
+		return "ClassChanged"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Change is a single difference between two Node trees.
+type Change struct {
+	Kind ChangeKind
+
+	// OldPath is the path to the Node in the old tree, relative to the old
+	// tree's root.  It is empty for NodeAdded changes.
+	OldPath string
+
+	// NewPath is the path to the Node in the new tree, relative to the new
+	// tree's root.  It is empty for NodeRemoved changes.
+	NewPath string
+
+	// Old is the Node from the old tree or nil for NodeAdded changes.
+	Old Node
+
+	// New is the Node from the new tree or nil for NodeRemoved changes.
+	New Node
+}
+
+// String implements fmt.Stringer.
+func (c Change) String() string {
+	switch c.Kind {
+	case NodeAdded:
+		return fmt.Sprintf("%v %s", c.Kind, c.NewPath)
+	case NodeRemoved:
+		return fmt.Sprintf("%v %s", c.Kind, c.OldPath)
+	case NodeRenamed:
+		return fmt.Sprintf("%v %s -> %s", c.Kind, c.OldPath, c.NewPath)
+	}
+	return fmt.Sprintf("%v %s", c.Kind, c.NewPath)
+}
+
+// DiffTrees compares the trees under old and new and returns the changes
+// needed to get from old to new.  Children are matched by their
+// case-insensitive names.  For each pair of matched Nodes, the changes to the
+// Nodes themselves come first, followed by the changes within their matched
+// children, then their removed (or renamed) children and finally their added
+// children.  The names of old and new themselves are not compared.
+func DiffTrees(old, new Node) []Change {
+	d := differ{oldRoot: old, newRoot: new}
+	d.diff(old, new)
+	return d.changes
+}
+
+type differ struct {
+	oldRoot, newRoot Node
+	changes          []Change
+}
+
+// path gets node's path relative to root.  Nodes whose Parents don't lead
+// back to root (e.g. children that don't know their parent) get their full
+// paths instead (see GetPath).
+func (d *differ) path(root, node Node) string {
+	if node == root {
+		return ""
+	}
+	p, err := RelativePath(root, node)
+	if err != nil {
+		logger.Debug2("Using the full path of %v: %v", GetPath(node), err)
+		return GetPath(node)
+	}
+	return p.String()
+}
+
+func (d *differ) add(kind ChangeKind, old, new Node) {
+	c := Change{Kind: kind, Old: old, New: new}
+	if old != nil {
+		c.OldPath = d.path(d.oldRoot, old)
+	}
+	if new != nil {
+		c.NewPath = d.path(d.newRoot, new)
+	}
+	d.changes = append(d.changes, c)
+}
+
+func (d *differ) diff(old, new Node) {
+	if old.Class() != new.Class() {
+		d.add(NodeClassChanged, old, new)
+	}
+	if !valuesEqual(old, new) {
+		d.add(NodeValueChanged, old, new)
+	}
+	oldChildren := childNodes(old)
+	newChildren := childNodes(new)
+	matched := make([]bool, len(newChildren))
+	newIndex := make(map[string]int, len(newChildren))
+	for i, child := range newChildren {
+		newIndex[child.Name().Lower()] = i
+	}
+	removed := make([]Node, 0, len(oldChildren))
+	pairs := make([][2]Node, 0, len(oldChildren))
+	for _, child := range oldChildren {
+		i, ok := newIndex[child.Name().Lower()]
+		if !ok {
+			removed = append(removed, child)
+			continue
+		}
+		matched[i] = true
+		pairs = append(pairs, [2]Node{child, newChildren[i]})
+	}
+	for _, pair := range pairs {
+		d.diff(pair[0], pair[1])
+	}
+	for _, child := range removed {
+		renamed := false
+		for i, candidate := range newChildren {
+			if !matched[i] && treesEqual(child, candidate) {
+				matched[i] = true
+				renamed = true
+				d.add(NodeRenamed, child, candidate)
+				break
+			}
+		}
+		if !renamed {
+			d.add(NodeRemoved, child, nil)
+		}
+	}
+	for i, child := range newChildren {
+		if !matched[i] {
+			d.add(NodeAdded, nil, child)
+		}
+	}
+}
+
+// valuesEqual checks if two Nodes either both do not implement the Value
+// interface or both implement it and their values are equal.
+func valuesEqual(a, b Node) bool {
+	av, aok := a.(Value)
+	bv, bok := b.(Value)
+	if aok != bok {
+		return false
+	}
+	return !aok || reflect.DeepEqual(av.Value(), bv.Value())
+}
+
+// treesEqual checks if the trees under a and b are the same, ignoring the
+// names of a and b themselves.
+func treesEqual(a, b Node) bool {
+	if a.Class() != b.Class() || !valuesEqual(a, b) {
+		return false
+	}
+	ac := childNodes(a)
+	bc := childNodes(b)
+	if len(ac) != len(bc) {
+		return false
+	}
+	for i := range ac {
+		if !ac[i].Name().Equal(bc[i].Name()) || !treesEqual(ac[i], bc[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffNodeDefs compares two NodeDef trees like DiffTrees compares Node trees,
+// without creating Nodes from them.  The Old and New Nodes of the Changes are
+// read-only views of the NodeDefs: their Classes are named by the NodeDefs'
+// ClassURIs (compared as strings) and their values are the NodeDefs'
+// Values.
+func DiffNodeDefs(old, new *NodeDef) []Change {
+	classes := make(map[string]Class)
+	return DiffTrees(
+		newNodeDefView(old, nil, classes),
+		newNodeDefView(new, nil, classes))
+}
+
+// nodeDefView is a read-only Node view of a NodeDef for DiffNodeDefs.
+type nodeDefView struct {
+	BasicNode
+	def *NodeDef
+}
+
+func newNodeDefView(def *NodeDef, parent Node, classes map[string]Class) *nodeDefView {
+	classuri := ""
+	if def.ClassURI != nil {
+		classuri = def.ClassURI.String()
+	}
+	cls, ok := classes[classuri]
+	if !ok {
+		cls = &nodeclass{name: MakeString(classuri), base: &nodeClassValue}
+		classes[classuri] = cls
+	}
+	v := &nodeDefView{def: def}
+	v.NodeClass = cls
+	v.NodeName = def.Name
+	v.NodeParent = parent
+	v.NodeChildren = NewMultiNodeMap(len(def.Children))
+	for _, child := range def.Children {
+		v.NodeChildren.AddNode(newNodeDefView(child, v, classes), false)
+	}
+	return v
+}
+
+// Value implements Value.
+func (v *nodeDefView) Value() interface{} { return v.def.Value }
@@ -0,0 +1,54 @@
+package skink
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Referrer is implemented by Nodes that refer to other Nodes outside of their
+// own children (e.g. references or dependencies).  WriteDOT draws these as
+// dashed edges.
+type Referrer interface {
+	References() []Node
+}
+
+// WriteDOT writes the hierarchy of root and its descendants to w as a
+// Graphviz DOT digraph.  Each Node is labeled with its name and Class name
+// and has a solid edge to each of its children.  Nodes that implement
+// Referrer have dashed edges to the Nodes they refer to if those Nodes are
+// in the same tree.
+func WriteDOT(w io.Writer, root Node) (err error) {
+	bw := bufio.NewWriter(w)
+	defer CatchDeferred(&err, bw.Flush)
+	ids := make(map[Node]string)
+	nodes := make([]Node, 0, DefaultNodeMapCapacity)
+	Walk(root, func(n Node) WalkAction {
+		ids[n] = "n" + strconv.Itoa(len(nodes))
+		nodes = append(nodes, n)
+		return Continue
+	})
+	bw.WriteString("digraph {\n")
+	for _, node := range nodes {
+		label := node.Name().String()
+		if cls := node.Class(); cls != nil {
+			label = fmt.Sprintf("%s\n(%v)", label, cls.Name())
+		}
+		fmt.Fprintf(bw, "\t%s [label=%s];\n", ids[node], strconv.Quote(label))
+	}
+	for _, node := range nodes {
+		for _, child := range childNodes(node) {
+			fmt.Fprintf(bw, "\t%s -> %s;\n", ids[node], ids[child])
+		}
+		if r, ok := node.(Referrer); ok {
+			for _, ref := range r.References() {
+				if id, ok := ids[ref]; ok {
+					fmt.Fprintf(bw, "\t%s -> %s [style=dashed];\n", ids[node], id)
+				}
+			}
+		}
+	}
+	bw.WriteString("}\n")
+	return nil
+}
@@ -0,0 +1,112 @@
+package skink
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Secreter is implemented by Nodes that know whether or not their values are
+// secret, overriding the name-based check of IsSecretName.
+type Secreter interface {
+	Secret() bool
+}
+
+// SecretNameParts are the (lower-case) substrings of Node names that mark a
+// Node's value as secret so that it is redacted by Dump.
+var SecretNameParts = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"apikey",
+	"api_key",
+	"credential",
+	"privatekey",
+	"private_key",
+}
+
+// RedactedValue replaces secret values in Dump's output.
+const RedactedValue = "******"
+
+// IsSecretName checks if a Node's name contains any of the SecretNameParts.
+func IsSecretName(name String) bool {
+	lower := name.Lower()
+	for _, part := range SecretNameParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSecret checks if the value of a Node should be kept secret.  If the Node
+// implements Secreter, its Secret method decides, otherwise IsSecretName
+// does.
+func IsSecret(node Node) bool {
+	if s, ok := node.(Secreter); ok {
+		return s.Secret()
+	}
+	return IsSecretName(node.Name())
+}
+
+// Dump writes an indented tree of root and its descendants to w, one Node per
+// line, with each Node's name, Class name and (for Nodes that implement the
+// Value interface) its value.  Secret values (see IsSecret) are redacted.
+//
+//	app (Node)
+//	├── servers (Node)
+//	│   └── web (Node)
+//	│       ├── listen (String) = ":8080"
+//	│       └── password (String) = ******
+//	└── timeout (String) = "10"
+func Dump(w io.Writer, root Node) (err error) {
+	bw := bufio.NewWriter(w)
+	defer CatchDeferred(&err, bw.Flush)
+	dumpNode(bw, root, "", "")
+	return nil
+}
+
+func dumpNode(w *bufio.Writer, node Node, first, indent string) {
+	w.WriteString(first)
+	w.WriteString(node.Name().String())
+	if cls := node.Class(); cls != nil {
+		fmt.Fprintf(w, " (%v)", cls.Name())
+	}
+	if v, ok := node.(Value); ok {
+		if IsSecret(node) {
+			w.WriteString(" = " + RedactedValue)
+		} else {
+			fmt.Fprintf(w, " = %q", fmt.Sprint(v.Value()))
+		}
+	}
+	w.WriteString("\n")
+	children := childNodes(node)
+	for i, child := range children {
+		if i == len(children)-1 {
+			dumpNode(w, child, indent+"└── ", indent+"    ")
+		} else {
+			dumpNode(w, child, indent+"├── ", indent+"│   ")
+		}
+	}
+}
+
+// RedactNodeDef copies the NodeDef tree with the Values of the NodeDefs whose
+// names are secret (see IsSecretName) replaced with RedactedValue so that it
+// can be written (e.g. with WriteJSON) without its secrets.  There are no
+// Nodes to ask, so Secreters aren't consulted.  def is not modified.
+func RedactNodeDef(def *NodeDef) *NodeDef {
+	def = def.Clone(def.Parent)
+	redactNodeDefNames(def)
+	return def
+}
+
+func redactNodeDefNames(def *NodeDef) {
+	if def.Value != "" && IsSecretName(def.Name) {
+		def.Value = RedactedValue
+	}
+	for _, child := range def.Children {
+		redactNodeDefNames(child)
+	}
+}
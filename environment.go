@@ -0,0 +1,227 @@
+package skink
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+var (
+	// EnvironmentAttrName is the name of the child NodeDef (e.g. the XML
+	// attribute) that tags a subtree as an overlay for an environment.
+	// Only children whose ClassURI is EnvironmentAttrClassURI are tags, so
+	// ordinary children named "environment" are left alone.
+	EnvironmentAttrName = MakeString("environment")
+
+	environmentAttrClassURIValue = url.URL{
+		Scheme:   "import",
+		Opaque:   "nodes",
+		Fragment: "environment",
+	}
+
+	// EnvironmentAttrClassURI is the ClassURI of EnvironmentAttrName tags.
+	// In XML documents, it's an attribute in the import:nodes namespace:
+	//
+	//	<db xmlns:nodes="import:nodes" nodes:environment="prod">
+	EnvironmentAttrClassURI = &environmentAttrClassURIValue
+)
+
+// SetEnvironment sets the environment (e.g. "prod") whose overlays are applied
+// to the NodeDef trees loaded by CreateNodeDef.  An empty environment means
+// no overlays are applied.
+//
+// When a document is loaded from a URI such as file:///etc/app/config.xml, a
+// sibling document for the environment (file:///etc/app/config.prod.xml) is
+// also loaded if it exists and is merged over the base document with
+// MergeDeep.  Any subtree in either document with an EnvironmentAttrName
+// tag is removed from the tree and, if the tag's value is the environment
+// (case-insensitive), its children are merged over the subtree's parent, too.
+// Without an environment, documents are loaded as they are.
+func (sk *Skink) SetEnvironment(env string) {
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	sk.environment = env
+}
+
+// Environment gets the environment set with SetEnvironment.
+func (sk *Skink) Environment() string {
+	sk.mutex.RLock()
+	defer sk.mutex.RUnlock()
+	return sk.environment
+}
+
+// applyEnvironment applies the current environment's tagged subtrees and
+// sibling document to a NodeDef loaded from uri.
+func (sk *Skink) applyEnvironment(uri *url.URL, nodedef *NodeDef) (*NodeDef, error) {
+	env := sk.Environment()
+	if env == "" {
+		return nodedef, nil
+	}
+	nodedef, err := ApplyEnvironmentSubtrees(nodedef, env)
+	if err != nil {
+		return nil, err
+	}
+	siblinguri, ok := EnvironmentSiblingURI(uri, env)
+	if !ok {
+		return nodedef, nil
+	}
+	if siblinguri.Scheme == "file" {
+		if _, err := os.Stat(GetURIPath(siblinguri)); os.IsNotExist(err) {
+			return nodedef, nil
+		}
+	}
+	sibling, err := sk.loadNodeDef(siblinguri)
+	if err != nil {
+		if isNotExist(err) {
+			logger.Debug2(
+				"no %v overlay loaded from %v", env, siblinguri)
+			return nodedef, nil
+		}
+		return nil, errors.ErrorfWithCause(
+			err,
+			"failed to load %v overlay %v: %v",
+			env, siblinguri, err)
+	}
+	if sibling, err = ApplyEnvironmentSubtrees(sibling, env); err != nil {
+		return nil, err
+	}
+	return MergeNodeDefs(nodedef, sibling, MergeDeep)
+}
+
+// isNotExist checks if err or any of its causes means that a document doesn't
+// exist (see os.IsNotExist), e.g. an HTTP 404.
+func isNotExist(err error) bool {
+	if err == nil {
+		return false
+	}
+	if os.IsNotExist(err) {
+		return true
+	}
+	for _, cause := range errorCauses(err) {
+		if isNotExist(cause) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnvironmentSiblingURI gets the URI of the environment-specific sibling of a
+// document by inserting the environment before the extension of the URI's
+// path (e.g. config.xml becomes config.prod.xml).  If the URI has no path,
+// false is returned.
+func EnvironmentSiblingURI(uri *url.URL, env string) (*url.URL, bool) {
+	p := GetURIPath(uri)
+	if p == "" || strings.HasSuffix(p, "/") {
+		return nil, false
+	}
+	ext := path.Ext(p)
+	p = strings.Join([]string{strings.TrimSuffix(p, ext), ".", env, ext}, "")
+	sibling := *uri
+	if sibling.Opaque == "" {
+		sibling.Path = p
+		sibling.RawPath = ""
+	} else {
+		sibling.Opaque = p
+	}
+	return &sibling, true
+}
+
+// ApplyEnvironmentSubtrees removes every subtree tagged with an
+// EnvironmentAttrName (see EnvironmentAttrClassURI) from the NodeDef tree and
+// merges the children of the subtrees tagged with env over their parents.  The
+// tree is not modified; a new tree is returned if any subtrees are found.
+func ApplyEnvironmentSubtrees(nodedef *NodeDef, env string) (*NodeDef, error) {
+	if !hasEnvironmentSubtrees(nodedef) {
+		return nodedef, nil
+	}
+	nodedef = nodedef.Clone(nodedef.Parent)
+	if err := applyEnvironmentSubtrees(nodedef, env); err != nil {
+		return nil, err
+	}
+	return nodedef, nil
+}
+
+func hasEnvironmentSubtrees(nodedef *NodeDef) bool {
+	for _, child := range nodedef.Children {
+		if findEnvironmentAttr(child) != nil || hasEnvironmentSubtrees(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyEnvironmentSubtrees modifies nodedef in place.
+func applyEnvironmentSubtrees(nodedef *NodeDef, env string) error {
+	children := make([]*NodeDef, 0, len(nodedef.Children))
+	overlays := make([]*NodeDef, 0, 1)
+	for _, child := range nodedef.Children {
+		attr := findEnvironmentAttr(child)
+		if attr == nil {
+			children = append(children, child)
+			continue
+		}
+		if env != "" && strings.EqualFold(strings.TrimSpace(attr.Value), env) {
+			overlays = append(overlays, child)
+		}
+	}
+	nodedef.Children = children
+	for _, child := range children {
+		if err := applyEnvironmentSubtrees(child, env); err != nil {
+			return err
+		}
+	}
+	for _, overlay := range overlays {
+		if err := applyEnvironmentSubtrees(overlay, env); err != nil {
+			return err
+		}
+		overlay.Name = nodedef.Name
+		overlay.ClassURI = nodedef.ClassURI
+		overlay.Value = ""
+		overlay.Children = withoutEnvironmentAttrs(overlay.Children)
+		merged, err := MergeNodeDefs(nodedef, overlay, MergeDeep)
+		if err != nil {
+			return errors.ErrorfWithCause(
+				err,
+				"failed to apply %v overlay to %v: %v",
+				env, nodedef.Name, err)
+		}
+		nodedef.Children = merged.Children
+		for _, child := range nodedef.Children {
+			child.Parent = nodedef
+		}
+	}
+	return nil
+}
+
+// isEnvironmentAttr checks if nodedef is an EnvironmentAttrName tag.
+func isEnvironmentAttr(nodedef *NodeDef) bool {
+	return nodedef.Name.Equal(EnvironmentAttrName) &&
+		nodedef.ClassURI != nil &&
+		classURIsEqual(nodedef.ClassURI, EnvironmentAttrClassURI)
+}
+
+// findEnvironmentAttr finds nodedef's EnvironmentAttrName tag or returns nil
+// if it has none.
+func findEnvironmentAttr(nodedef *NodeDef) *NodeDef {
+	for _, child := range nodedef.Children {
+		if isEnvironmentAttr(child) {
+			return child
+		}
+	}
+	return nil
+}
+
+// withoutEnvironmentAttrs gets the NodeDefs that are not EnvironmentAttrName
+// tags.
+func withoutEnvironmentAttrs(nodedefs []*NodeDef) []*NodeDef {
+	filtered := make([]*NodeDef, 0, len(nodedefs))
+	for _, nodedef := range nodedefs {
+		if !isEnvironmentAttr(nodedef) {
+			filtered = append(filtered, nodedef)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,65 @@
+package skink
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const environmentTestXML = `<app xmlns:nodes="import:nodes">
+	<service><environment>prod</environment><port>80</port></service>
+	<db><host>localhost</host></db>
+	<prod nodes:environment="prod"><db><host>db.prod</host></db></prod>
+</app>`
+
+func loadEnvironmentTestXML(t *testing.T, env string) *NodeDef {
+	t.Helper()
+	sk, err := GlobalSkink.CreateChild("environmenttest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sk.TempDir)
+	sk.SetEnvironment(env)
+	path := filepath.Join(sk.TempDir, "app.xml")
+	if err := ioutil.WriteFile(path, []byte(environmentTestXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	def, err := sk.CreateNodeDef(&url.URL{Scheme: "file", Path: path})
+	if err != nil {
+		t.Fatalf("failed to load %v: %v", path, err)
+	}
+	return def
+}
+
+func TestEnvironmentValueChildIsKept(t *testing.T) {
+	for _, env := range []string{"", "prod", "dev"} {
+		def := loadEnvironmentTestXML(t, env)
+		service := def.FindChild(MakeString("service"))
+		if service == nil {
+			t.Errorf("environment %q: service was removed", env)
+			continue
+		}
+		child := service.FindChild(EnvironmentAttrName)
+		if child == nil || child.Value != "prod" {
+			t.Errorf("environment %q: service's environment = %v, want prod",
+				env, child)
+		}
+	}
+}
+
+func TestEnvironmentOverlay(t *testing.T) {
+	def := loadEnvironmentTestXML(t, "prod")
+	db := def.FindChild(MakeString("db"))
+	if db == nil {
+		t.Fatal("db was removed")
+	}
+	host := db.FindChild(MakeString("host"))
+	if host == nil || host.Value != "db.prod" {
+		t.Errorf("db.host = %v, want db.prod", host)
+	}
+	if def.FindChild(MakeString("prod")) != nil {
+		t.Error("the prod overlay was kept")
+	}
+}
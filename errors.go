@@ -2,12 +2,146 @@ package skink
 
 import (
 	"fmt"
+	"io"
 	"net/url"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/skillian/errors"
 )
 
+// ErrorKind categorizes the errors returned by Skink so that callers can
+// handle them without matching their messages.  See ErrorKindOf.
+type ErrorKind int
+
+const (
+	// UnknownErrorKind is the ErrorKind of errors that weren't categorized.
+	UnknownErrorKind ErrorKind = iota
+
+	// LoadErrorKind errors are returned when a NodeDef can't be loaded.
+	LoadErrorKind
+
+	// ClassErrorKind errors are returned when a Node's Class can't be
+	// found or created.
+	ClassErrorKind
+
+	// InitErrorKind errors are returned when a Node can't be initialized.
+	InitErrorKind
+
+	// StartErrorKind errors are returned when a Node can't be started.
+	StartErrorKind
+
+	// ValidationErrorKind errors are returned when a NodeDef is invalid.
+	ValidationErrorKind
+
+	// ArgumentErrorKind errors are returned when the arguments of a call
+	// can't be bound to the Caller's parameters.
+	ArgumentErrorKind
+)
+
+var errorKindNames = [...]string{
+	UnknownErrorKind:    "UnknownError",
+	LoadErrorKind:       "LoadError",
+	ClassErrorKind:      "ClassError",
+	InitErrorKind:       "InitError",
+	StartErrorKind:      "StartError",
+	ValidationErrorKind: "ValidationError",
+	ArgumentErrorKind:   "ArgumentError",
+}
+
+// String implements fmt.Stringer.
+func (k ErrorKind) String() string {
+	if k >= 0 && int(k) < len(errorKindNames) {
+		return errorKindNames[k]
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// ErrorKinder is implemented by errors that know their own ErrorKind.
+type ErrorKinder interface {
+	ErrorKind() ErrorKind
+}
+
+// KindError attaches an ErrorKind to an error.
+type KindError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+// WithErrorKind attaches kind to err.  A nil error stays nil.
+func WithErrorKind(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return KindError{Kind: kind, Err: err}
+}
+
+// Error implements the error interface.
+func (e KindError) Error() string {
+	return e.Err.Error()
+}
+
+// ErrorKind implements ErrorKinder.
+func (e KindError) ErrorKind() ErrorKind {
+	return e.Kind
+}
+
+// Unwrap gets the error that the ErrorKind was attached to.
+func (e KindError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorKindOf gets the ErrorKind of err.  If err doesn't have one itself, its
+// causes are searched (depth-first) and the first ErrorKind found is
+// returned.  If none of them have one, UnknownErrorKind is returned.
+func ErrorKindOf(err error) ErrorKind {
+	if err == nil {
+		return UnknownErrorKind
+	}
+	if kinder, ok := err.(ErrorKinder); ok {
+		return kinder.ErrorKind()
+	}
+	for _, cause := range errorCauses(err) {
+		if kind := ErrorKindOf(cause); kind != UnknownErrorKind {
+			return kind
+		}
+	}
+	return UnknownErrorKind
+}
+
+// IsErrorKind checks if err or any of its causes has the given ErrorKind.
+func IsErrorKind(err error, kind ErrorKind) bool {
+	if err == nil {
+		return false
+	}
+	if kinder, ok := err.(ErrorKinder); ok && kinder.ErrorKind() == kind {
+		return true
+	}
+	for _, cause := range errorCauses(err) {
+		if IsErrorKind(cause, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorCauses gets the errors that err wraps.
+func errorCauses(err error) []error {
+	switch err := err.(type) {
+	case errors.Error:
+		return []error{err.Err, err.Cause, err.Context}
+	case *errors.Error:
+		return []error{err.Err, err.Cause, err.Context}
+	case interface{ Unwrap() []error }:
+		return err.Unwrap()
+	case interface{ Unwrap() error }:
+		return []error{err.Unwrap()}
+	}
+	return nil
+}
+
 // ClassNotFound is returned when an attempt is made to find a class but there
 // is no such class registered
 type ClassNotFound struct {
@@ -19,6 +153,132 @@ func (err ClassNotFound) Error() string {
 	return fmt.Sprintf("Class %v not found", err.URL)
 }
 
+// ErrorKind implements ErrorKinder.
+func (err ClassNotFound) ErrorKind() ErrorKind {
+	return ClassErrorKind
+}
+
+// LoadError is returned when a NodeDef tree can't be loaded.  If the loader
+// knows where in its source the problem is, the position is set, too.
+type LoadError struct {
+	// URI is the URI that couldn't be loaded.  It's nil if the NodeDef
+	// tree wasn't loaded from a URI (e.g. by LoadXML).
+	URI *url.URL
+
+	// Offset is the byte offset of the problem in the source or -1 if it
+	// isn't known.
+	Offset int64
+
+	// Line and Column are the 1-based line and (byte) column of the
+	// problem in the source or 0 if they aren't known.
+	Line   int
+	Column int
+
+	// Err is the error that caused the load to fail.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *LoadError) Error() string {
+	source := "NodeDef"
+	if e.URI != nil {
+		source = "URI " + e.URI.String()
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf(
+			"failed to load %s at line %d, column %d: %v",
+			source, e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("failed to load %s: %v", source, e.Err)
+}
+
+// ErrorKind implements ErrorKinder.
+func (e *LoadError) ErrorKind() ErrorKind {
+	return LoadErrorKind
+}
+
+// Unwrap gets the error that caused the load to fail.
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// wrapLoadError wraps err in a LoadError of uri.  If err is already a
+// LoadError, it's returned instead, with uri as its URI if it had none, so
+// that LoadErrors aren't nested.  If err was caused by another LoadError,
+// its position is kept.
+func wrapLoadError(uri *url.URL, err error) *LoadError {
+	if le, ok := err.(*LoadError); ok {
+		if le.URI == nil {
+			le.URI = uri
+		}
+		return le
+	}
+	le := &LoadError{URI: uri, Offset: -1, Err: err}
+	if cause := findLoadError(err); cause != nil {
+		le.Offset, le.Line, le.Column = cause.Offset, cause.Line, cause.Column
+	}
+	return le
+}
+
+// findLoadError finds the first LoadError with a known position in err or
+// its causes.
+func findLoadError(err error) *LoadError {
+	if err == nil {
+		return nil
+	}
+	if le, ok := err.(*LoadError); ok && le.Line > 0 {
+		return le
+	}
+	for _, cause := range errorCauses(err) {
+		if le := findLoadError(cause); le != nil {
+			return le
+		}
+	}
+	return nil
+}
+
+// positionReader keeps track of where the lines start in what's read through
+// it so that a loader can convert an offset into a line and column.
+type positionReader struct {
+	r        io.Reader
+	offset   int64
+	newlines []int64
+}
+
+func newPositionReader(r io.Reader) *positionReader {
+	return &positionReader{r: r}
+}
+
+func (p *positionReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	for i, c := range b[:n] {
+		if c == '\n' {
+			p.newlines = append(p.newlines, p.offset+int64(i))
+		}
+	}
+	p.offset += int64(n)
+	return n, err
+}
+
+// position gets the 1-based line and column of offset.
+func (p *positionReader) position(offset int64) (line, column int) {
+	i := sort.Search(len(p.newlines), func(i int) bool {
+		return p.newlines[i] >= offset
+	})
+	start := int64(0)
+	if i > 0 {
+		start = p.newlines[i-1] + 1
+	}
+	return i + 1, int(offset-start) + 1
+}
+
+// loadError makes a LoadError of err at offset.
+func (p *positionReader) loadError(offset int64, err error) *LoadError {
+	le := &LoadError{Offset: offset, Err: err}
+	le.Line, le.Column = p.position(offset)
+	return le
+}
+
 // NodeNotFound errors are returned when a requested node cannot be found.
 type NodeNotFound struct {
 	// Parent is the node under which another node was sought.  If the parent
@@ -55,6 +315,78 @@ func (n NodeNotFound) Error() string {
 	return fmt.Sprintf("Node %s not found%s", n.Name, extra)
 }
 
+// NodeError attaches the path of the Node (or NodeDef) that an error is about
+// to the error.
+type NodeError struct {
+	Path string
+	Err  error
+}
+
+// Error implements the error interface.
+func (e NodeError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap gets the error about the Node.
+func (e NodeError) Unwrap() error {
+	return e.Err
+}
+
+// WithNodePath annotates err with the path of node (see GetPath) so that
+// errors bubbling up out of a tree keep track of which Node they came from.
+// If err already has a path (see NodePathOf), it's returned as-is so that
+// the innermost, most specific path is kept.  A nil error stays nil.
+func WithNodePath(node Node, err error) error {
+	if err == nil || node == nil {
+		return err
+	}
+	if _, ok := NodePathOf(err); ok {
+		return err
+	}
+	return NodeError{Path: GetPath(node), Err: err}
+}
+
+// WithNodeDefPath is like WithNodePath but for errors about NodeDefs.
+func WithNodeDefPath(nodedef *NodeDef, err error) error {
+	if err == nil || nodedef == nil {
+		return err
+	}
+	if _, ok := NodePathOf(err); ok {
+		return err
+	}
+	return NodeError{Path: nodeDefPath(nodedef), Err: err}
+}
+
+// NodePathOf gets the path of the Node that err (or the first of its causes
+// that's a NodeError) is about.  If there is none, false is returned.
+func NodePathOf(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+	if ne, ok := err.(NodeError); ok {
+		return ne.Path, true
+	}
+	for _, cause := range errorCauses(err) {
+		if path, ok := NodePathOf(cause); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// CycleError is returned when Nodes (or NodeDefs) refer to each other in a
+// cycle.
+type CycleError struct {
+	// Paths are the paths of the Nodes in the cycle.  The first and last
+	// paths are the same.
+	Paths []string
+}
+
+// Error implements the error interface.
+func (e CycleError) Error() string {
+	return "cycle: " + strings.Join(e.Paths, " -> ")
+}
+
 // IndexError is just like in Python, describing an index out of range.
 type IndexError struct {
 	Index  int
@@ -68,6 +400,18 @@ func (e IndexError) Error() string {
 		e.Index, e.Length)
 }
 
+// ReadOnlyError is returned by the mutating methods of read-only NodeMaps
+// (see ReadOnly).
+type ReadOnlyError struct {
+	// Op is the name of the NodeMap method that was called.
+	Op string
+}
+
+// Error implements the error interface.
+func (e ReadOnlyError) Error() string {
+	return fmt.Sprintf("cannot %s: NodeMap is read-only", e.Op)
+}
+
 // CatchDeferred ensures that an error returned by a deferred function is not
 // discarded.  errptr should be a pointer to a named return value.
 func CatchDeferred(errptr *error, errorers ...func() error) {
@@ -87,8 +431,9 @@ func CatchDeferred(errptr *error, errorers ...func() error) {
 }
 
 // ConcurrentErrors holds a collection of errors from concurrently executed
-// functions.
+// functions.  It's safe to Add errors to it from multiple goroutines.
 type ConcurrentErrors struct {
+	mutex  sync.Mutex
 	errors []error
 }
 
@@ -99,9 +444,10 @@ func NewConcurrentErrors() *ConcurrentErrors {
 
 // Error concatenates the errors all together into a single error string
 func (ce *ConcurrentErrors) Error() string {
-	errors := make([]string, len(ce.errors)+1)
-	errors[0] = fmt.Sprintf("%d errors occurred:", len(ce.errors))
-	for i, err := range ce.errors {
+	errs := ce.Errors()
+	errors := make([]string, len(errs)+1)
+	errors[0] = fmt.Sprintf("%d errors occurred:", len(errs))
+	for i, err := range errs {
 		errors[i+1] = fmt.Sprintf("%3d:\t%s", i+1, err.Error())
 	}
 	return strings.Join(errors, "\n\t")
@@ -109,15 +455,131 @@ func (ce *ConcurrentErrors) Error() string {
 
 // Add an error to the collection of concurrent errors.
 func (ce *ConcurrentErrors) Add(errs ...error) {
+	ce.mutex.Lock()
 	ce.errors = append(ce.errors, errs...)
+	ce.mutex.Unlock()
+}
+
+// Errors gets a copy of the bundled errors in the order they were added.
+func (ce *ConcurrentErrors) Errors() []error {
+	ce.mutex.Lock()
+	defer ce.mutex.Unlock()
+	errs := make([]error, len(ce.errors))
+	copy(errs, ce.errors)
+	return errs
+}
+
+// Unwrap gets the bundled errors so that errors.Is and errors.As from the
+// standard library check each of them.
+func (ce *ConcurrentErrors) Unwrap() []error {
+	return ce.Errors()
 }
 
 // Len gets the length of the ConcurrentErrors slice (that is, the number of
 // bundled concurrent errors).
 func (ce *ConcurrentErrors) Len() int {
+	ce.mutex.Lock()
+	defer ce.mutex.Unlock()
 	return len(ce.errors)
 }
 
+// ValidationErrors maps the paths of misconfigured Nodes (or NodeDefs) to
+// their problems so that every problem in a tree can be reported at once.
+// It's safe to Add errors to it from multiple goroutines.
+type ValidationErrors struct {
+	mutex  sync.Mutex
+	paths  []string
+	errors map[string][]error
+	count  int
+}
+
+// NewValidationErrors creates an empty ValidationErrors.
+func NewValidationErrors() *ValidationErrors {
+	return &ValidationErrors{errors: make(map[string][]error)}
+}
+
+// Add errors about the Node at path.
+func (ve *ValidationErrors) Add(path string, errs ...error) {
+	if len(errs) == 0 {
+		return
+	}
+	ve.mutex.Lock()
+	defer ve.mutex.Unlock()
+	if _, ok := ve.errors[path]; !ok {
+		ve.paths = append(ve.paths, path)
+	}
+	ve.errors[path] = append(ve.errors[path], errs...)
+	ve.count += len(errs)
+}
+
+// Paths gets the paths of the Nodes that have errors in the order their
+// first errors were added.
+func (ve *ValidationErrors) Paths() []string {
+	ve.mutex.Lock()
+	defer ve.mutex.Unlock()
+	paths := make([]string, len(ve.paths))
+	copy(paths, ve.paths)
+	return paths
+}
+
+// Get gets the errors about the Node at path.
+func (ve *ValidationErrors) Get(path string) []error {
+	ve.mutex.Lock()
+	defer ve.mutex.Unlock()
+	errs := make([]error, len(ve.errors[path]))
+	copy(errs, ve.errors[path])
+	return errs
+}
+
+// Map gets a copy of all of the errors keyed by the paths of the Nodes
+// they're about.
+func (ve *ValidationErrors) Map() map[string][]error {
+	ve.mutex.Lock()
+	defer ve.mutex.Unlock()
+	m := make(map[string][]error, len(ve.errors))
+	for path, errs := range ve.errors {
+		m[path] = append([]error(nil), errs...)
+	}
+	return m
+}
+
+// Len gets the total number of errors (not paths).
+func (ve *ValidationErrors) Len() int {
+	ve.mutex.Lock()
+	defer ve.mutex.Unlock()
+	return ve.count
+}
+
+// Unwrap gets all of the errors as NodeErrors so that errors.Is and errors.As
+// from the standard library check each of them.
+func (ve *ValidationErrors) Unwrap() []error {
+	ve.mutex.Lock()
+	defer ve.mutex.Unlock()
+	errs := make([]error, 0, ve.count)
+	for _, path := range ve.paths {
+		for _, err := range ve.errors[path] {
+			errs = append(errs, NodeError{Path: path, Err: err})
+		}
+	}
+	return errs
+}
+
+// Error lists the errors grouped by the paths of the Nodes they're about.
+func (ve *ValidationErrors) Error() string {
+	errs := ve.Unwrap()
+	lines := make([]string, len(errs)+1)
+	lines[0] = fmt.Sprintf("%d errors occurred:", len(errs))
+	for i, err := range errs {
+		lines[i+1] = fmt.Sprintf("%3d:\t%s", i+1, err.Error())
+	}
+	return strings.Join(lines, "\n\t")
+}
+
+// Report makes an ErrorReport of the errors.
+func (ve *ValidationErrors) Report() ErrorReport {
+	return MakeErrorReport(ve)
+}
+
 // PanicOnError is for initialization functions that should panic if their
 // returned error value is not nil.
 func PanicOnError(err error) {
@@ -125,3 +587,52 @@ func PanicOnError(err error) {
 		panic(err)
 	}
 }
+
+// PanicError is returned by Safely when its function panics.
+type PanicError struct {
+	// Value is the value that was passed to panic.
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack string
+}
+
+// Error implements the error interface.
+func (e PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
+
+// Unwrap gets the value passed to panic if it's an error.
+func (e PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// findPanicError finds a PanicError in err or its causes.
+func findPanicError(err error) (PanicError, bool) {
+	if err == nil {
+		return PanicError{}, false
+	}
+	if pe, ok := err.(PanicError); ok {
+		return pe, true
+	}
+	for _, cause := range errorCauses(err) {
+		if pe, ok := findPanicError(cause); ok {
+			return pe, true
+		}
+	}
+	return PanicError{}, false
+}
+
+// Safely calls f and returns its error.  If f panics, the panic is recovered
+// and returned as a PanicError instead so that a misbehaving Class can't
+// crash the whole process.
+func Safely(f func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			buf := make([]byte, 64<<10)
+			err = PanicError{Value: v, Stack: string(buf[:runtime.Stack(buf, false)])}
+		}
+	}()
+	return f()
+}
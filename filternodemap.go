@@ -0,0 +1,126 @@
+package skink
+
+// filteredNodeMap is a read-only view of the Nodes in a NodeMap that match a
+// predicate.
+type filteredNodeMap struct {
+	readOnlyNodeMap
+	pred func(n Node) bool
+}
+
+// FilterNodeMap creates a live, read-only view (see ReadOnly) of the Nodes in
+// m that match pred.  The view is not a copy:  pred is evaluated whenever the
+// view is used, so changes to m (or to whatever pred checks) are reflected by
+// the view.  Because of that, GetIndex and Len are O(n).
+func FilterNodeMap(m NodeMap, pred func(n Node) bool) NodeMap {
+	return filteredNodeMap{readOnlyNodeMap: readOnlyNodeMap{m: m}, pred: pred}
+}
+
+// Clone copies the matching Nodes into a new NodeMap.
+func (m filteredNodeMap) Clone() NodeMap {
+	c := NewNodeMap(-1)
+	c.AddNodes(m.Nodes(), false)
+	return c
+}
+
+func (m filteredNodeMap) Contains(node Node) bool {
+	return m.pred(node) && m.m.Contains(node)
+}
+
+func (m filteredNodeMap) ContainsName(name String) bool {
+	_, err := m.GetName(name)
+	return err == nil
+}
+
+func (m filteredNodeMap) GetName(name String) (Node, error) {
+	node, err := m.m.GetName(name)
+	if err != nil {
+		return nil, err
+	}
+	if !m.pred(node) {
+		return nil, NodeNotFound{Name: name}
+	}
+	return node, nil
+}
+
+func (m filteredNodeMap) GetAll(name String) []Node {
+	all := m.m.GetAll(name)
+	nodes := make([]Node, 0, len(all))
+	for _, node := range all {
+		if m.pred(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func (m filteredNodeMap) GetIndex(index int) (Node, error) {
+	if index < 0 {
+		length := m.Len()
+		var ok bool
+		if index, ok = GetTrueIndex(length, index); !ok {
+			return nil, IndexError{index, length}
+		}
+	}
+	var found Node
+	i := 0
+	m.Range(func(_ String, n Node) bool {
+		if i == index {
+			found = n
+			return false
+		}
+		i++
+		return true
+	})
+	if found == nil {
+		return nil, IndexError{index, i}
+	}
+	return found, nil
+}
+
+func (m filteredNodeMap) Len() int {
+	length := 0
+	m.Range(func(String, Node) bool {
+		length++
+		return true
+	})
+	return length
+}
+
+func (m filteredNodeMap) Nodes() []Node {
+	nodes := make([]Node, 0, m.m.Len())
+	m.Range(func(_ String, n Node) bool {
+		nodes = append(nodes, n)
+		return true
+	})
+	return nodes
+}
+
+func (m filteredNodeMap) NodesInto(nodes []Node) int {
+	return rangeNodesInto(m, nodes)
+}
+
+func (m filteredNodeMap) Range(f func(name String, n Node) bool) {
+	m.m.Range(func(name String, n Node) bool {
+		if !m.pred(n) {
+			return true
+		}
+		return f(name, n)
+	})
+}
+
+func (m filteredNodeMap) OnAdd(hook NodeMapHook) func() {
+	return m.m.OnAdd(m.filterHook(hook))
+}
+
+func (m filteredNodeMap) OnRemove(hook NodeMapHook) func() {
+	return m.m.OnRemove(m.filterHook(hook))
+}
+
+// filterHook wraps a hook so it's only called for matching Nodes.
+func (m filteredNodeMap) filterHook(hook NodeMapHook) NodeMapHook {
+	return func(_ NodeMap, n Node) {
+		if m.pred(n) {
+			hook(m, n)
+		}
+	}
+}
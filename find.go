@@ -0,0 +1,426 @@
+package skink
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/skillian/errors"
+)
+
+// TraversalOrder defines the order in which a Node tree is traversed by
+// FindNodesWithOptions.
+type TraversalOrder int
+
+const (
+	// BreadthFirst visits the root, then all of its children, then all of
+	// its grandchildren, etc.  Siblings are visited in their NodeMap order.
+	BreadthFirst TraversalOrder = iota
+
+	// DepthFirstPreOrder visits a Node before any of its children and
+	// visits each child's entire subtree before moving on to its next
+	// sibling.
+	DepthFirstPreOrder
+
+	// DepthFirstPostOrder visits all of a Node's children (and their
+	// subtrees) before the Node itself, so the root is always visited last.
+	DepthFirstPostOrder
+)
+
+// String implements fmt.Stringer.
+func (o TraversalOrder) String() string {
+	switch o {
+	case BreadthFirst:
+		return "BreadthFirst"
+	case DepthFirstPreOrder:
+		return "DepthFirstPreOrder"
+	case DepthFirstPostOrder:
+		return "DepthFirstPostOrder"
+	}
+	return fmt.Sprintf("TraversalOrder(%d)", int(o))
+}
+
+// FindOptions configures how FindNodesWithOptions traverses a Node tree.  The
+// zero value is a breadth-first traversal.
+type FindOptions struct {
+	// Order is the order in which Nodes are visited.
+	Order TraversalOrder
+
+	// MaxDepth limits how far below the root the traversal descends.  The
+	// root is at depth 0, its direct children are at depth 1, etc.  Nodes
+	// deeper than MaxDepth are never enumerated.  A MaxDepth <= 0 means
+	// there is no limit.
+	MaxDepth int
+
+	// Sorted visits each Node's children ordered by their names (see
+	// String.Cmp) instead of their NodeMap order so that the traversal is
+	// the same regardless of the order the Nodes were defined in.
+	Sorted bool
+}
+
+// descend checks if the children of a Node at the given depth should be
+// traversed.
+func (o FindOptions) descend(depth int) bool {
+	return o.MaxDepth <= 0 || depth < o.MaxDepth
+}
+
+// FindNodes returns a NodeIterator that iterates over a root Node's
+// descendants to find nodes that match the filter.  Every time the iterator's
+// Next method is called, the next matching node is returned and the bool
+// returned value is true.  When all descendants are traversed and there are
+// no more matches, subsequent calls to Next will result in nil, false.
+//
+// FindNodes traverses the tree breadth-first, starting with the root.
+func FindNodes(root Node, filter func(n Node) bool) NodeIterator {
+	return FindNodesWithOptions(root, filter, FindOptions{})
+}
+
+// FindNodesWithOptions is just like FindNodes but the order and depth of the
+// traversal are determined by options.
+func FindNodesWithOptions(root Node, filter func(n Node) bool, options FindOptions) NodeIterator {
+	var start func() func() (Node, bool)
+	switch options.Order {
+	case BreadthFirst:
+		start = func() func() (Node, bool) { return breadthFirst(root, options) }
+	case DepthFirstPreOrder:
+		start = func() func() (Node, bool) { return depthFirstPreOrder(root, options) }
+	case DepthFirstPostOrder:
+		start = func() func() (Node, bool) { return depthFirstPostOrder(root, options) }
+	default:
+		panic(fmt.Sprintf("invalid traversal order: %v", options.Order))
+	}
+	return FilterNodes(NewFuncNodeIterator(start), filter)
+}
+
+// FindNode finds a single node matching the given predicate
+func FindNode(root Node, predicate func(n Node) bool) (Node, bool) {
+	iter := FindNodes(root, predicate)
+	defer iter.Close()
+	return iter.Next()
+}
+
+// FindNodesByClass finds the Nodes under root (including root itself) whose
+// Class is cls or is derived from cls.
+func FindNodesByClass(root Node, cls Class) NodeIterator {
+	return FindNodes(root, func(n Node) bool {
+		return IsSubclass(n.Class(), cls)
+	})
+}
+
+// FindNodesByName finds the Nodes under root (including root itself) with the
+// given case-insensitive name.
+func FindNodesByName(root Node, name String) NodeIterator {
+	return FindNodes(root, func(n Node) bool {
+		return n.Name().Equal(name)
+	})
+}
+
+// FindNodesByNameGlob finds the Nodes under root (including root itself)
+// whose names match a glob pattern (using the syntax of path.Match).  The
+// match is case-insensitive.
+func FindNodesByNameGlob(root Node, pattern string) (NodeIterator, error) {
+	glob := MakeString(pattern)
+	if _, err := (String{}).MatchGlob(glob); err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"invalid Node name pattern %q: %v",
+			pattern, err)
+	}
+	return FindNodes(root, func(n Node) bool {
+		ok, _ := n.Name().MatchGlob(glob)
+		return ok
+	}), nil
+}
+
+// FindNodesByNameRegexp finds the Nodes under root (including root itself)
+// whose names match a regular expression.  The expression is compiled to be
+// case-insensitive.
+func FindNodesByNameRegexp(root Node, expr string) (NodeIterator, error) {
+	re, err := regexp.Compile("(?i)" + expr)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"invalid Node name expression %q: %v",
+			expr, err)
+	}
+	return FindNodes(root, func(n Node) bool {
+		return re.MatchString(n.Name().String())
+	}), nil
+}
+
+// children gets a Node's children in the order they should be traversed.
+func (o FindOptions) children(node Node) []Node {
+	children := childNodes(node)
+	if o.Sorted {
+		SortNodesByName(children)
+	}
+	return children
+}
+
+// rangeChildren calls f with each of a Node's children in the order they
+// should be traversed until f returns false.  Unless the children have to
+// be sorted, they're not copied.
+func (o FindOptions) rangeChildren(node Node, f func(n Node) bool) {
+	if o.Sorted {
+		for _, child := range o.children(node) {
+			if !f(child) {
+				return
+			}
+		}
+		return
+	}
+	rangeChildNodes(node, f)
+}
+
+// SortNodesByName sorts a slice of Nodes by their names (see String.Cmp).
+// Nodes with equal names keep their relative order.
+func SortNodesByName(nodes []Node) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Name().Cmp(nodes[j].Name()) < 0
+	})
+}
+
+// childNodes gets a Node's children as a slice.  Leaf nodes whose Children
+// method returns a nil NodeMap have no child nodes.
+func childNodes(node Node) []Node {
+	children := node.Children()
+	if children == nil {
+		return nil
+	}
+	return children.Nodes()
+}
+
+// rangeChildNodes calls f with each of a Node's children until f returns
+// false.  Like childNodes, it's safe to use on leaf nodes.
+func rangeChildNodes(node Node, f func(n Node) bool) {
+	children := node.Children()
+	if children == nil {
+		return
+	}
+	children.Range(func(_ String, n Node) bool {
+		return f(n)
+	})
+}
+
+// depthNode is a Node along with its depth below the root of a traversal.
+type depthNode struct {
+	node  Node
+	depth int
+}
+
+func breadthFirst(root Node, options FindOptions) func() (Node, bool) {
+	nodes := make([]depthNode, 1, DefaultNodeMapCapacity)
+	nodes[0] = depthNode{node: root}
+	return func() (Node, bool) {
+		if len(nodes) == 0 {
+			return nil, false
+		}
+		dn := nodes[0]
+		nodes[0] = depthNode{}
+		nodes = nodes[1:]
+		if options.descend(dn.depth) {
+			options.rangeChildren(dn.node, func(child Node) bool {
+				nodes = append(nodes, depthNode{node: child, depth: dn.depth + 1})
+				return true
+			})
+		}
+		return dn.node, true
+	}
+}
+
+func depthFirstPreOrder(root Node, options FindOptions) func() (Node, bool) {
+	stack := make([]depthNode, 1, DefaultNodeMapCapacity)
+	stack[0] = depthNode{node: root}
+	return func() (Node, bool) {
+		length := len(stack)
+		if length == 0 {
+			return nil, false
+		}
+		dn := stack[length-1]
+		stack = stack[:length-1]
+		if options.descend(dn.depth) {
+			start := len(stack)
+			options.rangeChildren(dn.node, func(child Node) bool {
+				stack = append(stack, depthNode{node: child, depth: dn.depth + 1})
+				return true
+			})
+			// Reverse the children so the first is popped first.
+			for i, j := start, len(stack)-1; i < j; i, j = i+1, j-1 {
+				stack[i], stack[j] = stack[j], stack[i]
+			}
+		}
+		return dn.node, true
+	}
+}
+
+// postOrderFrame is a Node on the depthFirstPostOrder stack and whether or
+// not its children have already been pushed.
+type postOrderFrame struct {
+	depthNode
+	expanded bool
+}
+
+func depthFirstPostOrder(root Node, options FindOptions) func() (Node, bool) {
+	stack := make([]postOrderFrame, 1, DefaultNodeMapCapacity)
+	stack[0] = postOrderFrame{depthNode: depthNode{node: root}}
+	return func() (Node, bool) {
+		for {
+			length := len(stack)
+			if length == 0 {
+				return nil, false
+			}
+			top := &stack[length-1]
+			if top.expanded || !options.descend(top.depth) {
+				stack = stack[:length-1]
+				return top.node, true
+			}
+			top.expanded = true
+			depth := top.depth + 1
+			node := top.node
+			start := len(stack)
+			options.rangeChildren(node, func(child Node) bool {
+				stack = append(stack, postOrderFrame{
+					depthNode: depthNode{node: child, depth: depth},
+				})
+				return true
+			})
+			for i, j := start, len(stack)-1; i < j; i, j = i+1, j-1 {
+				stack[i], stack[j] = stack[j], stack[i]
+			}
+		}
+	}
+}
+
+// WalkAction is returned by the function passed to Walk to control how the
+// walk proceeds.
+type WalkAction int
+
+const (
+	// Continue walks into the current Node's children and then on to the
+	// rest of the tree.
+	Continue WalkAction = iota
+
+	// SkipChildren does not walk into the current Node's children but
+	// continues on to its next sibling.
+	SkipChildren
+
+	// Stop ends the walk immediately.
+	Stop
+)
+
+// String implements fmt.Stringer.
+func (a WalkAction) String() string {
+	switch a {
+	case Continue:
+		return "Continue"
+	case SkipChildren:
+		return "SkipChildren"
+	case Stop:
+		return "Stop"
+	}
+	return fmt.Sprintf("WalkAction(%d)", int(a))
+}
+
+// Walk visits root and its descendants depth-first (pre-order), calling visit
+// on each Node.  The WalkAction returned by visit determines whether the
+// Node's subtree is walked and whether the walk continues at all.  Walk
+// returns false if the walk was stopped by visit returning Stop.
+func Walk(root Node, visit func(n Node) WalkAction) bool {
+	switch visit(root) {
+	case SkipChildren:
+		return true
+	case Stop:
+		return false
+	}
+	ok := true
+	rangeChildNodes(root, func(child Node) bool {
+		ok = Walk(child, visit)
+		return ok
+	})
+	return ok
+}
+
+// WalkChan traverses root and its descendants breadth-first in a new goroutine
+// and sends each Node that matches filter to the returned channel.  The
+// channel is closed when the traversal finishes or when ctx is cancelled,
+// whichever happens first.
+func WalkChan(ctx context.Context, root Node, filter func(n Node) bool) <-chan Node {
+	ch := make(chan Node)
+	go func() {
+		defer close(ch)
+		iter := FindNodes(root, filter)
+		defer iter.Close()
+		for node, ok := iter.Next(); ok; node, ok = iter.Next() {
+			select {
+			case ch <- node:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// FindNodesParallel finds the Nodes under root (including root itself) that
+// match filter by traversing each of root's direct child subtrees
+// concurrently with at most workers goroutines.  If workers <= 0,
+// runtime.GOMAXPROCS(0) workers are used.  filter must be safe to call
+// concurrently.
+//
+// The results are merged deterministically: root (if it matches) comes first
+// (or last for DepthFirstPostOrder), along with the matches from each child's
+// subtree in the order of root's children.  Within a subtree, Nodes are
+// ordered as specified by options.
+func FindNodesParallel(root Node, filter func(n Node) bool, workers int, options FindOptions) []Node {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	var children []Node
+	if options.descend(0) {
+		children = options.children(root)
+	}
+	subtree := options
+	if subtree.MaxDepth > 0 {
+		subtree.MaxDepth--
+	}
+	results := make([][]Node, len(children))
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := minint(workers, len(children)); i > 0; i-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				if options.MaxDepth == 1 {
+					if filter(children[index]) {
+						results[index] = children[index : index+1]
+					}
+					continue
+				}
+				iter := FindNodesWithOptions(children[index], filter, subtree)
+				for node, ok := iter.Next(); ok; node, ok = iter.Next() {
+					results[index] = append(results[index], node)
+				}
+			}
+		}()
+	}
+	for i := range children {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	nodes := make([]Node, 0, DefaultNodeMapCapacity)
+	if options.Order != DepthFirstPostOrder && filter(root) {
+		nodes = append(nodes, root)
+	}
+	for _, result := range results {
+		nodes = append(nodes, result...)
+	}
+	if options.Order == DepthFirstPostOrder && filter(root) {
+		nodes = append(nodes, root)
+	}
+	return nodes
+}
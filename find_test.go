@@ -0,0 +1,84 @@
+package skink
+
+import (
+	"net/url"
+	"testing"
+)
+
+var findTestSubClass = MustRegisterClassString(
+	"import:findtest#SubSortedNode",
+	&nodeclass{
+		name:         MakeString("SubSortedNode"),
+		base:         SortedNodeClass,
+		allocator:    allocBasicNode,
+		initializer:  initBasicNode,
+		nodemapmaker: NewSortedNodeMap,
+	})
+
+func TestIsSubclass(t *testing.T) {
+	tests := []struct {
+		cls, base Class
+		want      bool
+	}{
+		{SortedNodeClass, SortedNodeClass, true},
+		{findTestSubClass, SortedNodeClass, true},
+		{findTestSubClass, NodeClass, true},
+		{SortedNodeClass, findTestSubClass, false},
+		{StringClass, SortedNodeClass, false},
+	}
+	for _, tt := range tests {
+		if got := IsSubclass(tt.cls, tt.base); got != tt.want {
+			t.Errorf("IsSubclass(%v, %v) = %v, want %v",
+				tt.cls.Name(), tt.base.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestFindNodesByClass(t *testing.T) {
+	newDef := func(name, classURI string, parent *NodeDef) *NodeDef {
+		uri, err := url.Parse(classURI)
+		if err != nil {
+			t.Fatal(err)
+		}
+		def := NewNodeDef(MakeString(name), parent, uri)
+		if parent != nil {
+			parent.Children = append(parent.Children, def)
+		}
+		return def
+	}
+	root := newDef("root", "import:nodes#Node", nil)
+	newDef("plain", "import:nodes#String", root)
+	newDef("sorted", "import:nodes#SortedNode", root)
+	newDef("sub", "import:findtest#SubSortedNode", root)
+	node, err := GlobalSkink.CreateNode(nil, root)
+	if err != nil {
+		t.Fatalf("failed to create tree: %v", err)
+	}
+	tests := []struct {
+		cls  Class
+		want []string
+	}{
+		{SortedNodeClass, []string{"sorted", "sub"}},
+		{findTestSubClass, []string{"sub"}},
+		{CaseSensitiveNodeClass, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		it := FindNodesByClass(node, tt.cls)
+		for n, ok := it.Next(); ok; n, ok = it.Next() {
+			got = append(got, n.Name().String())
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("FindNodesByClass(%v) = %v, want %v",
+				tt.cls.Name(), got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("FindNodesByClass(%v) = %v, want %v",
+					tt.cls.Name(), got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package skink
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+)
+
+// Fingerprint computes a SHA-256 hash of the canonical form (see Normalize) of
+// a NodeDef tree.  Trees that only differ by formatting (the order of
+// children, the case of ClassURIs or whitespace-only Values) have the same
+// fingerprint.  def is not modified.
+func Fingerprint(def *NodeDef) []byte {
+	def = def.Clone(nil)
+	Normalize(def)
+	h := sha256.New()
+	writeFingerprint(h, def)
+	return h.Sum(nil)
+}
+
+// writeFingerprint writes each field of the NodeDef tree prefixed with its
+// length so that different trees can't produce the same stream of bytes.
+func writeFingerprint(h hash.Hash, def *NodeDef) {
+	writeFingerprintString(h, def.Name.String())
+	writeFingerprintString(h, classURIString(def))
+	writeFingerprintString(h, def.Value)
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(def.Children)))
+	h.Write(length[:])
+	for _, child := range def.Children {
+		writeFingerprint(h, child)
+	}
+}
+
+func writeFingerprintString(h hash.Hash, s string) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(s)))
+	h.Write(length[:])
+	h.Write([]byte(s))
+}
+
+// ConfigFingerprint combines the fingerprints of every root loaded into the
+// Skink context (see Roots) into a single hex-encoded SHA-256 hash that
+// identifies the configuration the context is running.
+func (sk *Skink) ConfigFingerprint() (string, error) {
+	h := sha256.New()
+	for _, root := range sk.Roots() {
+		def, err := ExportNodeDef(root)
+		if err != nil {
+			return "", err
+		}
+		h.Write(Fingerprint(def))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
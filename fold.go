@@ -0,0 +1,171 @@
+package skink
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+// CaseFolder folds a string so that strings that should compare equal
+// regardless of their case fold to the same string.  String's Lower value is
+// the folded value.
+type CaseFolder func(s string) string
+
+var (
+	// FoldLower folds with strings.ToLower.  It's the default.
+	FoldLower CaseFolder = strings.ToLower
+
+	// FoldASCII only folds the ASCII letters A-Z so that other letters
+	// are compared exactly.
+	FoldASCII CaseFolder = foldASCII
+
+	// FoldUnicode folds with Unicode simple case folding (see
+	// unicode.SimpleFold) so that, e.g., "ß" and "ẞ" or "K" (Kelvin sign)
+	// and "k" are equal.
+	FoldUnicode CaseFolder = foldUnicode
+
+	// FoldTurkish folds with the Turkish and Azeri casing rules where "I"
+	// lower-cases to dotless "ı" and "İ" to "i".
+	FoldTurkish CaseFolder = func(s string) string {
+		return strings.ToLowerSpecial(unicode.TurkishCase, s)
+	}
+
+	// FoldNone doesn't fold at all, making Strings case-sensitive.
+	FoldNone CaseFolder = func(s string) string { return s }
+
+	// defaultCaseFolder is initialized with a function instead of in init
+	// because package-level Strings are made before init is called.
+	defaultCaseFolder = func() *atomic.Value {
+		v := new(atomic.Value)
+		v.Store(FoldLower)
+		return v
+	}()
+)
+
+var (
+	// caseSensitive is non-zero when SetCaseSensitive(true) was called.
+	caseSensitive int32
+
+	// caseSensitiveMutex serializes SetCaseSensitive calls so that
+	// caseInsensitiveFolder is only saved once.
+	caseSensitiveMutex sync.Mutex
+
+	// caseInsensitiveFolder is the default CaseFolder from before Skink
+	// was made case-sensitive.  It's restored when Skink is made
+	// case-insensitive again.
+	caseInsensitiveFolder CaseFolder
+)
+
+// SetCaseSensitive makes node names, NodeMap keys and Class URIs
+// case-sensitive (or case-insensitive again) throughout Skink, for
+// configurations where case is significant (e.g. machine-generated
+// documents).  Making Skink case-sensitive sets the default CaseFolder to
+// FoldNone and making it case-insensitive again restores the CaseFolder it
+// had before (e.g. one set with SetDefaultCaseFolder).  Like
+// SetDefaultCaseFolder, it should be called before any configuration is
+// loaded.
+//
+// It's a process-wide setting instead of a Skink context option because
+// Strings are folded when they're made (see MakeString), long before (and
+// independently of) the Skink context that will use them, and because the
+// Class registry is shared by every Skink context.  A per-context setting
+// couldn't make the names made by loaders, Classes or package-level vars
+// case-sensitive.
+func SetCaseSensitive(enabled bool) {
+	caseSensitiveMutex.Lock()
+	defer caseSensitiveMutex.Unlock()
+	if enabled == CaseSensitive() {
+		return
+	}
+	if enabled {
+		caseInsensitiveFolder = DefaultCaseFolder()
+		atomic.StoreInt32(&caseSensitive, 1)
+		SetDefaultCaseFolder(FoldNone)
+		return
+	}
+	atomic.StoreInt32(&caseSensitive, 0)
+	SetDefaultCaseFolder(caseInsensitiveFolder)
+	caseInsensitiveFolder = nil
+}
+
+// CaseSensitive checks if Skink is case-sensitive (see SetCaseSensitive).
+func CaseSensitive() bool {
+	return atomic.LoadInt32(&caseSensitive) != 0
+}
+
+// SetDefaultCaseFolder sets the CaseFolder used by MakeString.  Strings that
+// were already made keep their folded values, so this should be called
+// before any configuration is loaded.  If string interning is on,
+// ResetStringInterning should be called too.
+func SetDefaultCaseFolder(folder CaseFolder) {
+	if folder == nil {
+		folder = FoldLower
+	}
+	defaultCaseFolder.Store(folder)
+}
+
+// DefaultCaseFolder gets the CaseFolder used by MakeString.
+func DefaultCaseFolder() CaseFolder {
+	return defaultCaseFolder.Load().(CaseFolder)
+}
+
+// MakeStringWithFolder makes a String whose Lower value is folded with
+// folder instead of the default CaseFolder.  Strings should only be compared
+// with Strings folded the same way.  The value is normalized with the
+// default Normalizer first (see SetDefaultNormalizer).
+func MakeStringWithFolder(value string, folder CaseFolder) String {
+	value = DefaultNormalizer()(value)
+	lower := folder(value)
+	return makeFoldedString(value, lower)
+}
+
+// makeValueString makes the String of a StringNode's value.  Unlike
+// MakeString's, its value is kept byte-for-byte so that values round-trip
+// exactly; only the folded value that it's compared by is normalized.  Values
+// aren't interned because they're unbounded.
+func makeValueString(value string) String {
+	return makeFoldedString(value, DefaultCaseFolder()(DefaultNormalizer()(value)))
+}
+
+// makeFoldedString makes a String from its value and its already-folded
+// value.
+func makeFoldedString(value, lower string) String {
+	s := String{value: value}
+	if lower != value {
+		s.lower = lower
+	}
+	return s
+}
+
+func foldASCII(s string) string {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; 'A' <= c && c <= 'Z' {
+			b := []byte(s)
+			for j := i; j < len(b); j++ {
+				if c := b[j]; 'A' <= c && c <= 'Z' {
+					b[j] = c + 'a' - 'A'
+				}
+			}
+			return string(b)
+		}
+	}
+	return s
+}
+
+// foldUnicode maps every rune to the lower-case member of its case folding
+// orbit.  The orbit is first canonicalized to its smallest rune (so that,
+// e.g., "K" (Kelvin sign), "K" and "k" all map to the same rune) and that rune
+// is then lower-cased so that, like FoldLower's, the Lower values are
+// lower-case.
+func foldUnicode(s string) string {
+	return strings.Map(func(r rune) rune {
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		return unicode.ToLower(min)
+	}, s)
+}
@@ -0,0 +1,108 @@
+package skink
+
+import (
+	"net/url"
+	"os"
+	"testing"
+)
+
+// caseSensitiveTestClass is a Class that can be registered under several
+// URIs by making several of them.
+type caseSensitiveTestClass struct{ nodeclass }
+
+func newCaseSensitiveTestClass(name string) *caseSensitiveTestClass {
+	return &caseSensitiveTestClass{nodeclass{
+		name:        MakeString(name),
+		base:        NodeClass,
+		allocator:   allocBasicNode,
+		initializer: initBasicNode,
+	}}
+}
+
+func TestCaseSensitiveClassURI(t *testing.T) {
+	SetCaseSensitive(true)
+	defer SetCaseSensitive(false)
+	lower := newCaseSensitiveTestClass("thing")
+	upper := newCaseSensitiveTestClass("THING")
+	if err := RegisterClassString("import:casetest#thing", lower); err != nil {
+		t.Fatalf("failed to register thing: %v", err)
+	}
+	if err := RegisterClassString("import:casetest#THING", upper); err != nil {
+		t.Fatalf("failed to register THING: %v", err)
+	}
+	for uri, want := range map[string]Class{
+		"import:casetest#thing": lower,
+		"import:casetest#THING": upper,
+	} {
+		u, err := url.Parse(uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cls, err := GetClassByURI(u)
+		if err != nil {
+			t.Errorf("GetClassByURI(%v): %v", uri, err)
+			continue
+		}
+		if cls != want {
+			t.Errorf("GetClassByURI(%v) = %v, want %v", uri, cls.Name(), want.Name())
+		}
+		def := NewNodeDef(MakeString("n"), nil, u)
+		Normalize(def)
+		if def.ClassURI.String() != uri {
+			t.Errorf("Normalize changed ClassURI %v to %v", uri, def.ClassURI)
+		}
+	}
+}
+
+func TestCaseSensitivePath(t *testing.T) {
+	SetCaseSensitive(true)
+	defer SetCaseSensitive(false)
+	nodeClassURI, err := url.Parse("import:nodes#Node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stringClassURI, err := url.Parse("import:nodes#String")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := NewNodeDef(MakeString("headers"), nil, nodeClassURI)
+	for _, name := range []string{"Content-Type", "content-type"} {
+		def := NewNodeDef(MakeString(name), root, stringClassURI)
+		def.Value = name
+		root.Children = append(root.Children, def)
+	}
+	node, err := GlobalSkink.CreateNode(nil, root)
+	if err != nil {
+		t.Fatalf("failed to create tree: %v", err)
+	}
+	sk, err := GlobalSkink.CreateChild("foldtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sk.TempDir)
+	sk.EnablePathIndex()
+	cache := NewPathCache(node)
+	for i := 0; i < 2; i++ {
+		for _, name := range []string{"Content-Type", "content-type"} {
+			lookups := map[string]func(string) (Node, error){
+				"GetChildByPath": func(path string) (Node, error) {
+					return GetChildByPath(node, path)
+				},
+				"PathCache": cache.GetChildByPath,
+				"GetNodeByPath": func(path string) (Node, error) {
+					return sk.GetNodeByPath(node, path)
+				},
+			}
+			for lookup, get := range lookups {
+				child, err := get(name)
+				if err != nil {
+					t.Errorf("%v(%v): %v", lookup, name, err)
+					continue
+				}
+				if got := child.(Value).Value(); got != name {
+					t.Errorf("%v(%v) = %v", lookup, name, got)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package skink
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/skillian/errors"
+)
+
+// RegisterFunc registers a Go function under name so that it can be called
+// with CallFunc and from "${name(args...)}" expressions in NodeDef Values
+// (see (*Skink).InterpolateNodeDef).  Function names are case-insensitive
+// like Node names and child Skink contexts can call their parents'
+// functions.
+//
+// fn can be a Caller or a Go function, which is adapted with FuncCaller.
+func (sk *Skink) RegisterFunc(name string, fn interface{}) error {
+	c, ok := fn.(Caller)
+	if !ok {
+		var err error
+		if c, err = newFuncCaller(fn); err != nil {
+			return errors.ErrorfWithCause(
+				err,
+				"failed to register function %v: %v",
+				name, err)
+		}
+	}
+	key := MakeString(name).Lower()
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	if _, ok := sk.funcs[key]; ok {
+		return errors.Errorf("function %v is already registered", name)
+	}
+	if sk.funcs == nil {
+		sk.funcs = make(map[string]Caller)
+	}
+	sk.funcs[key] = c
+	sk.Debug1("Registered function %v", name)
+	return nil
+}
+
+// MustRegisterFunc registers a function with RegisterFunc and panics if it
+// fails.
+func (sk *Skink) MustRegisterFunc(name string, fn interface{}) {
+	if err := sk.RegisterFunc(name, fn); err != nil {
+		panic(err)
+	}
+}
+
+// Func gets the Caller of the function registered under name in this Skink
+// context or the nearest of its parents.
+func (sk *Skink) Func(name string) (Caller, bool) {
+	key := MakeString(name).Lower()
+	parents := sk.Parents()
+	for p, ok := parents(); ok; p, ok = parents() {
+		p.mutex.RLock()
+		c, ok := p.funcs[key]
+		p.mutex.RUnlock()
+		if ok {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// CallFunc calls the function registered under name with args through the
+// Skink context's Interceptors (see Intercept).
+func (sk *Skink) CallFunc(name string, args ...Node) (Node, error) {
+	c, ok := sk.Func(name)
+	if !ok {
+		return nil, errors.Errorf("function %v is not registered", name)
+	}
+	result, err := sk.Call(c, args...)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"function %v failed: %v",
+			name, err)
+	}
+	return result, nil
+}
+
+var (
+	nodeType   = reflect.TypeOf((*Node)(nil)).Elem()
+	stringType = reflect.TypeOf("")
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// FuncCaller adapts a Go function into a Caller.  FuncCaller panics if fn
+// isn't a function or isn't supported (see below).
+//
+// The function's parameters are bound to the arguments by position: the
+// i-th parameter gets the argument named strconv.Itoa(i) or, if there isn't
+// one, the i-th argument.  A function whose only parameter is a struct (or a
+// pointer to one) with at least one `skink:"name"` field tag gets its
+// exported fields bound by name instead, using the field's tag if it has one
+// and its name otherwise; arguments that don't bind to any field are an
+// error.  Other structs, such as time.Time or Nodes like *StringNode, are
+// bound by position.  Parameters or fields that are Nodes get the argument
+// Nodes and the rest get the arguments' values converted with Coerce.
+//
+// The function must return one result, optionally followed by an error.
+// Results that are Nodes are returned as-is and other results are returned
+// in a Value (see NewValueNode).
+func FuncCaller(fn interface{}) Caller {
+	fc, err := newFuncCaller(fn)
+	if err != nil {
+		panic(err)
+	}
+	return fc
+}
+
+// funcCaller calls a Go function through reflection (see FuncCaller).
+type funcCaller struct {
+	fn reflect.Value
+
+	// fields, if not nil, are the names of the fields of the function's
+	// struct parameter, by index.
+	fields []String
+}
+
+func newFuncCaller(fn interface{}) (*funcCaller, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, errors.Errorf("%T is not a function", fn)
+	}
+	t := v.Type()
+	if t.IsVariadic() {
+		return nil, errors.Errorf("variadic function %v is not supported", t)
+	}
+	results := t.NumOut()
+	if results > 0 && t.Out(results-1) == errorType {
+		results--
+	}
+	if results != 1 {
+		return nil, errors.Errorf(
+			"function %v must return one result and, optionally, an error",
+			t)
+	}
+	fc := &funcCaller{fn: v}
+	if st, ok := funcCallerStruct(t); ok {
+		fc.fields = make([]String, st.NumField())
+		for i := range fc.fields {
+			f := st.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := f.Tag.Get("skink")
+			if name == "" {
+				name = f.Name
+			}
+			fc.fields[i] = MakeString(name)
+		}
+	}
+	return fc, nil
+}
+
+// funcCallerStruct gets the struct type of a function's only parameter if
+// its fields are bound by name: the struct must have a field with a skink
+// tag and the parameter mustn't be a Node.
+func funcCallerStruct(t reflect.Type) (reflect.Type, bool) {
+	if t.NumIn() != 1 || t.In(0).Implements(nodeType) {
+		return nil, false
+	}
+	in := t.In(0)
+	if in.Kind() == reflect.Ptr {
+		in = in.Elem()
+	}
+	if in.Kind() != reflect.Struct {
+		return nil, false
+	}
+	for i := 0; i < in.NumField(); i++ {
+		if _, ok := in.Field(i).Tag.Lookup("skink"); ok {
+			return in, true
+		}
+	}
+	return nil, false
+}
+
+// Call implements Caller.
+func (fc *funcCaller) Call(args NodeMap) (Node, error) {
+	if args == nil {
+		args = NewNodeMap(0)
+	}
+	var in []reflect.Value
+	var err error
+	if fc.fields != nil {
+		in, err = fc.bindFields(args)
+	} else {
+		in, err = fc.bindPositions(args)
+	}
+	if err != nil {
+		return nil, WithErrorKind(ArgumentErrorKind, err)
+	}
+	out := fc.fn.Call(in)
+	if last := out[len(out)-1]; last.Type() == errorType {
+		if !last.IsNil() {
+			return nil, last.Interface().(error)
+		}
+	}
+	result := out[0].Interface()
+	if node, ok := result.(Node); ok {
+		return node, nil
+	}
+	return NewValueNode(MakeString("result"), result), nil
+}
+
+// bindPositions binds args to the function's parameters by position.
+func (fc *funcCaller) bindPositions(args NodeMap) ([]reflect.Value, error) {
+	t := fc.fn.Type()
+	if args.Len() != t.NumIn() {
+		return nil, errors.Errorf(
+			"function %v takes %d arguments, not %d",
+			t, t.NumIn(), args.Len())
+	}
+	in := make([]reflect.Value, t.NumIn())
+	for i := range in {
+		arg, err := args.GetName(MakeString(strconv.Itoa(i)))
+		if err != nil {
+			if arg, err = args.GetIndex(i); err != nil {
+				return nil, err
+			}
+		}
+		if in[i], err = bindArg(arg, t.In(i)); err != nil {
+			return nil, errors.ErrorfWithCause(
+				err,
+				"failed to bind argument %d: %v",
+				i, err)
+		}
+	}
+	return in, nil
+}
+
+// bindFields binds args to the fields of the function's struct parameter.
+func (fc *funcCaller) bindFields(args NodeMap) ([]reflect.Value, error) {
+	pt := fc.fn.Type().In(0)
+	st := pt
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	var unbound error
+	args.Range(func(name String, arg Node) bool {
+		for _, field := range fc.fields {
+			if field.value != "" && field.Equal(name) {
+				return true
+			}
+		}
+		unbound = errors.Errorf(
+			"argument %v does not bind to any field of %v", name, st)
+		return false
+	})
+	if unbound != nil {
+		return nil, unbound
+	}
+	sv := reflect.New(st)
+	for i, name := range fc.fields {
+		if name.value == "" {
+			continue
+		}
+		arg, err := args.GetName(name)
+		if err != nil {
+			continue
+		}
+		v, err := bindArg(arg, st.Field(i).Type)
+		if err != nil {
+			return nil, errors.ErrorfWithCause(
+				err,
+				"failed to bind argument %v: %v",
+				name, err)
+		}
+		sv.Elem().Field(i).Set(v)
+	}
+	if pt.Kind() == reflect.Ptr {
+		return []reflect.Value{sv}, nil
+	}
+	return []reflect.Value{sv.Elem()}, nil
+}
+
+// bindArg converts an argument Node into a parameter of type t.
+func bindArg(arg Node, t reflect.Type) (reflect.Value, error) {
+	if node := UnwrapArg(arg); reflect.TypeOf(node).AssignableTo(t) {
+		v := reflect.New(t).Elem()
+		v.Set(reflect.ValueOf(node))
+		return v, nil
+	}
+	if _, ok := arg.(Value); !ok {
+		return reflect.Value{}, errors.Errorf(
+			"%v is not a Value (type: %T)", arg.Name(), arg)
+	}
+	return Coerce(arg, t)
+}
+
+// ValueNode is a leaf Value of any Go value.  Callers return their results
+// in ValueNodes when they aren't already Nodes (see FuncCaller).
+type ValueNode struct {
+	name   String
+	parent Node
+	value  interface{}
+}
+
+// NewValueNode creates a parentless ValueNode.  Strings are returned as
+// StringNodes instead.
+func NewValueNode(name String, value interface{}) Value {
+	if s, ok := value.(string); ok {
+		return newStringNode(name, s)
+	}
+	return &ValueNode{name: name, value: value}
+}
+
+// Name gets the ValueNode's name.
+func (v *ValueNode) Name() String { return v.name }
+
+// Parent gets the ValueNode's parent.
+func (v *ValueNode) Parent() Node { return v.parent }
+
+// Class gets the ValueNode's Class (NodeClass).
+func (v *ValueNode) Class() Class { return NodeClass }
+
+// Children returns a nil NodeMap.
+func (v *ValueNode) Children() NodeMap { return nil }
+
+// Value gets the ValueNode's Go value.
+func (v *ValueNode) Value() interface{} { return v.value }
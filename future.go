@@ -0,0 +1,64 @@
+package skink
+
+// Future is a Value of the result of a call that runs in its own goroutine
+// (see CallAsync).  Its Value blocks until the call finishes; Done can be
+// used to wait for it along with other channels.
+type Future struct {
+	name   String
+	done   chan struct{}
+	result Node
+	err    error
+}
+
+// CallAsync calls c with args (see Call) in a new goroutine and returns a
+// Future of its result.  Panics in the call are returned as PanicErrors.
+func CallAsync(c Caller, args ...Node) *Future {
+	f := &Future{
+		name: MakeString("future"),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(f.done)
+		f.err = Safely(func() (err error) {
+			f.result, err = Call(c, args...)
+			return err
+		})
+	}()
+	return f
+}
+
+// Name gets the Future's name.
+func (f *Future) Name() String { return f.name }
+
+// Parent returns nil; Futures aren't part of a Node tree.
+func (f *Future) Parent() Node { return nil }
+
+// Class gets the Future's Class (NodeClass).
+func (f *Future) Class() Class { return NodeClass }
+
+// Children returns a nil NodeMap.  The result's children can be gotten from
+// Result.
+func (f *Future) Children() NodeMap { return nil }
+
+// Done gets a channel that's closed when the call finishes.
+func (f *Future) Done() <-chan struct{} { return f.done }
+
+// Result waits for the call to finish and gets its result.
+func (f *Future) Result() (Node, error) {
+	<-f.done
+	return f.result, f.err
+}
+
+// Value waits for the call to finish and gets the value of its result if
+// it's a Value or the result itself if it isn't.  If the call failed, Value
+// returns its error.
+func (f *Future) Value() interface{} {
+	result, err := f.Result()
+	if err != nil {
+		return err
+	}
+	if v, ok := result.(Value); ok {
+		return v.Value()
+	}
+	return result
+}
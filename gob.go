@@ -0,0 +1,141 @@
+package skink
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/skillian/errors"
+)
+
+// gobNodeDef is the binary representation of a NodeDef.  NodeDefs themselves
+// can't be gob-encoded because of their Parent references.
+type gobNodeDef struct {
+	Name     string
+	Class    string
+	Value    string
+	Children []gobNodeDef
+}
+
+func makeGobNodeDef(def *NodeDef) gobNodeDef {
+	g := gobNodeDef{
+		Name:     def.Name.String(),
+		Class:    classURIString(def),
+		Value:    def.Value,
+		Children: make([]gobNodeDef, len(def.Children)),
+	}
+	for i, child := range def.Children {
+		g.Children[i] = makeGobNodeDef(child)
+	}
+	return g
+}
+
+func (g gobNodeDef) nodeDef(parent *NodeDef) (*NodeDef, error) {
+	var classuri *url.URL
+	if g.Class != "" {
+		var err error
+		if classuri, err = url.Parse(g.Class); err != nil {
+			return nil, errors.ErrorfWithCause(
+				err,
+				"failed to parse class URI %q of %v: %v",
+				g.Class, g.Name, err)
+		}
+	}
+	def := NewNodeDef(MakeString(g.Name), parent, classuri)
+	def.Value = g.Value
+	for _, child := range g.Children {
+		childdef, err := child.nodeDef(def)
+		if err != nil {
+			return nil, err
+		}
+		def.Children = append(def.Children, childdef)
+	}
+	return def, nil
+}
+
+// EncodeNodeDef writes a NodeDef tree to w in a compact binary (gob) form that
+// DecodeNodeDef can read back much faster than the tree's source document
+// can be parsed.  XMLInfo is not encoded.
+func EncodeNodeDef(w io.Writer, def *NodeDef) error {
+	return gob.NewEncoder(w).Encode(makeGobNodeDef(def))
+}
+
+// DecodeNodeDef reads a NodeDef tree written by EncodeNodeDef.
+func DecodeNodeDef(r io.Reader) (*NodeDef, error) {
+	g := gobNodeDef{}
+	if err := gob.NewDecoder(r).Decode(&g); err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"failed to decode NodeDef: %v",
+			err)
+	}
+	return g.nodeDef(nil)
+}
+
+// CachingURILoader wraps a URI loader (see RegisterURILoader) so that the
+// NodeDef trees it loads from file URIs are cached in dir with
+// EncodeNodeDef.  A cached tree is used as long as the file's path,
+// modification time and size are unchanged.  URIs with other schemes are
+// passed through to loader.  Failing to write to the cache is not an error.
+func CachingURILoader(dir string, loader func(*url.URL) (*NodeDef, error)) func(*url.URL) (*NodeDef, error) {
+	return func(uri *url.URL) (nodedef *NodeDef, err error) {
+		if uri.Scheme != "file" {
+			return loader(uri)
+		}
+		path := GetURIPath(uri)
+		info, err := os.Stat(path)
+		if err != nil {
+			return loader(uri)
+		}
+		key := sha256.Sum256([]byte(fmt.Sprintf(
+			"%s\x00%s\x00%d\x00%d",
+			uri.String(), path, info.ModTime().UnixNano(), info.Size())))
+		cachepath := filepath.Join(dir, hex.EncodeToString(key[:])+".gob")
+		if file, err := os.Open(cachepath); err == nil {
+			nodedef, err = DecodeNodeDef(file)
+			file.Close()
+			if err == nil {
+				return nodedef, nil
+			}
+			logger.Warn2("ignoring corrupt NodeDef cache %v: %v", cachepath, err)
+		}
+		if nodedef, err = loader(uri); err != nil {
+			return nil, err
+		}
+		if err := writeNodeDefCache(dir, cachepath, nodedef); err != nil {
+			logger.Warn2("failed to cache NodeDef in %v: %v", cachepath, err)
+		}
+		return nodedef, nil
+	}
+}
+
+// writeNodeDefCache writes the NodeDef to a temporary file in dir and renames
+// it to cachepath so that partially written caches are never read.
+func writeNodeDefCache(dir, cachepath string, nodedef *NodeDef) (err error) {
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(dir, "nodedef")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(file.Name())
+		}
+	}()
+	if err = EncodeNodeDef(file, nodedef); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), cachepath)
+}
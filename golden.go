@@ -0,0 +1,144 @@
+package skink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+// CanonicalNodeDef exports the tree under node (see ExportNodeDef) in a
+// canonical form that only changes when the tree does: it's normalized (see
+// Normalize) and secret values (see IsSecret) are redacted.
+func CanonicalNodeDef(node Node) (*NodeDef, error) {
+	def, err := exportRedactedNodeDef(node, nil)
+	if err != nil {
+		return nil, err
+	}
+	Normalize(def)
+	return def, nil
+}
+
+// WriteCanonical writes the canonical form of the tree under node (see
+// CanonicalNodeDef) to w as JSON (see WriteJSON).  Trees written by
+// WriteCanonical can be read back with ReadJSON.
+func WriteCanonical(w io.Writer, node Node) error {
+	def, err := CanonicalNodeDef(node)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, def)
+}
+
+// GoldenMismatch is the error returned by CompareGolden when a tree isn't
+// the same as its golden file.
+type GoldenMismatch struct {
+	// Path is the golden file's path.
+	Path string
+
+	// Changes are the changes from the golden file's tree to the actual
+	// tree (see DiffNodeDefs).  They're empty if only the trees' root
+	// names differ.
+	Changes []Change
+}
+
+// Error implements error.  Every Change is on its own line, along with the
+// values or Classes that changed.
+func (m *GoldenMismatch) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "tree does not match golden file %v:", m.Path)
+	if len(m.Changes) == 0 {
+		b.WriteString("\n\troot name changed")
+	}
+	for _, c := range m.Changes {
+		b.WriteString("\n\t")
+		b.WriteString(describeGoldenChange(c))
+	}
+	return b.String()
+}
+
+// describeGoldenChange formats a Change between NodeDef views (see
+// DiffNodeDefs) with its values.
+func describeGoldenChange(c Change) string {
+	switch c.Kind {
+	case NodeAdded:
+		return fmt.Sprintf("%v (%v)", c, describeGoldenNode(c.New))
+	case NodeRemoved:
+		return fmt.Sprintf("%v (%v)", c, describeGoldenNode(c.Old))
+	case NodeValueChanged:
+		return fmt.Sprintf("%v: %q -> %q",
+			c, c.Old.(Value).Value(), c.New.(Value).Value())
+	case NodeClassChanged:
+		return fmt.Sprintf("%v: %v -> %v",
+			c, c.Old.Class().Name(), c.New.Class().Name())
+	}
+	return c.String()
+}
+
+func describeGoldenNode(node Node) string {
+	s := node.Class().Name().String()
+	if v, ok := node.(Value); ok && v.Value() != "" {
+		s += fmt.Sprintf(" %q", v.Value())
+	}
+	return s
+}
+
+// CompareGolden compares the canonical form of the tree under node (see
+// CanonicalNodeDef) with the tree in the golden file at path, which was
+// written by WriteCanonical.  If they differ, a *GoldenMismatch is returned.
+// If update is true, the golden file is (re)written with the tree instead,
+// e.g. after a change to the tree was reviewed.
+func CompareGolden(node Node, path string, update bool) error {
+	actual, err := CanonicalNodeDef(node)
+	if err != nil {
+		return err
+	}
+	if update {
+		return writeGoldenFile(path, actual)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"failed to open golden file %v (write it with update): %v",
+			path, err)
+	}
+	defer f.Close()
+	golden, err := ReadJSON(f)
+	if err != nil {
+		return wrapLoadError(&url.URL{Scheme: "file", Path: path}, err)
+	}
+	Normalize(golden)
+	if bytes.Equal(Fingerprint(golden), Fingerprint(actual)) {
+		return nil
+	}
+	return &GoldenMismatch{Path: path, Changes: DiffNodeDefs(golden, actual)}
+}
+
+// writeGoldenFile writes def to the golden file at path, creating its
+// directory if needed.
+func writeGoldenFile(path string, def *NodeDef) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"failed to create directory of golden file %v: %v",
+			path, err)
+	}
+	var b bytes.Buffer
+	if err := WriteJSON(&b, def); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, b.Bytes(), 0644); err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"failed to write golden file %v: %v",
+			path, err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package skink
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+)
+
+// Inspection is a snapshot of what a Skink context knows about (see
+// Skink.Inspect) for debugging.
+type Inspection struct {
+	Package     string `json:"package"`
+	Environment string `json:"environment,omitempty"`
+
+	// TempDir is the context's temporary directory and TempDirBytes is the
+	// total size of the files in it.
+	TempDir      string `json:"tempDir"`
+	TempDirBytes int64  `json:"tempDirBytes"`
+
+	// Loaders and Writers are the names of the URI loaders and writers
+	// registered for each scheme in the order they're tried.
+	Loaders map[string][]string `json:"loaders"`
+	Writers map[string][]string `json:"writers"`
+
+	// Classes are the URIs of all of the registered Classes (which are
+	// shared by every context).
+	Classes []string `json:"classes"`
+
+	// Roots are the paths of the context's root Nodes.
+	Roots []string `json:"roots"`
+
+	Children []Inspection `json:"children,omitempty"`
+}
+
+// Inspect gets a snapshot of the Skink context and its children.
+func (sk *Skink) Inspect() Inspection {
+	sk.mutex.RLock()
+	in := Inspection{
+		Package:     sk.Package,
+		Environment: sk.environment,
+		TempDir:     sk.TempDir,
+		Loaders:     make(map[string][]string, len(sk.uriloaders)),
+		Writers:     make(map[string][]string, len(sk.uriwriters)),
+		Roots:       make([]string, len(sk.roots)),
+	}
+	for scheme, loaders := range sk.uriloaders {
+		names := make([]string, len(loaders))
+		for i, ul := range loaders {
+			// loaders are tried from last to first.
+			names[len(loaders)-1-i] = funcName(ul.loader)
+		}
+		in.Loaders[scheme] = names
+	}
+	for scheme, writers := range sk.uriwriters {
+		names := make([]string, len(writers))
+		for i, uw := range writers {
+			names[i] = funcName(uw.writer)
+		}
+		in.Writers[scheme] = names
+	}
+	for i, root := range sk.roots {
+		in.Roots[i] = GetPath(root)
+	}
+	children := make([]*Skink, len(sk.children))
+	copy(children, sk.children)
+	sk.mutex.RUnlock()
+
+	in.TempDirBytes = dirSize(in.TempDir)
+	for uri := range RegisteredClasses() {
+		in.Classes = append(in.Classes, uri)
+	}
+	sort.Strings(in.Classes)
+	for _, child := range children {
+		in.Children = append(in.Children, child.Inspect())
+	}
+	return in
+}
+
+// funcName gets the name of a function value for Inspect.
+func funcName(f interface{}) string {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return "<nil>"
+	}
+	if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return "<unknown>"
+}
+
+// dirSize gets the total size of the files under dir.  Files that can't be
+// read are skipped.
+func dirSize(dir string) int64 {
+	if dir == "" {
+		return 0
+	}
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
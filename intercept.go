@@ -0,0 +1,119 @@
+package skink
+
+import (
+	"fmt"
+)
+
+// Interceptor wraps the Caller of a call dispatched by a Skink context (see
+// (*Skink).Call) for cross-cutting concerns such as auth checks, logging,
+// metrics and panic recovery.  next is either the Caller being called or the
+// Caller returned by the next Interceptor; CallTarget gets the Caller being
+// called from it either way.  An Interceptor can refuse the call by
+// returning an error instead of calling next.
+type Interceptor func(next Caller) Caller
+
+// CallerFunc adapts a function into a Caller so that Interceptors can be
+// written inline.
+type CallerFunc func(args NodeMap) (Node, error)
+
+// Call implements Caller.
+func (f CallerFunc) Call(args NodeMap) (Node, error) {
+	return f(args)
+}
+
+// interceptedCaller is a Caller returned by an Interceptor, which remembers
+// the Caller that's actually being called.
+type interceptedCaller struct {
+	Caller
+	target Caller
+}
+
+// CallTarget gets the Caller that's being called from the next Caller passed
+// to an Interceptor.  If the target is a Node (e.g. a MethodNode), its path
+// can be gotten with GetPath.
+func CallTarget(next Caller) Caller {
+	if ic, ok := next.(interceptedCaller); ok {
+		next = ic.target
+	}
+	if sc, ok := next.(scriptCaller); ok {
+		return sc.ScriptNode
+	}
+	return next
+}
+
+// Intercept adds Interceptors to the Skink context.  Interceptors added
+// first are outermost, and the interceptors of the context's parents wrap
+// its own.
+func (sk *Skink) Intercept(interceptors ...Interceptor) {
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	sk.interceptors = append(sk.interceptors, interceptors...)
+}
+
+// Intercepted wraps c with the Interceptors of the Skink context and its
+// parents.  If there aren't any, c is returned as-is.
+func (sk *Skink) Intercepted(c Caller) Caller {
+	var contexts []*Skink
+	parents := sk.Parents()
+	for p, ok := parents(); ok; p, ok = parents() {
+		contexts = append(contexts, p)
+	}
+	var interceptors []Interceptor
+	for i := len(contexts) - 1; i >= 0; i-- {
+		contexts[i].mutex.RLock()
+		interceptors = append(interceptors, contexts[i].interceptors...)
+		contexts[i].mutex.RUnlock()
+	}
+	target := c
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		c = interceptedCaller{Caller: interceptors[i](c), target: target}
+	}
+	return c
+}
+
+// Call calls c with args like the Call function but through the Skink
+// context's Interceptors.  The Skink context dispatches all of its calls
+// (e.g. CallPath, CallFunc and the calls of ConnectNodes, ScriptNodes and
+// CallHandlers) through Call.
+func (sk *Skink) Call(c Caller, args ...Node) (Node, error) {
+	return Call(sk.Intercepted(c), args...)
+}
+
+// RecoverCalls is an Interceptor that returns panics in calls as
+// PanicErrors (see Safely).
+func RecoverCalls(next Caller) Caller {
+	return CallerFunc(func(args NodeMap) (result Node, err error) {
+		err = Safely(func() (err error) {
+			result, err = next.Call(args)
+			return err
+		})
+		return result, err
+	})
+}
+
+// LogCalls makes an Interceptor that logs every call with the Skink
+// context's Logger at the debug level and every failed call at the warning
+// level.
+func (sk *Skink) LogCalls() Interceptor {
+	return func(next Caller) Caller {
+		return CallerFunc(func(args NodeMap) (Node, error) {
+			target := callTargetName(next)
+			sk.Debug1("Calling %v", target)
+			result, err := next.Call(args)
+			if err != nil {
+				sk.Warn2("Call of %v failed: %v", target, err)
+			}
+			return result, err
+		})
+	}
+}
+
+// callTargetName gets the path of the Node being called, or its type if it
+// isn't a Node, for messages about the call.
+func callTargetName(next Caller) string {
+	target := CallTarget(next)
+	if node, ok := target.(Node); ok {
+		return GetPath(node)
+	}
+	return fmt.Sprintf("%T", target)
+}
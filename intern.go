@@ -0,0 +1,65 @@
+package skink
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// interning is non-zero when MakeString interns its Strings.
+	interning int32
+
+	internMutex sync.RWMutex
+	internPool  = map[string]String{}
+)
+
+// SetStringInterning turns interning of Strings made by MakeString on or off.
+// When it's on, every String made from the same Go string shares the same
+// backing storage for both its value and its lower-case value, which saves a
+// lot of memory in large trees where the same names are repeated many times.
+// Interned Strings are kept until ResetStringInterning is called, so
+// interning should be avoided for Strings made from unbounded input.
+func SetStringInterning(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&interning, value)
+}
+
+// StringInterning checks if MakeString interns its Strings.
+func StringInterning() bool {
+	return atomic.LoadInt32(&interning) != 0
+}
+
+// InternString makes a String like MakeString but gets it from (or adds it to)
+// the interning pool regardless of SetStringInterning.
+func InternString(value string) String {
+	value = DefaultNormalizer()(value)
+	internMutex.RLock()
+	s, ok := internPool[value]
+	internMutex.RUnlock()
+	if ok {
+		return s
+	}
+	internMutex.Lock()
+	defer internMutex.Unlock()
+	if s, ok = internPool[value]; ok {
+		return s
+	}
+	lower := DefaultCaseFolder()(value)
+	if interned, ok := internPool[lower]; ok {
+		lower = interned.value
+	}
+	s = makeFoldedString(value, lower)
+	internPool[value] = s
+	return s
+}
+
+// ResetStringInterning empties the interning pool.  Strings that were
+// already interned are unaffected.
+func ResetStringInterning() {
+	internMutex.Lock()
+	internPool = map[string]String{}
+	internMutex.Unlock()
+}
@@ -0,0 +1,296 @@
+package skink
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+const (
+	// InterpolationStart and InterpolationEnd delimit the paths of other
+	// NodeDefs within a NodeDef's Value (see InterpolateNodeDef).
+	InterpolationStart = "${"
+	InterpolationEnd   = "}"
+
+	// interpolationEscape is written before InterpolationStart to keep it
+	// as-is.
+	interpolationEscape = "$"
+)
+
+// InterpolateNodeDef replaces every "${path}" in the Values of the NodeDefs in
+// root's tree with the (interpolated) Value of the NodeDef at path.  Paths
+// that start with a NodePathSeparator are relative to the NodeDef whose Value
+// they're in (see GetChildByPath) and other paths are relative to root, so
+// with root "app", "${db.host}" refers to "app.db.host".  "$${" is replaced
+// with a literal "${".  Values are only interpolated once, so interpolating
+// a tree again (e.g. after it's included in another) keeps those literal.
+//
+// Values that refer to NodeDefs that don't exist (or to Values that do) are
+// left unchanged, escapes and all, so that they can be interpolated later.
+// Values that refer to each other in a cycle are reported as CycleErrors.
+// All of the problems are returned together in a *ValidationErrors.  Values
+// with problems, or that refer to Values with problems, are left unchanged.
+func InterpolateNodeDef(root *NodeDef) error {
+	return interpolateNodeDef(root, nil)
+}
+
+// InterpolateNodeDef interpolates root's tree like the InterpolateNodeDef
+// function but also evaluates calls of the functions registered with
+// RegisterFunc: "${name(arg, ...)}" is replaced with the value of the Value
+// that the function returns.  Each argument is either a path, which is
+// interpolated as above, or a double-quoted Go string literal, and is passed
+// to the function as a StringNode.  The calls go through the Skink context's
+// Interceptors (see Intercept).  Like unknown paths, calls of functions that
+// aren't registered leave their Values unchanged.
+func (sk *Skink) InterpolateNodeDef(root *NodeDef) error {
+	return interpolateNodeDef(root, func(name string) (Caller, bool) {
+		c, ok := sk.Func(name)
+		if !ok {
+			return nil, false
+		}
+		return sk.Intercepted(c), true
+	})
+}
+
+func interpolateNodeDef(root *NodeDef, funcs func(name string) (Caller, bool)) error {
+	in := interpolator{
+		root:   root,
+		funcs:  funcs,
+		states: make(map[*NodeDef]interpolationState),
+		errors: NewValidationErrors(),
+	}
+	var walk func(def *NodeDef)
+	walk = func(def *NodeDef) {
+		in.interpolate(def, nil)
+		for _, child := range def.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	if in.errors.Len() == 0 {
+		return nil
+	}
+	return in.errors
+}
+
+type interpolationState int
+
+const (
+	interpolationPending interpolationState = iota
+	interpolationVisiting
+	interpolationDone
+	interpolationFailed
+
+	// interpolationUnresolved is the state of Values that refer to unknown
+	// NodeDefs or functions and are left unchanged.
+	interpolationUnresolved
+)
+
+type interpolator struct {
+	root   *NodeDef
+	funcs  func(name string) (Caller, bool)
+	states map[*NodeDef]interpolationState
+	errors *ValidationErrors
+}
+
+// interpolate interpolates def's Value after interpolating the Values it
+// refers to.  stack holds the NodeDefs whose Values are being interpolated
+// and refer (indirectly) to def.  Problems are added to in.errors at the
+// NodeDef where they're found and interpolationFailed is returned to
+// everything that depends on them.
+func (in *interpolator) interpolate(def *NodeDef, stack []*NodeDef) interpolationState {
+	switch state := in.states[def]; state {
+	case interpolationDone, interpolationFailed, interpolationUnresolved:
+		return state
+	case interpolationVisiting:
+		paths := make([]string, 0, len(stack)+1)
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i] == def {
+				for _, d := range stack[i:] {
+					paths = append(paths, nodeDefPath(d))
+				}
+				break
+			}
+		}
+		paths = append(paths, nodeDefPath(def))
+		invalid(in.errors, nodeDefPath(stack[len(stack)-1]), errors.ErrorfWithCause(
+			CycleError{Paths: paths},
+			"interpolation cycle: %v",
+			CycleError{Paths: paths}))
+		return interpolationFailed
+	}
+	if def.interpolated || !strings.Contains(def.Value, InterpolationStart) {
+		in.states[def] = interpolationDone
+		return interpolationDone
+	}
+	in.states[def] = interpolationVisiting
+	value, state := in.expand(def, append(stack, def))
+	if state == interpolationDone {
+		def.Value = value
+		def.interpolated = true
+	}
+	in.states[def] = state
+	return state
+}
+
+// expand gets def's Value with its paths replaced.  Unknown paths and
+// functions don't stop the expansion so that the rest of the Value's
+// problems are still reported.
+func (in *interpolator) expand(def *NodeDef, stack []*NodeDef) (string, interpolationState) {
+	var b bytes.Buffer
+	result := interpolationDone
+	rest := def.Value
+	for {
+		i := strings.Index(rest, InterpolationStart)
+		if i < 0 {
+			b.WriteString(rest)
+			return b.String(), result
+		}
+		if strings.HasSuffix(rest[:i], interpolationEscape) {
+			b.WriteString(rest[:i-len(interpolationEscape)])
+			b.WriteString(InterpolationStart)
+			rest = rest[i+len(InterpolationStart):]
+			continue
+		}
+		b.WriteString(rest[:i])
+		rest = rest[i+len(InterpolationStart):]
+		j := strings.Index(rest, InterpolationEnd)
+		if j < 0 {
+			invalid(in.errors, nodeDefPath(def), errors.Errorf(
+				"unterminated %q in value %q",
+				InterpolationStart, def.Value))
+			return "", interpolationFailed
+		}
+		expr := rest[:j]
+		rest = rest[j+len(InterpolationEnd):]
+		value, state := in.evaluate(def, expr, stack)
+		switch state {
+		case interpolationFailed:
+			return "", interpolationFailed
+		case interpolationUnresolved:
+			result = interpolationUnresolved
+		}
+		b.WriteString(value)
+	}
+}
+
+// evaluate gets the value of an interpolated path or function call in def's
+// Value.
+func (in *interpolator) evaluate(def *NodeDef, expr string, stack []*NodeDef) (string, interpolationState) {
+	name, args, call, err := parseInterpolationCall(expr)
+	if err != nil {
+		invalid(in.errors, nodeDefPath(def), err)
+		return "", interpolationFailed
+	}
+	if !call {
+		return in.lookup(def, expr, stack)
+	}
+	var c Caller
+	if in.funcs != nil {
+		c, _ = in.funcs(name)
+	}
+	if c == nil {
+		return "", interpolationUnresolved
+	}
+	nodes := make([]Node, len(args))
+	for i, arg := range args {
+		var value string
+		if strings.HasPrefix(arg, `"`) {
+			if value, err = strconv.Unquote(arg); err != nil {
+				invalid(in.errors, nodeDefPath(def), errors.ErrorfWithCause(
+					err,
+					"invalid argument %s of function %v: %v",
+					arg, name, err))
+				return "", interpolationFailed
+			}
+		} else {
+			var state interpolationState
+			if value, state = in.lookup(def, arg, stack); state != interpolationDone {
+				return "", state
+			}
+		}
+		nodes[i] = newStringNode(MakeString(strconv.Itoa(i)), value)
+	}
+	result, err := Call(c, nodes...)
+	if err != nil {
+		invalid(in.errors, nodeDefPath(def), errors.ErrorfWithCause(
+			err,
+			"function %v failed: %v",
+			name, err))
+		return "", interpolationFailed
+	}
+	v, ok := result.(Value)
+	if !ok {
+		invalid(in.errors, nodeDefPath(def), errors.Errorf(
+			"function %v returned %T, not a Value", name, result))
+		return "", interpolationFailed
+	}
+	return fmt.Sprint(v.Value()), interpolationDone
+}
+
+// lookup gets the interpolated Value of the NodeDef at path.  Paths that
+// don't resolve are unresolved rather than failures.
+func (in *interpolator) lookup(def *NodeDef, path string, stack []*NodeDef) (string, interpolationState) {
+	p, err := CompilePath(path)
+	if err != nil {
+		invalid(in.errors, nodeDefPath(def), errors.ErrorfWithCause(
+			err,
+			"failed to interpolate %q: %v",
+			path, err))
+		return "", interpolationFailed
+	}
+	target, err := in.resolve(def, path, p)
+	if err != nil {
+		return "", interpolationUnresolved
+	}
+	if state := in.interpolate(target, stack); state != interpolationDone {
+		return "", state
+	}
+	return target.Value, interpolationDone
+}
+
+// resolve resolves an interpolated path, compiled into p, from def.
+func (in *interpolator) resolve(def *NodeDef, path string, p Path) (*NodeDef, error) {
+	if strings.HasPrefix(path, NodePathSeparator) {
+		return p.ResolveNodeDef(def)
+	}
+	return p.ResolveNodeDef(in.root)
+}
+
+// parseInterpolationCall parses a "name(arg, ...)" function call.  If expr
+// isn't a call, call is false.
+func parseInterpolationCall(expr string) (name string, args []string, call bool, err error) {
+	expr = strings.TrimSpace(expr)
+	open := strings.IndexByte(expr, '(')
+	if open < 0 || !strings.HasSuffix(expr, ")") {
+		return "", nil, false, nil
+	}
+	name = strings.TrimSpace(expr[:open])
+	inner := expr[open+1 : len(expr)-1]
+	if strings.TrimSpace(inner) == "" {
+		return name, nil, true, nil
+	}
+	quoted, escaped, start := false, false, 0
+	for i, r := range inner {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			args = append(args, strings.TrimSpace(inner[start:i]))
+			start = i + 1
+		}
+	}
+	if quoted {
+		return "", nil, false, errors.Errorf(
+			"unterminated string in call %q", expr)
+	}
+	args = append(args, strings.TrimSpace(inner[start:]))
+	return name, args, true, nil
+}
@@ -0,0 +1,113 @@
+package skink
+
+// NodeIterator iterates over a sequence of Nodes.
+type NodeIterator interface {
+	// Next gets the next Node in the sequence.  When there are no more
+	// Nodes, Next returns nil, false.
+	Next() (Node, bool)
+
+	// Reset restarts the iteration from the beginning of the sequence.
+	Reset()
+
+	// Close abandons the iteration and releases any state held by the
+	// iterator.  After Close, Next always returns nil, false until the
+	// iterator is Reset.
+	Close() error
+}
+
+// funcIterator implements NodeIterator with a function that creates a new
+// "next" function every time the iterator is (re)started.
+type funcIterator struct {
+	start func() func() (Node, bool)
+	next  func() (Node, bool)
+}
+
+// NewFuncNodeIterator creates a NodeIterator from a start function.  start is
+// called to create the function that yields each Node of the sequence and is
+// called again every time the iterator is Reset.
+func NewFuncNodeIterator(start func() func() (Node, bool)) NodeIterator {
+	return &funcIterator{start: start, next: start()}
+}
+
+func (it *funcIterator) Next() (Node, bool) {
+	if it.next == nil {
+		return nil, false
+	}
+	node, ok := it.next()
+	if !ok {
+		it.next = nil
+	}
+	return node, ok
+}
+
+func (it *funcIterator) Reset() {
+	it.next = it.start()
+}
+
+func (it *funcIterator) Close() error {
+	it.next = nil
+	return nil
+}
+
+// sliceIterator iterates over a slice of Nodes.
+type sliceIterator struct {
+	nodes []Node
+	index int
+}
+
+// NewSliceNodeIterator creates a NodeIterator over the Nodes in a slice.
+func NewSliceNodeIterator(nodes []Node) NodeIterator {
+	return &sliceIterator{nodes: nodes}
+}
+
+func (it *sliceIterator) Next() (Node, bool) {
+	if it.index >= len(it.nodes) {
+		return nil, false
+	}
+	it.index++
+	return it.nodes[it.index-1], true
+}
+
+func (it *sliceIterator) Reset() {
+	it.index = 0
+}
+
+func (it *sliceIterator) Close() error {
+	it.index = len(it.nodes)
+	return nil
+}
+
+// filterIterator yields only the Nodes of another iterator that match a
+// predicate.
+type filterIterator struct {
+	NodeIterator
+	predicate func(n Node) bool
+}
+
+// FilterNodes creates a NodeIterator that only yields the Nodes from iter for
+// which predicate returns true.  Resetting or closing the returned iterator
+// resets or closes iter.
+func FilterNodes(iter NodeIterator, predicate func(n Node) bool) NodeIterator {
+	return &filterIterator{NodeIterator: iter, predicate: predicate}
+}
+
+func (it *filterIterator) Next() (Node, bool) {
+	for {
+		node, ok := it.NodeIterator.Next()
+		if !ok {
+			return nil, false
+		}
+		if it.predicate(node) {
+			return node, true
+		}
+	}
+}
+
+// CollectNodes drains the rest of an iterator into a slice and closes it.
+func CollectNodes(iter NodeIterator) (nodes []Node, err error) {
+	defer CatchDeferred(&err, iter.Close)
+	for node, ok := iter.Next(); ok; node, ok = iter.Next() {
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
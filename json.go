@@ -0,0 +1,100 @@
+package skink
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+
+	"github.com/skillian/errors"
+)
+
+// jsonNodeDef is the JSON representation of a NodeDef:
+//
+//	{
+//	  "name": "servers",
+//	  "class": "dynamic#servers",
+//	  "value": "",
+//	  "children": [ ... ]
+//	}
+//
+// "value" and "children" are omitted when they're empty.  A NodeDef without a
+// ClassURI has no "class".
+type jsonNodeDef struct {
+	Name     string     `json:"name"`
+	Class    string     `json:"class,omitempty"`
+	Value    string     `json:"value,omitempty"`
+	Children []*NodeDef `json:"children,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.  See WriteJSON for the JSON shape.
+func (n *NodeDef) MarshalJSON() ([]byte, error) {
+	j := jsonNodeDef{
+		Name:     n.Name.String(),
+		Value:    n.Value,
+		Children: n.Children,
+	}
+	if n.ClassURI != nil {
+		j.Class = n.ClassURI.String()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.  The Parents of the unmarshaled
+// children are set to n; n's own Parent is left as-is.
+func (n *NodeDef) UnmarshalJSON(data []byte) error {
+	j := jsonNodeDef{}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	n.Name = MakeString(j.Name)
+	n.ClassURI = nil
+	if j.Class != "" {
+		classuri, err := url.Parse(j.Class)
+		if err != nil {
+			return errors.ErrorfWithCause(
+				err,
+				"failed to parse class URI %q of %v: %v",
+				j.Class, j.Name, err)
+		}
+		n.ClassURI = classuri
+	}
+	n.Value = j.Value
+	n.Children = make([]*NodeDef, 0, len(j.Children))
+	for _, child := range j.Children {
+		child.Parent = n
+		n.Children = append(n.Children, child)
+	}
+	return nil
+}
+
+// WriteJSON writes a NodeDef tree to w as indented JSON.  Each NodeDef is an
+// object with its "name", its ClassURI as "class", its "value" and its
+// "children" as an array of objects of the same shape.  Empty values and
+// children are omitted.  To write a Node tree, convert it with
+// ExportNodeDef first.
+func WriteJSON(w io.Writer, nodedef *NodeDef) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(nodedef)
+}
+
+// ReadJSON reads a NodeDef tree in the shape written by WriteJSON.
+// Syntax and type errors are returned as a *LoadError with their position.
+func ReadJSON(r io.Reader) (*NodeDef, error) {
+	position := newPositionReader(r)
+	nodedef := new(NodeDef)
+	if err := json.NewDecoder(position).Decode(nodedef); err != nil {
+		wrapped := errors.ErrorfWithCause(
+			err,
+			"failed to decode NodeDef from JSON: %v",
+			err)
+		switch err := err.(type) {
+		case *json.SyntaxError:
+			return nil, position.loadError(err.Offset, wrapped)
+		case *json.UnmarshalTypeError:
+			return nil, position.loadError(err.Offset, wrapped)
+		}
+		return nil, wrapped
+	}
+	return nodedef, nil
+}
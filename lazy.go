@@ -0,0 +1,209 @@
+package skink
+
+import (
+	"net/url"
+	"sync"
+
+	"github.com/skillian/errors"
+)
+
+// lazyNodeMap is a NodeMap whose Nodes are loaded the first time they're
+// needed.
+type lazyNodeMap struct {
+	m    NodeMap
+	once *sync.Once
+	load func() ([]Node, error)
+	err  *error
+}
+
+// NewLazyNodeMap creates a NodeMap that calls load to get its Nodes the first
+// time that any of its Nodes are needed (hooks can be registered without
+// loading).  If load fails, the methods that return errors return the load
+// error and the rest act as if the NodeMap is empty.
+func NewLazyNodeMap(load func() ([]Node, error)) NodeMap {
+	return lazyNodeMap{
+		m:    NewNodeMap(0),
+		once: new(sync.Once),
+		load: load,
+		err:  new(error),
+	}
+}
+
+// NewNodeDefNodeMap creates a lazy NodeMap (see NewLazyNodeMap) of Nodes
+// created from the NodeDefs (and their descendants) with parent as their
+// parent.
+func (sk *Skink) NewNodeDefNodeMap(parent Node, nodedefs []*NodeDef) NodeMap {
+	return NewLazyNodeMap(func() ([]Node, error) {
+		return sk.createNodes(parent, nodedefs)
+	})
+}
+
+// NewURINodeMap creates a lazy NodeMap (see NewLazyNodeMap) of Nodes created
+// under parent from the children of the root NodeDef loaded from uri (see
+// CreateNodeDef).  The URI isn't loaded until the Nodes are needed.
+func (sk *Skink) NewURINodeMap(parent Node, uri *url.URL) NodeMap {
+	return NewLazyNodeMap(func() ([]Node, error) {
+		nodedef, err := sk.CreateNodeDef(uri)
+		if err != nil {
+			return nil, err
+		}
+		return sk.createNodes(parent, nodedef.Children)
+	})
+}
+
+// createNodes creates Nodes from each of the NodeDefs.
+func (sk *Skink) createNodes(parent Node, nodedefs []*NodeDef) ([]Node, error) {
+	nodes := make([]Node, len(nodedefs))
+	for i, nodedef := range nodedefs {
+		var err error
+		if nodes[i], err = sk.CreateNode(parent, nodedef); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// materialize loads the lazyNodeMap's Nodes if they're not loaded yet.
+func (m lazyNodeMap) materialize() error {
+	m.once.Do(func() {
+		nodes, err := m.load()
+		if err == nil {
+			err = m.m.AddNodes(nodes, false)
+		}
+		if err != nil {
+			*m.err = errors.ErrorfWithCause(
+				err,
+				"failed to load lazy NodeMap: %v",
+				err)
+			logger.Error1("%v", *m.err)
+		}
+		m.load = nil
+	})
+	return *m.err
+}
+
+func (m lazyNodeMap) AddNode(node Node, overwrite bool) error {
+	if err := m.materialize(); err != nil {
+		return err
+	}
+	return m.m.AddNode(node, overwrite)
+}
+
+func (m lazyNodeMap) AddNodes(nodes []Node, overwrite bool) error {
+	if err := m.materialize(); err != nil {
+		return err
+	}
+	return m.m.AddNodes(nodes, overwrite)
+}
+
+func (m lazyNodeMap) InsertNode(index int, node Node) error {
+	if err := m.materialize(); err != nil {
+		return err
+	}
+	return m.m.InsertNode(index, node)
+}
+
+func (m lazyNodeMap) Move(from, to int) error {
+	if err := m.materialize(); err != nil {
+		return err
+	}
+	return m.m.Move(from, to)
+}
+
+// Clone loads the NodeMap's Nodes and clones them into a new NodeMap.
+func (m lazyNodeMap) Clone() NodeMap {
+	m.materialize()
+	return m.m.Clone()
+}
+
+func (m lazyNodeMap) Merge(other NodeMap, overwrite bool) error {
+	if err := m.materialize(); err != nil {
+		return err
+	}
+	return m.m.Merge(other, overwrite)
+}
+
+func (m lazyNodeMap) Contains(node Node) bool {
+	m.materialize()
+	return m.m.Contains(node)
+}
+
+func (m lazyNodeMap) ContainsName(name String) bool {
+	m.materialize()
+	return m.m.ContainsName(name)
+}
+
+func (m lazyNodeMap) GetName(name String) (Node, error) {
+	if err := m.materialize(); err != nil {
+		return nil, err
+	}
+	return m.m.GetName(name)
+}
+
+func (m lazyNodeMap) GetAll(name String) []Node {
+	m.materialize()
+	return m.m.GetAll(name)
+}
+
+func (m lazyNodeMap) GetIndex(index int) (Node, error) {
+	if err := m.materialize(); err != nil {
+		return nil, err
+	}
+	return m.m.GetIndex(index)
+}
+
+func (m lazyNodeMap) Len() int {
+	m.materialize()
+	return m.m.Len()
+}
+
+func (m lazyNodeMap) Nodes() []Node {
+	m.materialize()
+	return m.m.Nodes()
+}
+
+func (m lazyNodeMap) NodesInto(nodes []Node) int {
+	m.materialize()
+	return m.m.NodesInto(nodes)
+}
+
+func (m lazyNodeMap) Range(f func(name String, n Node) bool) {
+	m.materialize()
+	m.m.Range(f)
+}
+
+func (m lazyNodeMap) OnAdd(hook NodeMapHook) func() {
+	return m.m.OnAdd(func(_ NodeMap, n Node) { hook(m, n) })
+}
+
+func (m lazyNodeMap) OnRemove(hook NodeMapHook) func() {
+	return m.m.OnRemove(func(_ NodeMap, n Node) { hook(m, n) })
+}
+
+func (m lazyNodeMap) Rename(old, new String) error {
+	if err := m.materialize(); err != nil {
+		return err
+	}
+	return m.m.Rename(old, new)
+}
+
+func (m lazyNodeMap) RemoveName(name String) error {
+	if err := m.materialize(); err != nil {
+		return err
+	}
+	return m.m.RemoveName(name)
+}
+
+func (m lazyNodeMap) RemoveIndex(index int) error {
+	if err := m.materialize(); err != nil {
+		return err
+	}
+	return m.m.RemoveIndex(index)
+}
+
+func (m lazyNodeMap) Remove(node Node) error {
+	if err := m.materialize(); err != nil {
+		return err
+	}
+	return m.m.Remove(node)
+}
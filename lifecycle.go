@@ -0,0 +1,134 @@
+package skink
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// LifecyclePhase is a phase of a Node's lifecycle.
+type LifecyclePhase string
+
+const (
+	// CreatePhase is when a Node is created from its NodeDef.
+	CreatePhase LifecyclePhase = "create"
+
+	// InitPhase is when a Node is initialized (see InitNoder).
+	InitPhase LifecyclePhase = "init"
+
+	// StartPhase is when a Node is started (see StartNoder).
+	StartPhase LifecyclePhase = "start"
+)
+
+// LifecycleEvent describes a phase of a single Node's lifecycle.
+type LifecycleEvent struct {
+	// Path is the path of the Node (see GetPath).
+	Path string
+
+	// ClassURI is the URI of the Node's Class or empty if the Class isn't
+	// registered.
+	ClassURI string
+
+	Phase LifecyclePhase
+
+	// Time is when the phase started and Duration is how long it took.
+	Time     time.Time
+	Duration time.Duration
+
+	// Err is the error that the phase failed with or nil if it succeeded.
+	Err error
+}
+
+// Fields gets the event's fields keyed by the names used by
+// JSONLogAdapter so that they can be passed to other structured loggers.
+func (e LifecycleEvent) Fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"path":        e.Path,
+		"class":       e.ClassURI,
+		"phase":       string(e.Phase),
+		"time":        e.Time.Format(time.RFC3339Nano),
+		"duration_ms": float64(e.Duration) / float64(time.Millisecond),
+	}
+	if e.Err != nil {
+		fields["error"] = e.Err.Error()
+		fields["error_kind"] = ErrorKindOf(e.Err).String()
+	}
+	return fields
+}
+
+// LogAdapter receives the LifecycleEvents of a Skink context's Nodes (see
+// Skink.LogAdapter).  Events are sent from the goroutines that initialize
+// and start the Nodes so LogEvent must be safe for concurrent use.
+type LogAdapter interface {
+	LogEvent(event LifecycleEvent)
+}
+
+// LogAdapterFunc adapts a function into a LogAdapter.
+type LogAdapterFunc func(event LifecycleEvent)
+
+// LogEvent implements LogAdapter.
+func (f LogAdapterFunc) LogEvent(event LifecycleEvent) {
+	f(event)
+}
+
+// jsonLogAdapter writes LifecycleEvents as JSON objects, one per line.
+type jsonLogAdapter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONLogAdapter creates a LogAdapter that writes each LifecycleEvent to w
+// as a JSON object of its Fields on its own line.
+func NewJSONLogAdapter(w io.Writer) LogAdapter {
+	return &jsonLogAdapter{encoder: json.NewEncoder(w)}
+}
+
+func (a *jsonLogAdapter) LogEvent(event LifecycleEvent) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if err := a.encoder.Encode(event.Fields()); err != nil {
+		logger.Warn1("failed to write lifecycle event: %v", err)
+	}
+}
+
+// logLifecycle records node's phase that started at start in the Skink
+// context's StartupProfile and sends a LifecycleEvent of it to the context's
+// LogAdapter (if it has one).
+func (sk *Skink) logLifecycle(node Node, phase LifecyclePhase, start time.Time, err error) {
+	duration := time.Since(start)
+	sk.profile.record(node, phase, duration)
+	if sk.LogAdapter == nil {
+		return
+	}
+	event := LifecycleEvent{
+		Path:     GetPath(node),
+		Phase:    phase,
+		Time:     start,
+		Duration: duration,
+		Err:      err,
+	}
+	if uri, ok := GetClassURI(node.Class()); ok {
+		event.ClassURI = uri.String()
+	}
+	sk.LogAdapter.LogEvent(event)
+}
+
+// logNodeDefLifecycle is like logLifecycle but for when a Node couldn't be
+// created from nodedef.
+func (sk *Skink) logNodeDefLifecycle(nodedef *NodeDef, phase LifecyclePhase, start time.Time, err error) {
+	if sk.LogAdapter == nil {
+		return
+	}
+	event := LifecycleEvent{
+		Path:     nodeDefPath(nodedef),
+		Phase:    phase,
+		Time:     start,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	if nodedef.ClassURI != nil {
+		event.ClassURI = nodedef.ClassURI.String()
+	}
+	sk.LogAdapter.LogEvent(event)
+}
@@ -0,0 +1,290 @@
+package skink
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Issue is a problem that a LintRule found in a NodeDef tree.  Unlike
+// validation errors, Issues don't stop the tree from being used; they're
+// reported as Warnings by ValidateNodeDef.
+type Issue struct {
+	// Path is the path of the NodeDef that the Issue is about.  Lint fills
+	// it in with the path of the NodeDef that the rule was called with if
+	// the rule leaves it empty.
+	Path string
+
+	// Rule is the name of the LintRule that found the Issue.  Lint fills
+	// it in.
+	Rule string
+
+	Message string
+}
+
+// String implements fmt.Stringer.
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s (%s)", i.Path, i.Message, i.Rule)
+}
+
+// LintRule checks a NodeDef for Issues.  Lint calls each LintRule with every
+// NodeDef in a tree, so rules about whole trees should only check them when
+// they're called with the root (i.e. the NodeDef without a Parent).
+type LintRule func(def *NodeDef) []Issue
+
+// lintRule is a registered LintRule.
+type lintRule struct {
+	name string
+	rule LintRule
+}
+
+var (
+	lintRules      []lintRule
+	lintRulesMutex sync.RWMutex
+
+	// deprecatedClassURIs maps the lower-case URIs of deprecated Classes to
+	// their exact URIs and replacements (see DeprecateClassURI).
+	deprecatedClassURIs = map[string]deprecatedClassURI{}
+)
+
+func init() {
+	RegisterLintRule("unknown-class", LintUnknownClass)
+	RegisterLintRule("renamed-duplicate", LintRenamedDuplicate)
+	RegisterLintRule("unused-caller", LintUnusedCaller)
+	RegisterLintRule("deprecated-class", LintDeprecatedClass)
+}
+
+// RegisterLintRule registers a LintRule under name so that Lint (and so
+// ValidateNodeDef) runs it.  Registering another rule under the same name
+// replaces the rule.
+func RegisterLintRule(name string, rule LintRule) {
+	lintRulesMutex.Lock()
+	defer lintRulesMutex.Unlock()
+	for i := range lintRules {
+		if lintRules[i].name == name {
+			lintRules[i].rule = rule
+			return
+		}
+	}
+	lintRules = append(lintRules, lintRule{name: name, rule: rule})
+}
+
+// UnregisterLintRule removes the LintRule registered under name.
+func UnregisterLintRule(name string) {
+	lintRulesMutex.Lock()
+	defer lintRulesMutex.Unlock()
+	for i := range lintRules {
+		if lintRules[i].name == name {
+			lintRules = append(lintRules[:i], lintRules[i+1:]...)
+			return
+		}
+	}
+}
+
+// Lint runs the registered LintRules, in the order they were registered,
+// with every NodeDef in root's tree (depth-first, parents before children)
+// and gets the Issues they found.  Panics in rules are reported as Issues.
+func Lint(root *NodeDef) []Issue {
+	lintRulesMutex.RLock()
+	rules := make([]lintRule, len(lintRules))
+	copy(rules, lintRules)
+	lintRulesMutex.RUnlock()
+	var issues []Issue
+	var walk func(def *NodeDef)
+	walk = func(def *NodeDef) {
+		for _, rule := range rules {
+			var found []Issue
+			err := Safely(func() error {
+				found = rule.rule(def)
+				return nil
+			})
+			if err != nil {
+				found = []Issue{{Message: err.Error()}}
+			}
+			for _, issue := range found {
+				if issue.Path == "" {
+					issue.Path = nodeDefPath(def)
+				}
+				issue.Rule = rule.name
+				issues = append(issues, issue)
+			}
+		}
+		for _, child := range def.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	return issues
+}
+
+// LintUnknownClass reports NodeDefs whose Classes aren't registered and so
+// are (or would be) created dynamically (see CreateDynamicClass).  The
+// Classes of XML elements without a namespace (e.g. "dynamic#name") are
+// meant to be dynamic, so they aren't reported.  NodeDefs without a ClassURI
+// are reported by ValidateNodeDef instead.
+func LintUnknownClass(def *NodeDef) []Issue {
+	if def.ClassURI == nil || isDynamicClassURI(def.ClassURI) {
+		return nil
+	}
+	cls, err := GetClassByURI(def.ClassURI)
+	if err == nil {
+		if nc, ok := cls.(*nodeclass); !ok || !nc.dynamic {
+			return nil
+		}
+	} else if _, ok := err.(ClassNotFound); !ok {
+		return nil
+	}
+	return []Issue{{Message: fmt.Sprintf(
+		"Class %v is not registered", def.ClassURI)}}
+}
+
+// LintRenamedDuplicate reports NodeDefs that were renamed because a sibling
+// already had their name (see NodeDef.OriginalName), which usually means
+// that a document repeats an element by mistake.
+func LintRenamedDuplicate(def *NodeDef) []Issue {
+	if def.OriginalName.String() == "" {
+		return nil
+	}
+	return []Issue{{Message: fmt.Sprintf(
+		"duplicate %v was renamed to %v", def.OriginalName, def.Name)}}
+}
+
+// DeprecateClassURI marks the Class URI old as deprecated in favor of
+// replacement (which can be empty if there is none) so that
+// LintDeprecatedClass reports the NodeDefs that still use it.
+func DeprecateClassURI(old, replacement string) {
+	lintRulesMutex.Lock()
+	defer lintRulesMutex.Unlock()
+	deprecatedClassURIs[strings.ToLower(old)] = deprecatedClassURI{
+		old:         old,
+		replacement: replacement,
+	}
+}
+
+// deprecatedClassURI is a Class URI deprecated with DeprecateClassURI.
+type deprecatedClassURI struct {
+	old, replacement string
+}
+
+// LintDeprecatedClass reports NodeDefs whose ClassURIs were deprecated with
+// DeprecateClassURI.
+func LintDeprecatedClass(def *NodeDef) []Issue {
+	if def.ClassURI == nil {
+		return nil
+	}
+	lintRulesMutex.RLock()
+	uri := def.ClassURI.String()
+	deprecated, ok := deprecatedClassURIs[strings.ToLower(uri)]
+	lintRulesMutex.RUnlock()
+	if !ok || (CaseSensitive() && deprecated.old != uri) {
+		return nil
+	}
+	message := fmt.Sprintf("Class %v is deprecated", def.ClassURI)
+	if deprecated.replacement != "" {
+		message += "; use " + deprecated.replacement + " instead"
+	}
+	return []Issue{{Message: message}}
+}
+
+// LintUnusedCaller reports the Callers defined in a tree (Scripts, Pipelines
+// and Caches) that nothing else in the tree refers to: they aren't the
+// source or target of a Connect, the target of a Cache, a step of a
+// Pipeline or called (or read) by a Script.  Methods aren't reported
+// because they're meant to be called from outside the tree.  Only the root
+// is checked since the references can be anywhere in the tree.
+func LintUnusedCaller(def *NodeDef) []Issue {
+	if def.Parent != nil {
+		return nil
+	}
+	var defined []*NodeDef
+	used := make(map[*NodeDef]bool)
+	var walk func(def *NodeDef)
+	walk = func(def *NodeDef) {
+		cls, _ := validateNodeDefClass(def)
+		switch {
+		case cls == nil:
+		case IsSubclass(cls, ScriptClass):
+			defined = append(defined, def)
+			for _, ref := range scriptReferences(def.Value) {
+				markLintReference(def, def, ref, used)
+			}
+		case IsSubclass(cls, PipelineClass):
+			defined = append(defined, def)
+			for _, step := range def.Children {
+				if step.Value != "" {
+					markLintReference(def, step, step.Value, used)
+				}
+			}
+		case IsSubclass(cls, CacheClass):
+			defined = append(defined, def)
+			if target := def.FindChild(cacheTargetName); target != nil {
+				markLintReference(def, def, target.Value, used)
+			}
+		case IsSubclass(cls, ConnectClass):
+			for _, name := range []String{connectSourceName, connectTargetName} {
+				if setting := def.FindChild(name); setting != nil {
+					markLintReference(def, def, setting.Value, used)
+				}
+			}
+		}
+		for _, child := range def.Children {
+			walk(child)
+		}
+	}
+	walk(def)
+	var issues []Issue
+	for _, d := range defined {
+		if !used[d] && !isPipelineStep(d) {
+			issues = append(issues, Issue{
+				Path:    nodeDefPath(d),
+				Message: "nothing in the tree refers to it",
+			})
+		}
+	}
+	return issues
+}
+
+// isPipelineStep checks if def is itself a step of a Pipeline.
+func isPipelineStep(def *NodeDef) bool {
+	if def.Parent == nil {
+		return false
+	}
+	cls, _ := validateNodeDefClass(def.Parent)
+	return cls != nil && IsSubclass(cls, PipelineClass)
+}
+
+// markLintReference marks the NodeDef that from refers to with path (which
+// is resolved like ResolveCaller's) as used unless it's the referring owner
+// itself.
+func markLintReference(owner, from *NodeDef, path string, used map[*NodeDef]bool) {
+	if path == "" {
+		return
+	}
+	base := from
+	if !strings.HasPrefix(path, NodePathSeparator) {
+		for base.Parent != nil {
+			base = base.Parent
+		}
+	}
+	if target, err := validateNodeDefReference(base, path); err == nil && target != owner {
+		used[target] = true
+	}
+}
+
+// scriptReferences gets the paths in a Script.  Scripts that don't parse are
+// reported by their Class's Init instead.
+func scriptReferences(source string) []string {
+	var refs []string
+	for _, line := range strings.Split(source, "\n") {
+		tokens, err := scanScriptLine(line)
+		if err != nil {
+			continue
+		}
+		for _, t := range tokens {
+			if t.kind == scriptPathToken && t.text != "return" {
+				refs = append(refs, t.text)
+			}
+		}
+	}
+	return refs
+}
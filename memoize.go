@@ -0,0 +1,258 @@
+package skink
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skillian/errors"
+)
+
+// MaxMemoizedResults is how many results a Caller returned by Memoize caches
+// at most.  When it's full, expired results are removed and, if that's not
+// enough, an arbitrary result is evicted.
+const MaxMemoizedResults = 1024
+
+// Memoize wraps c in a Caller that caches c's successful results by their
+// arguments (see ArgsKey) for ttl, so that expensive, idempotent calls
+// aren't repeated.  If ttl isn't positive, results are cached until they're
+// evicted (see MaxMemoizedResults).  Failed calls aren't cached.
+func Memoize(c Caller, ttl time.Duration) Caller {
+	return &memoizer{
+		caller:  c,
+		ttl:     ttl,
+		results: make(map[string]memoResult),
+	}
+}
+
+// memoizer is the Caller returned by Memoize.
+type memoizer struct {
+	caller Caller
+	ttl    time.Duration
+
+	mutex   sync.Mutex
+	results map[string]memoResult
+}
+
+// memoResult is a result cached by a memoizer.
+type memoResult struct {
+	result  Node
+	expires time.Time
+}
+
+// Call implements Caller.
+func (m *memoizer) Call(args NodeMap) (Node, error) {
+	key := ArgsKey(args)
+	now := time.Now()
+	m.mutex.Lock()
+	cached, ok := m.results[key]
+	if ok && m.ttl > 0 && !now.Before(cached.expires) {
+		delete(m.results, key)
+		ok = false
+	}
+	m.mutex.Unlock()
+	if ok {
+		return cached.result, nil
+	}
+	result, err := m.caller.Call(args)
+	if err != nil {
+		return nil, err
+	}
+	m.mutex.Lock()
+	if _, ok := m.results[key]; !ok && len(m.results) >= MaxMemoizedResults {
+		m.evict(now)
+	}
+	m.results[key] = memoResult{result: result, expires: now.Add(m.ttl)}
+	m.mutex.Unlock()
+	return result, nil
+}
+
+// evict makes room for a result by removing the expired results or, if
+// none have expired, an arbitrary one.  The mutex must be held.
+func (m *memoizer) evict(now time.Time) {
+	if m.ttl > 0 {
+		for key, cached := range m.results {
+			if !now.Before(cached.expires) {
+				delete(m.results, key)
+			}
+		}
+	}
+	for key := range m.results {
+		if len(m.results) < MaxMemoizedResults {
+			return
+		}
+		delete(m.results, key)
+	}
+}
+
+// clear removes all of the cached results.
+func (m *memoizer) clear() {
+	m.mutex.Lock()
+	m.results = make(map[string]memoResult)
+	m.mutex.Unlock()
+}
+
+// ArgsKey computes a stable hash of the arguments of a call for Memoize.
+// Arguments are hashed by their (case-insensitive) names regardless of their
+// order, along with their values' types and values (see Value) and their
+// children, recursively.  Arguments that aren't Values are also hashed by
+// their identity (their paths and addresses), so different Nodes with the
+// same children don't share results.
+func ArgsKey(args NodeMap) string {
+	h := sha256.New()
+	if args == nil {
+		return string(h.Sum(nil))
+	}
+	names := make([]string, 0, args.Len())
+	nodes := make(map[string]Node, args.Len())
+	args.Range(func(name String, arg Node) bool {
+		names = append(names, name.Lower())
+		nodes[name.Lower()] = arg
+		return true
+	})
+	sort.Strings(names)
+	for _, name := range names {
+		writeFingerprintString(h, name)
+		writeArgFingerprint(h, UnwrapArg(nodes[name]))
+	}
+	return string(h.Sum(nil))
+}
+
+// writeArgFingerprint writes an argument Node's value and children like
+// writeFingerprint writes NodeDefs.
+func writeArgFingerprint(h hash.Hash, node Node) {
+	if v, ok := node.(Value); ok {
+		value := v.Value()
+		writeFingerprintString(h, fmt.Sprintf("%T", value))
+		writeFingerprintString(h, fmt.Sprint(value))
+	} else {
+		writeFingerprintString(h, fmt.Sprintf("%T", node))
+		writeFingerprintString(h, nodeIdentity(node))
+	}
+	children := childNodes(node)
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(children)))
+	h.Write(length[:])
+	for _, child := range children {
+		writeFingerprintString(h, child.Name().Lower())
+		writeArgFingerprint(h, child)
+	}
+}
+
+// nodeIdentity identifies a Node that isn't a Value for ArgsKey by its path
+// and, if it's a pointer, its address.
+func nodeIdentity(node Node) string {
+	if node == nil {
+		return ""
+	}
+	identity := GetPath(node)
+	if v := reflect.ValueOf(node); v.Kind() == reflect.Ptr {
+		identity += fmt.Sprintf("@%x", v.Pointer())
+	}
+	return identity
+}
+
+var (
+	cacheClassValue = nodeclass{
+		name:        MakeString("Cache"),
+		base:        &nodeClassValue,
+		allocator:   allocCacheNode,
+		initializer: initCacheNode,
+	}
+
+	// CacheClass is the Class of CacheNodes.
+	CacheClass = MustRegisterClassString(
+		"import:nodes#Cache", &cacheClassValue)
+
+	cacheTargetName = MakeString("target")
+	cacheTTLName    = MakeString("ttl")
+)
+
+// CacheNode memoizes (see Memoize) the results of a Caller in configuration
+// so that every Node that calls it shares the cached results.  Its settings
+// are the Values of its children:
+//
+//   - "target" is the path of the Caller to cache, such as a Method.  It's
+//     resolved like ResolveCaller's when the CacheNode is started.
+//   - "ttl" is how long the results are cached as a time.Duration (e.g.
+//     "5m").  Without it, results are cached forever.
+type CacheNode struct {
+	BasicNode
+	target string
+	ttl    time.Duration
+
+	mutex  sync.RWMutex
+	caller *memoizer
+}
+
+func allocCacheNode(nodeDef *NodeDef) (Node, error) {
+	return new(CacheNode), nil
+}
+
+func initCacheNode(self, parent Node, nodeDef *NodeDef) error {
+	c, ok := self.(*CacheNode)
+	if !ok {
+		return errors.Errorf(
+			"CacheClass cannot init %T, only *CacheNode.", self)
+	}
+	target := nodeDef.FindChild(cacheTargetName)
+	if target == nil || target.Value == "" {
+		return errors.Errorf(
+			"Cache %v has no %v", nodeDef.Name, cacheTargetName)
+	}
+	c.target = target.Value
+	if ttl := nodeDef.FindChild(cacheTTLName); ttl != nil && ttl.Value != "" {
+		d, err := time.ParseDuration(ttl.Value)
+		if err != nil {
+			return errors.ErrorfWithCause(
+				err,
+				"invalid %v of Cache %v: %v",
+				cacheTTLName, nodeDef.Name, err)
+		}
+		c.ttl = d
+	}
+	return initBasicNode(&c.BasicNode, parent, nodeDef)
+}
+
+// StartNode implements StartNoder.  The target is resolved when the
+// CacheNode is started so that it can be anywhere in the tree.
+func (c *CacheNode) StartNode(sk *Skink, root Node) error {
+	target, err := ResolveCaller(c, c.target)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	c.caller = Memoize(target, c.ttl).(*memoizer)
+	c.mutex.Unlock()
+	return nil
+}
+
+// TTL gets how long the CacheNode caches results.  Zero means forever.
+func (c *CacheNode) TTL() time.Duration { return c.ttl }
+
+// Call implements Caller.  The CacheNode must be started first.
+func (c *CacheNode) Call(args NodeMap) (Node, error) {
+	c.mutex.RLock()
+	caller := c.caller
+	c.mutex.RUnlock()
+	if caller == nil {
+		return nil, errors.Errorf(
+			"Cache %v has not been started", GetPath(c))
+	}
+	return caller.Call(args)
+}
+
+// Invalidate removes all of the CacheNode's cached results.
+func (c *CacheNode) Invalidate() {
+	c.mutex.RLock()
+	caller := c.caller
+	c.mutex.RUnlock()
+	if caller != nil {
+		caller.clear()
+	}
+}
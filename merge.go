@@ -0,0 +1,161 @@
+package skink
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+// MergeStrategy determines how an overlay NodeDef is merged into a base
+// NodeDef by MergeNodeDefs.
+type MergeStrategy int
+
+const (
+	// MergeDeep keeps the base NodeDef and overrides its ClassURI and Value
+	// with the overlay's (if the overlay's Value is not just whitespace).
+	// Children with the same name are merged recursively and the overlay's
+	// other children are appended.
+	MergeDeep MergeStrategy = iota
+
+	// MergeReplace replaces the base NodeDef (and all of its children) with
+	// the overlay.
+	MergeReplace
+
+	// MergeAppendChildren keeps the base NodeDef and appends all of the
+	// overlay's children to it.  Overlay children whose names are already
+	// used by the base are renamed (see NodeDef.UniqueChildName).
+	MergeAppendChildren
+)
+
+// String implements fmt.Stringer.
+func (s MergeStrategy) String() string {
+	switch s {
+	case MergeDeep:
+		return "deep"
+	case MergeReplace:
+		return "replace"
+	case MergeAppendChildren:
+		return "append"
+	}
+	return fmt.Sprintf("MergeStrategy(%d)", int(s))
+}
+
+// ParseMergeStrategy parses the name of a MergeStrategy (as returned by its
+// String method).  The name is case-insensitive.
+func ParseMergeStrategy(name string) (MergeStrategy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "deep":
+		return MergeDeep, nil
+	case "replace":
+		return MergeReplace, nil
+	case "append":
+		return MergeAppendChildren, nil
+	}
+	return MergeDeep, errors.Errorf("unknown merge strategy: %q", name)
+}
+
+var (
+	// MergeAttrName is the name of the child NodeDef (e.g. the XML
+	// attribute) in an overlay that overrides the MergeStrategy used for
+	// that NodeDef and its children.  It is removed from the merged result.
+	MergeAttrName = MakeString("merge")
+)
+
+// MergeNodeDefs merges the overlay NodeDef tree into the base NodeDef tree
+// and returns the merged tree.  Neither base nor overlay is modified.
+// strategy is used unless an overlay NodeDef has a MergeAttrName child
+// specifying another strategy for it and its descendants.
+func MergeNodeDefs(base, overlay *NodeDef, strategy MergeStrategy) (*NodeDef, error) {
+	return mergeNodeDefs(base, overlay, base.Parent, strategy)
+}
+
+func mergeNodeDefs(base, overlay, parent *NodeDef, strategy MergeStrategy) (*NodeDef, error) {
+	strategy, err := getMergeStrategy(overlay, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if strategy == MergeReplace {
+		return cloneOverlay(overlay, parent), nil
+	}
+	merged := NewNodeDef(base.Name, parent, base.ClassURI)
+	merged.Value = base.Value
+	for _, child := range base.Children {
+		merged.Children = append(merged.Children, child.Clone(merged))
+	}
+	if strategy == MergeDeep {
+		if overlay.ClassURI != nil {
+			merged.ClassURI = overlay.ClassURI
+		}
+		if strings.TrimSpace(overlay.Value) != "" {
+			merged.Value = overlay.Value
+		}
+	}
+	for _, child := range overlay.Children {
+		if child.Name.Equal(MergeAttrName) {
+			continue
+		}
+		if strategy == MergeDeep {
+			if i := indexOfChild(merged, child.Name); i >= 0 {
+				mergedChild, err := mergeNodeDefs(
+					merged.Children[i], child, merged, strategy)
+				if err != nil {
+					return nil, errors.ErrorfWithCause(
+						err,
+						"failed to merge %v into %v: %v",
+						child.Name, merged.Name, err)
+				}
+				merged.Children[i] = mergedChild
+				continue
+			}
+		}
+		clone := cloneOverlay(child, merged)
+		if name := merged.UniqueChildName(clone.Name); !name.Equal(clone.Name) {
+			clone.OriginalName = clone.Name
+			clone.Name = name
+		}
+		merged.Children = append(merged.Children, clone)
+	}
+	return merged, nil
+}
+
+// getMergeStrategy gets the MergeStrategy specified by an overlay NodeDef's
+// MergeAttrName child or the default if it has none.
+func getMergeStrategy(overlay *NodeDef, def MergeStrategy) (MergeStrategy, error) {
+	attr := overlay.FindChild(MergeAttrName)
+	if attr == nil {
+		return def, nil
+	}
+	strategy, err := ParseMergeStrategy(attr.Value)
+	if err != nil {
+		return def, errors.ErrorfWithCause(
+			err,
+			"invalid %v on %v: %v",
+			MergeAttrName, overlay.Name, err)
+	}
+	return strategy, nil
+}
+
+// cloneOverlay clones an overlay NodeDef without its MergeAttrName children.
+func cloneOverlay(overlay, parent *NodeDef) *NodeDef {
+	clone := NewNodeDef(overlay.Name, parent, overlay.ClassURI)
+	clone.Value = overlay.Value
+	for _, child := range overlay.Children {
+		if child.Name.Equal(MergeAttrName) {
+			continue
+		}
+		clone.Children = append(clone.Children, cloneOverlay(child, clone))
+	}
+	return clone
+}
+
+// indexOfChild gets the index of the child of n with the given name or -1 if
+// there is no such child.
+func indexOfChild(n *NodeDef, name String) int {
+	for i, child := range n.Children {
+		if child.Name.Equal(name) {
+			return i
+		}
+	}
+	return -1
+}
@@ -25,6 +25,11 @@ func (n *LeafNode) Name() String {
 	return n.NodeName
 }
 
+// SetName renames the Node.  See Renamer.
+func (n *LeafNode) SetName(name String) {
+	n.NodeName = name
+}
+
 // Parent gets the node's parent.
 func (n *LeafNode) Parent() Node {
 	return n.NodeParent
@@ -46,46 +51,19 @@ type BasicNode struct {
 // from one another to describe the hierarchy.
 const NodePathSeparator = "."
 
-// FindNodes returns a function that iterates over a root Node's descendants
-// to find nodes that match the filter.  Every time that returned function
-// is called, the next matching node is returned and the bool returned value
-// is true.  When all descendants are traversed and there are no more matches,
-// subsequent calls to this returned function will result in nil, false.
-func FindNodes(root Node, filter func(n Node) bool) func() (Node, bool) {
-	nodes := make([]Node, 1, DefaultNodeMapCapacity)
-	nodes[0] = root
-	return func() (Node, bool) {
-		for {
-			logger.Debug1("nodes: %#v", nodes)
-			if len(nodes) == 0 {
-				return nil, false
-			}
-			node := nodes[0]
-			nodes = append(nodes[1:], node.Children().Nodes()...)
-			if filter(node) {
-				return node, true
-			}
-		}
-	}
-}
-
-// FindNode finds a single node matching the given predicate
-func FindNode(root Node, predicate func(n Node) bool) (Node, bool) {
-	return FindNodes(root, predicate)()
-}
-
 // ConcurrentFunc is executed in calls to ForEach.
 type ConcurrentFunc func(node Node) error
 
 // ForEach executes a ConcurrentFunc on every node in the node iterator
 // concurrently and waits for them to all finish before returning.  If any of
 // the ConcurrentFuncs returns an error, that/those errors are returned in a
-// ConcurrentErrors.
-func ForEach(nodeIter func() (Node, bool), f ConcurrentFunc) *ConcurrentErrors {
+// ConcurrentErrors.  To only visit part of a tree, pass an iterator from
+// FindNodesWithOptions with a MaxDepth.
+func ForEach(nodeIter NodeIterator, f ConcurrentFunc) *ConcurrentErrors {
 	wg := sync.WaitGroup{}
 	ce := NewConcurrentErrors()
 	for {
-		node, ok := nodeIter()
+		node, ok := nodeIter.Next()
 		if !ok {
 			break
 		}
@@ -108,72 +86,123 @@ func ForEach(nodeIter func() (Node, bool), f ConcurrentFunc) *ConcurrentErrors {
 // ForEachInSlice concurrently executes a function on each node in the given
 // slice.
 func ForEachInSlice(nodes []Node, f ConcurrentFunc) *ConcurrentErrors {
-	i := 0
-	iter := func() (Node, bool) {
-		if i >= len(nodes) {
-			return nil, false
-		}
-		i++
-		return nodes[i-1], true
-	}
-	return ForEach(iter, f)
+	return ForEach(NewSliceNodeIterator(nodes), f)
 }
 
 // FindParents starts at a child and goes through it's "ancestors" checking
 // if any match a predicate.  When a match is found, it is returned, if not,
 // nil, false is returned.
-func FindParents(node Node, predicate func(n Node) bool) func() (Node, bool) {
-	return func() (Node, bool) {
-		for {
-			if node == nil {
+func FindParents(node Node, predicate func(n Node) bool) NodeIterator {
+	return FilterNodes(NewFuncNodeIterator(func() func() (Node, bool) {
+		next := node
+		return func() (Node, bool) {
+			if next == nil {
 				return nil, false
 			}
-			child := node
-			node = node.Parent()
-			if predicate(child) {
-				return child, true
-			}
+			child := next
+			next = next.Parent()
+			return child, true
 		}
+	}), predicate)
+}
+
+// ContainsDescendant checks if node is a descendant of ancestor (i.e. if
+// ancestor is one of node's Parents).  A Node is not its own descendant.
+func ContainsDescendant(ancestor, node Node) bool {
+	if ancestor == nil || node == nil {
+		return false
 	}
+	for parent := node.Parent(); parent != nil; parent = parent.Parent() {
+		if parent == ancestor {
+			return true
+		}
+	}
+	return false
 }
 
 // GetChildByPath traverses a path from a parent node to a child and gets that
-// child node.
+// child node.  Names within the path that contain a NodePathSeparator must
+// have it escaped (see EscapeNodeName and JoinNodePath).
+//
+// Each leading NodePathSeparator in the path moves one level up to the
+// current node's parent before the rest of the path is traversed, so
+// ".Sibling" refers to a sibling of node and "..Uncle" to a sibling of
+// node's parent.  A path made only of separators refers to that ancestor.
+//
+// Paths that are resolved frequently should be compiled once with CompilePath
+// instead.
 func GetChildByPath(node Node, path string) (child Node, err error) {
-	parts := strings.Split(path, NodePathSeparator)
-	for _, part := range parts {
-		name := MakeString(part)
-		child, err = node.Children().GetName(name)
-		if err != nil {
-			return nil, NodeNotFound{Parent: node, Name: name}
-		}
-		node = child
+	p, err := CompilePath(path)
+	if err != nil {
+		return nil, err
 	}
-	return node, nil
+	return p.Resolve(node)
 }
 
-// GetPath gets the full path to the given node as a string
+// GetPath gets the full path to the given node as a string.  Node names
+// containing a NodePathSeparator are escaped (see EscapeNodeName).
 func GetPath(node Node) string {
-	parents := make([]Node, 1, DefaultNodeMapCapacity)
-	parents[0] = node
-	iter := FindParents(node, TruePred)
-	for parent, ok := iter(); ok; parent, ok = iter() {
-		parents = append(parents, parent)
+	return GetPathWithOptions(node, PathOptions{})
+}
+
+// PathOptions configures the format of the paths from GetPathWithOptions and
+// GetPathSegments.  The zero value formats paths just like GetPath.
+type PathOptions struct {
+	// ExcludeRoot leaves the root Node's name out of the path so that the
+	// path can be resolved from the root with GetChildByPath.
+	ExcludeRoot bool
+
+	// Separator separates the Node names in the path.  If it's empty,
+	// NodePathSeparator is used.  Occurrences of Separator within Node names
+	// are escaped with NodePathEscape.
+	Separator string
+}
+
+// GetPathWithOptions gets the path to the given node as a string formatted
+// according to options.
+func GetPathWithOptions(node Node, options PathOptions) string {
+	names := GetPathSegments(node, options)
+	strs := make([]string, len(names))
+	for i, name := range names {
+		strs[i] = name.String()
+	}
+	if options.Separator == "" || options.Separator == NodePathSeparator {
+		return JoinNodePath(strs...)
+	}
+	escaper := strings.NewReplacer(
+		NodePathEscape, NodePathEscape+NodePathEscape,
+		options.Separator, NodePathEscape+options.Separator)
+	for i, str := range strs {
+		strs[i] = escaper.Replace(str)
 	}
-	reversed := make([]string, len(parents))
-	for i, parent := range parents {
-		reversed[len(parents)-1-i] = parent.Name().String()
+	return strings.Join(strs, options.Separator)
+}
+
+// GetPathSegments gets the names of the Nodes from the root of node's tree
+// down to node itself.  Only options.ExcludeRoot is used.
+func GetPathSegments(node Node, options PathOptions) []String {
+	names := pathNames(node)
+	if options.ExcludeRoot {
+		return names[1:]
 	}
-	return strings.Join(reversed, NodePathSeparator)
+	return names
 }
 
 // NewNode constructs an instance of the given class with the given parent.
-func NewNode(cls Class, parent Node, nodeDef *NodeDef) (Node, error) {
-	node, err := cls.Alloc(nodeDef)
+// Panics in the Class's Alloc and Init are returned as PanicErrors (see
+// Safely).
+func NewNode(cls Class, parent Node, nodeDef *NodeDef) (node Node, err error) {
+	err = Safely(func() (err error) {
+		node, err = cls.Alloc(nodeDef)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	if err = cls.Init(node, parent, nodeDef); err != nil {
+	err = Safely(func() error {
+		return cls.Init(node, parent, nodeDef)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return node, nil
@@ -212,7 +241,7 @@ func initBasicNode(self, parent Node, nodeDef *NodeDef) (err error) {
 	if err = InitLeafNode(&n.LeafNode, parent, nodeDef); err != nil {
 		return err
 	}
-	n.NodeChildren = NewNodeMap(len(nodeDef.Children))
+	n.NodeChildren = MakeChildNodeMap(n.NodeClass, len(nodeDef.Children))
 	return nil
 }
 
@@ -1,185 +1,534 @@
-package skink
-
-import "github.com/skillian/errors"
-
-// NodeTypeAttrMap defines an ordered collection of TypeAttrs used to get the
-// value of a child node from a parent.
-type NodeTypeAttrMap struct {
-	index map[string]int
-	pairs []TypeAttr
-}
-
-// NewNodeTypeAttrMap creates a new NodeTypeAttrMap with its inner attributes
-// initialized
-func NewNodeTypeAttrMap() *NodeTypeAttrMap {
-	return &NodeTypeAttrMap{
-		index: make(map[string]int),
-		pairs: make([]TypeAttr, 0),
-	}
-}
-
-// AddTypeAttr defines a new type attribute in the current NodeTypeAttrMap.
-func (m *NodeTypeAttrMap) AddTypeAttr(a TypeAttr, overwrite bool) error {
-	existing, exists := m.TypeAttrByName(a.Name)
-	if exists && !overwrite {
-		return errors.Errorf("Attribute %s already defined", a.Name)
-	}
-	if !exists {
-		index := len(m.pairs)
-		m.index[a.Name.Lower()] = index
-		m.pairs = append(m.pairs, TypeAttr{})
-		existing = &m.pairs[index]
-	}
-	*existing = a
-	return nil
-}
-
-// Bind a Node to a NodeTypeAttrMap to get a NodeAttrMap.  This NodeAttrMap
-func (m *NodeTypeAttrMap) Bind(node Node) NodeAttrMap {
-	return NodeAttrMap{Node: node, NodeTypeAttrMap: m, dynamic: NewNodeMap(0)}
-}
-
-// ContainsKey returns whether or not a TypeAttr with the given key exists
-func (m *NodeTypeAttrMap) ContainsKey(key string) bool {
-	_, ok := m.index[key]
-	return ok
-}
-
-// Len gets the length of the NodeTypeAttrMap
-func (m *NodeTypeAttrMap) Len() int {
-	return len(m.pairs)
-}
-
-// MustAddTypeAttr should be used in package-level var blocks to initialize
-// a NodeTypeAttrMap
-func (m *NodeTypeAttrMap) MustAddTypeAttr(a TypeAttr, overwrite bool) *NodeTypeAttrMap {
-	PanicOnError(m.AddTypeAttr(a, overwrite))
-	return m
-}
-
-// TypeAttrByKey gets a TypeAttr by its key in the NodeTypeAttrMap's index
-func (m *NodeTypeAttrMap) TypeAttrByKey(key string) (*TypeAttr, bool) {
-	index, ok := m.index[key]
-	if !ok {
-		return nil, false
-	}
-	return &m.pairs[index], true
-}
-
-// TypeAttrByName gets a TypeAttr from the NodeTypeAttrMap by its name.
-func (m *NodeTypeAttrMap) TypeAttrByName(name String) (*TypeAttr, bool) {
-	return m.TypeAttrByKey(name.Lower())
-}
-
-// TypeAttr defines a Node attribute and how to get that attribute's value.
-type TypeAttr struct {
-	Name   String
-	Getter func(self Node) (Node, error)
-	Setter func(self, value Node) error
-}
-
-// NodeAttrMap binds a NodeTypeAttrMap to a Node.  It also has a fallback NodeMap
-// for dynamically defined attributes.
-type NodeAttrMap struct {
-	Node
-	*NodeTypeAttrMap
-	dynamic NodeMap
-}
-
-// AddNode adds a node to the dynamic NodeMap.
-func (m NodeAttrMap) AddNode(node Node, overwrite bool) error {
-	if a, ok := m.TypeAttrByName(node.Name()); ok {
-		return a.Setter(m.Node, node)
-	}
-	return m.dynamic.AddNode(node, overwrite)
-}
-
-// Contains returns true if the given child node is contained in the node this
-// NodeAttrMap is bound to.
-func (m NodeAttrMap) Contains(node Node) bool {
-	a, ok := m.NodeTypeAttrMap.TypeAttrByName(node.Name())
-	if ok {
-		if child, err := a.Getter(m.Node); err == nil {
-			return child == node
-		}
-	}
-	return m.dynamic.Contains(node)
-}
-
-// GetName tries to return a node from its NodeTypeAttrMap and falls back to its
-// dynamic NodeMap
-func (m NodeAttrMap) GetName(name String) (Node, error) {
-	if a, ok := m.NodeTypeAttrMap.TypeAttrByName(name); ok {
-		return a.Getter(m.Node)
-	}
-	return m.dynamic.GetName(name)
-}
-
-// GetIndex gets a child node by its index in the NodeAttrMap.  If the index
-// is less than the length of its NodeTypeAttrMap, the attribute is retrieved from
-// there.  If it's greater, subtract the length of the NodeTypeAttrMap from the
-// index and get that index out of the dynamic NodeMap.
-func (m NodeAttrMap) GetIndex(index int) (Node, error) {
-	length := m.Len()
-	index, ok := GetTrueIndex(length, index)
-	if !ok {
-		return nil, IndexError{Index: index, Length: length}
-	}
-	typelength := m.NodeTypeAttrMap.Len()
-	if index < typelength {
-		return m.NodeTypeAttrMap.pairs[index].Getter(m.Node)
-	}
-	return m.dynamic.GetIndex(index - typelength)
-}
-
-// Len gets the length of both the NodeTypeAttrMap and the dynamic NodeMap
-func (m NodeAttrMap) Len() int {
-	return m.NodeTypeAttrMap.Len() + m.dynamic.Len()
-}
-
-// Nodes gets all of the child nodes into a slice.
-func (m NodeAttrMap) Nodes() []Node {
-	nodes := make([]Node, m.Len())
-	var err error
-	for i, pair := range m.NodeTypeAttrMap.pairs {
-		nodes[i], err = pair.Getter(m.Node)
-		if err != nil {
-			panic(err)
-		}
-	}
-	_ = m.dynamic.(*nodemap).NodesInto(nodes[m.NodeTypeAttrMap.Len():])
-	return nodes
-}
-
-// RemoveName removes a child node by its name in the attribute.  If the
-// attribute is in the NodeTypeAttrMap, the removal will fail.
-func (m NodeAttrMap) RemoveName(name String) error {
-	if _, ok := m.TypeAttrByName(name); ok {
-		return errors.Errorf("cannot remove type attribute %v", name)
-	}
-	return m.dynamic.RemoveName(name)
-}
-
-// RemoveIndex removes an attribute at the given index from the node.
-// if the attribute is in the NodeTypeAttrMap, the removal will fail.
-func (m NodeAttrMap) RemoveIndex(index int) error {
-	mlen := m.Len()
-	index, ok := GetTrueIndex(mlen, index)
-	if ok {
-		tamlen := m.NodeTypeAttrMap.Len()
-		if index < tamlen {
-			return errors.Errorf("cannot remove type attribute at index %d", index)
-		}
-		return m.dynamic.RemoveIndex(index - tamlen)
-	}
-	return IndexError{index, mlen}
-}
-
-// Remove will remove a node from the dynamic NodeMap.  If the node is present
-// in the NodeTypeAttrMap, the removal will fail.
-func (m NodeAttrMap) Remove(node Node) error {
-	if _, ok := m.NodeTypeAttrMap.TypeAttrByName(node.Name()); ok {
-		return errors.Errorf("cannot remove attribute from NodeTypeAttrMap")
-	}
-	return m.dynamic.Remove(node)
-}
+package skink
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/skillian/errors"
+)
+
+// NodeTypeAttrMap defines an ordered collection of TypeAttrs used to get the
+// value of a child node from a parent.
+type NodeTypeAttrMap struct {
+	index map[string]int
+	pairs []TypeAttr
+
+	// base is the NodeTypeAttrMap whose TypeAttrs are inherited.
+	base *NodeTypeAttrMap
+
+	// version is (atomically) incremented whenever a TypeAttr is added so
+	// that derived NodeTypeAttrMaps know when to recalculate flat.
+	version int64
+
+	// flat holds the inherited TypeAttrs along with this map's own when
+	// there's a base.  It was calculated when the versions of the maps in
+	// the chain added up to flatStamp.  Both are guarded by flatMutex
+	// because they're calculated lazily by readers.
+	flatMutex sync.Mutex
+	flat      *NodeTypeAttrMap
+	flatStamp int64
+}
+
+// NewNodeTypeAttrMap creates a new NodeTypeAttrMap with its inner attributes
+// initialized
+func NewNodeTypeAttrMap() *NodeTypeAttrMap {
+	return &NodeTypeAttrMap{
+		index: make(map[string]int),
+		pairs: make([]TypeAttr, 0),
+	}
+}
+
+// NewDerivedNodeTypeAttrMap creates a new NodeTypeAttrMap that inherits the
+// TypeAttrs of base.  The inherited TypeAttrs come first (in base's order),
+// followed by the new map's own.  Inherited TypeAttrs can be overridden by
+// adding a TypeAttr with the same name and overwrite set; the override keeps
+// the inherited TypeAttr's position.  TypeAttrs added to base later are
+// inherited, too.
+func NewDerivedNodeTypeAttrMap(base *NodeTypeAttrMap) *NodeTypeAttrMap {
+	m := NewNodeTypeAttrMap()
+	m.base = base
+	return m
+}
+
+// NewClassNodeTypeAttrMap creates a NodeTypeAttrMap for a Class derived from
+// base.  If base (or its closest Base that does) implements TypeAttrMapper,
+// the new map is derived from base's map (see NewDerivedNodeTypeAttrMap).
+func NewClassNodeTypeAttrMap(base Class) *NodeTypeAttrMap {
+	for ; base != nil; base = base.Base() {
+		if mapper, ok := base.(TypeAttrMapper); ok {
+			return NewDerivedNodeTypeAttrMap(mapper.TypeAttrMap())
+		}
+	}
+	return NewNodeTypeAttrMap()
+}
+
+// Base gets the NodeTypeAttrMap that this one inherits from (or nil).
+func (m *NodeTypeAttrMap) Base() *NodeTypeAttrMap {
+	return m.base
+}
+
+// effective gets a NodeTypeAttrMap of both the inherited and own TypeAttrs.
+// If there's no base, that's m itself.
+func (m *NodeTypeAttrMap) effective() *NodeTypeAttrMap {
+	if m.base == nil {
+		return m
+	}
+	var stamp int64
+	for p := m; p != nil; p = p.base {
+		stamp += atomic.LoadInt64(&p.version)
+	}
+	m.flatMutex.Lock()
+	defer m.flatMutex.Unlock()
+	if m.flat != nil && m.flatStamp == stamp {
+		return m.flat
+	}
+	inherited := m.base.effective().pairs
+	flat := NewNodeTypeAttrMap()
+	flat.pairs = make([]TypeAttr, 0, len(inherited)+len(m.pairs))
+	for _, pairs := range [][]TypeAttr{inherited, m.pairs} {
+		for _, a := range pairs {
+			if index, ok := flat.index[a.Name.Lower()]; ok {
+				flat.pairs[index] = a
+				continue
+			}
+			flat.index[a.Name.Lower()] = len(flat.pairs)
+			flat.pairs = append(flat.pairs, a)
+		}
+	}
+	m.flat, m.flatStamp = flat, stamp
+	return flat
+}
+
+// AddTypeAttr defines a new type attribute in the current NodeTypeAttrMap.
+func (m *NodeTypeAttrMap) AddTypeAttr(a TypeAttr, overwrite bool) error {
+	if _, exists := m.TypeAttrByName(a.Name); exists && !overwrite {
+		return errors.Errorf("Attribute %s already defined", a.Name)
+	}
+	index, exists := m.index[a.Name.Lower()]
+	if !exists {
+		index = len(m.pairs)
+		m.index[a.Name.Lower()] = index
+		m.pairs = append(m.pairs, TypeAttr{})
+	}
+	m.pairs[index] = a
+	atomic.AddInt64(&m.version, 1)
+	return nil
+}
+
+// Bind a Node to a NodeTypeAttrMap to get a NodeAttrMap.  This NodeAttrMap
+func (m *NodeTypeAttrMap) Bind(node Node) NodeAttrMap {
+	return NodeAttrMap{Node: node, NodeTypeAttrMap: m, dynamic: NewNodeMap(0)}
+}
+
+// ContainsKey returns whether or not a TypeAttr with the given key exists
+func (m *NodeTypeAttrMap) ContainsKey(key string) bool {
+	_, ok := m.effective().index[key]
+	return ok
+}
+
+// Len gets the length of the NodeTypeAttrMap (including inherited TypeAttrs)
+func (m *NodeTypeAttrMap) Len() int {
+	return len(m.effective().pairs)
+}
+
+// MustAddTypeAttr should be used in package-level var blocks to initialize
+// a NodeTypeAttrMap
+func (m *NodeTypeAttrMap) MustAddTypeAttr(a TypeAttr, overwrite bool) *NodeTypeAttrMap {
+	PanicOnError(m.AddTypeAttr(a, overwrite))
+	return m
+}
+
+// TypeAttrByKey gets a TypeAttr by its key in the NodeTypeAttrMap's index
+func (m *NodeTypeAttrMap) TypeAttrByKey(key string) (*TypeAttr, bool) {
+	e := m.effective()
+	index, ok := e.index[key]
+	if !ok {
+		return nil, false
+	}
+	return &e.pairs[index], true
+}
+
+// TypeAttrByName gets a TypeAttr from the NodeTypeAttrMap by its name.
+func (m *NodeTypeAttrMap) TypeAttrByName(name String) (*TypeAttr, bool) {
+	return m.TypeAttrByKey(name.Lower())
+}
+
+// TypeAttr defines a Node attribute and how to get that attribute's value.
+type TypeAttr struct {
+	Name   String
+	Getter func(self Node) (Node, error)
+	Setter func(self, value Node) error
+
+	// Class is the Class of the attribute's value.  If it's nil, the value
+	// can be of any Class.
+	Class Class
+
+	// Required is true if the attribute must be defined in configuration
+	// documents.
+	Required bool
+
+	// Converters convert a value Node (e.g. a StringNode loaded from an XML
+	// attribute) into the Node that's passed to Setter.  Each converter
+	// gets the previous one's result.
+	Converters []TypeAttrConverter
+
+	// Validators check the converted value before it's passed to Setter.
+	Validators []TypeAttrValidator
+}
+
+// TypeAttrConverter converts the value of a TypeAttr.  See TypeAttr.Converters.
+type TypeAttrConverter func(value Node) (Node, error)
+
+// TypeAttrValidator checks the value of a TypeAttr.  See TypeAttr.Validators.
+type TypeAttrValidator func(value Node) error
+
+// Prepare runs the TypeAttr's Converters and then its Validators on a value and
+// checks that the result is of the TypeAttr's Class.  The converted value is
+// returned.
+func (a *TypeAttr) Prepare(value Node) (Node, error) {
+	var err error
+	for i, convert := range a.Converters {
+		if value, err = convert(value); err != nil {
+			return nil, err
+		}
+		if value == nil {
+			return nil, errors.Errorf(
+				"converter %d of attribute %v returned a nil Node",
+				i, a.Name)
+		}
+	}
+	if a.Class != nil && !IsSubclass(value.Class(), a.Class) {
+		return nil, errors.Errorf(
+			"value %v is not a %v", value.Name(), a.Class.Name())
+	}
+	for _, validate := range a.Validators {
+		if err = validate(value); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// TypeAttrMapper is implemented by Classes whose Nodes have a
+// NodeTypeAttrMap so that the attributes can be discovered from the Class
+// (e.g. to generate schemas).
+type TypeAttrMapper interface {
+	TypeAttrMap() *NodeTypeAttrMap
+}
+
+// TypeAttrs gets the NodeTypeAttrMap's TypeAttrs (including inherited ones)
+// in order.
+func (m *NodeTypeAttrMap) TypeAttrs() []TypeAttr {
+	pairs := m.effective().pairs
+	attrs := make([]TypeAttr, len(pairs))
+	copy(attrs, pairs)
+	return attrs
+}
+
+// NodeAttrMap binds a NodeTypeAttrMap to a Node.  It also has a fallback NodeMap
+// for dynamically defined attributes.
+type NodeAttrMap struct {
+	Node
+	*NodeTypeAttrMap
+	dynamic NodeMap
+}
+
+// Attrs gets the TypeAttrs of the NodeAttrMap's NodeTypeAttrMap.  Their Nodes
+// come first in the NodeAttrMap.
+func (m NodeAttrMap) Attrs() []TypeAttr {
+	return m.NodeTypeAttrMap.TypeAttrs()
+}
+
+// DynamicNodes gets the Nodes that were added to the NodeAttrMap that aren't
+// type attributes.  They come after the type attributes in the NodeAttrMap.
+func (m NodeAttrMap) DynamicNodes() []Node {
+	return m.dynamic.Nodes()
+}
+
+// RangeAttrs is like Range but also tells f whether each Node is a type
+// attribute (true) or a dynamic Node (false).
+func (m NodeAttrMap) RangeAttrs(f func(name String, n Node, isTypeAttr bool) bool) {
+	count := m.NodeTypeAttrMap.Len()
+	i := 0
+	m.Range(func(name String, n Node) bool {
+		isTypeAttr := i < count
+		i++
+		return f(name, n, isTypeAttr)
+	})
+}
+
+// AddNode adds a node to the dynamic NodeMap.
+func (m NodeAttrMap) AddNode(node Node, overwrite bool) error {
+	if a, ok := m.TypeAttrByName(node.Name()); ok {
+		var old Node
+		if a.Getter != nil {
+			old, _ = a.Getter(m.Node)
+		}
+		value, err := a.Prepare(node)
+		if err == nil {
+			err = a.Setter(m.Node, value)
+		}
+		if err != nil {
+			return errors.ErrorfWithCause(
+				err,
+				"failed to set attribute %v of Node %v: %v",
+				a.Name, GetPath(m.Node), err)
+		}
+		node = value
+		// Hooks are registered with the dynamic NodeMap, so they can only
+		// be called from here if it's a *nodemap.
+		if dynamic, ok := m.dynamic.(*nodemap); ok {
+			if old != nil && old != node {
+				notifyNodeAttrMap(m, dynamic.onRemove, old)
+			}
+			notifyNodeAttrMap(m, dynamic.onAdd, node)
+		}
+		return nil
+	}
+	return m.dynamic.AddNode(node, overwrite)
+}
+
+// notifyNodeAttrMap calls the hooks with the NodeAttrMap (instead of its
+// dynamic NodeMap) and the Node that was set.
+func notifyNodeAttrMap(m NodeAttrMap, hooks []*NodeMapHook, node Node) {
+	for _, hook := range hooks {
+		(*hook)(m, node)
+	}
+}
+
+// OnAdd registers a hook that is called when a dynamic Node is added or a
+// type attribute is set.
+func (m NodeAttrMap) OnAdd(hook NodeMapHook) func() {
+	return m.dynamic.OnAdd(m.rebind(hook))
+}
+
+// OnRemove registers a hook that is called when a dynamic Node is removed or
+// a type attribute is set to a different Node.
+func (m NodeAttrMap) OnRemove(hook NodeMapHook) func() {
+	return m.dynamic.OnRemove(m.rebind(hook))
+}
+
+// rebind wraps a hook registered on the dynamic NodeMap so that it's called
+// with the NodeAttrMap instead.
+func (m NodeAttrMap) rebind(hook NodeMapHook) NodeMapHook {
+	return func(_ NodeMap, n Node) {
+		hook(m, n)
+	}
+}
+
+// AddNodes adds each of the nodes with AddNode.
+func (m NodeAttrMap) AddNodes(nodes []Node, overwrite bool) error {
+	for _, node := range nodes {
+		if err := m.AddNode(node, overwrite); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InsertNode inserts a node into the dynamic NodeMap.  The index is relative to
+// the whole NodeAttrMap so it cannot be before the end of the NodeTypeAttrMap's
+// attributes.
+func (m NodeAttrMap) InsertNode(index int, node Node) error {
+	if _, ok := m.TypeAttrByName(node.Name()); ok {
+		return errors.Errorf("cannot insert type attribute %v", node.Name())
+	}
+	index, err := m.dynamicIndex(index, true)
+	if err != nil {
+		return err
+	}
+	return m.dynamic.InsertNode(index, node)
+}
+
+// Move moves a Node within the dynamic NodeMap.  Type attributes cannot be
+// moved.
+func (m NodeAttrMap) Move(from, to int) error {
+	from, err := m.dynamicIndex(from, false)
+	if err != nil {
+		return err
+	}
+	if to, err = m.dynamicIndex(to, false); err != nil {
+		return err
+	}
+	return m.dynamic.Move(from, to)
+}
+
+// dynamicIndex translates an index into the NodeAttrMap into an index into
+// its dynamic NodeMap.  If end is true, the index can be the NodeAttrMap's
+// Len.
+func (m NodeAttrMap) dynamicIndex(index int, end bool) (int, error) {
+	length := m.Len()
+	if !(end && index == length) {
+		var ok bool
+		if index, ok = GetTrueIndex(length, index); !ok {
+			return 0, IndexError{index, length}
+		}
+	}
+	tamlen := m.NodeTypeAttrMap.Len()
+	if index < tamlen {
+		return 0, errors.Errorf(
+			"cannot reorder type attribute at index %d", index)
+	}
+	return index - tamlen, nil
+}
+
+// Clone copies the type attributes' current Nodes and the dynamic Nodes into a
+// new NodeMap.  The copy isn't bound to the Node.
+func (m NodeAttrMap) Clone() NodeMap {
+	c := NewNodeMap(m.Len())
+	c.AddNodes(m.Nodes(), false)
+	return c
+}
+
+// Merge adds each of other's Nodes with AddNode.
+func (m NodeAttrMap) Merge(other NodeMap, overwrite bool) error {
+	return m.AddNodes(other.Nodes(), overwrite)
+}
+
+// Contains returns true if the given child node is contained in the node this
+// NodeAttrMap is bound to.
+func (m NodeAttrMap) Contains(node Node) bool {
+	a, ok := m.NodeTypeAttrMap.TypeAttrByName(node.Name())
+	if ok {
+		if child, err := a.Getter(m.Node); err == nil {
+			return child == node
+		}
+	}
+	return m.dynamic.Contains(node)
+}
+
+// ContainsName checks if the name is a type attribute or the name of a dynamic
+// Node.
+func (m NodeAttrMap) ContainsName(name String) bool {
+	if _, ok := m.NodeTypeAttrMap.TypeAttrByName(name); ok {
+		return true
+	}
+	return m.dynamic.ContainsName(name)
+}
+
+// GetName tries to return a node from its NodeTypeAttrMap and falls back to its
+// dynamic NodeMap
+func (m NodeAttrMap) GetName(name String) (Node, error) {
+	if a, ok := m.NodeTypeAttrMap.TypeAttrByName(name); ok {
+		return a.Getter(m.Node)
+	}
+	return m.dynamic.GetName(name)
+}
+
+// GetAll gets the type attribute with the name or all of the dynamic Nodes with
+// the name.
+func (m NodeAttrMap) GetAll(name String) []Node {
+	if a, ok := m.NodeTypeAttrMap.TypeAttrByName(name); ok {
+		if node, err := a.Getter(m.Node); err == nil && node != nil {
+			return []Node{node}
+		}
+		return nil
+	}
+	return m.dynamic.GetAll(name)
+}
+
+// GetIndex gets a child node by its index in the NodeAttrMap.  If the index
+// is less than the length of its NodeTypeAttrMap, the attribute is retrieved from
+// there.  If it's greater, subtract the length of the NodeTypeAttrMap from the
+// index and get that index out of the dynamic NodeMap.
+func (m NodeAttrMap) GetIndex(index int) (Node, error) {
+	length := m.Len()
+	index, ok := GetTrueIndex(length, index)
+	if !ok {
+		return nil, IndexError{Index: index, Length: length}
+	}
+	typelength := m.NodeTypeAttrMap.Len()
+	if index < typelength {
+		return m.NodeTypeAttrMap.effective().pairs[index].Getter(m.Node)
+	}
+	return m.dynamic.GetIndex(index - typelength)
+}
+
+// Len gets the length of both the NodeTypeAttrMap and the dynamic NodeMap
+func (m NodeAttrMap) Len() int {
+	return m.NodeTypeAttrMap.Len() + m.dynamic.Len()
+}
+
+// Nodes gets all of the child nodes into a slice.
+func (m NodeAttrMap) Nodes() []Node {
+	nodes := make([]Node, m.Len())
+	_ = m.NodesInto(nodes)
+	return nodes
+}
+
+// NodesInto writes the attributes' Nodes and then the dynamic Nodes into the
+// provided slice.  See NodeMap.NodesInto.
+func (m NodeAttrMap) NodesInto(nodes []Node) (written int) {
+	var err error
+	for _, pair := range m.NodeTypeAttrMap.effective().pairs {
+		if written == len(nodes) {
+			return written
+		}
+		nodes[written], err = pair.Getter(m.Node)
+		if err != nil {
+			panic(err)
+		}
+		written++
+	}
+	return written + m.dynamic.NodesInto(nodes[written:])
+}
+
+// Range calls f with each of the NodeTypeAttrMap's attributes and then each
+// of the dynamic Nodes until f returns false.
+func (m NodeAttrMap) Range(f func(name String, n Node) bool) {
+	for _, pair := range m.NodeTypeAttrMap.effective().pairs {
+		node, err := pair.Getter(m.Node)
+		if err != nil {
+			panic(err)
+		}
+		if !f(pair.Name, node) {
+			return
+		}
+	}
+	m.dynamic.Range(f)
+}
+
+// Rename renames a Node in the dynamic NodeMap.  Type attributes cannot be
+// renamed and Nodes cannot be renamed to a type attribute's name.
+func (m NodeAttrMap) Rename(old, new String) error {
+	if _, ok := m.TypeAttrByName(old); ok {
+		return errors.Errorf("cannot rename type attribute %v", old)
+	}
+	if _, ok := m.TypeAttrByName(new); ok {
+		return errors.Errorf("type attribute %v already exists", new)
+	}
+	return m.dynamic.Rename(old, new)
+}
+
+// RemoveName removes a child node by its name in the attribute.  If the
+// attribute is in the NodeTypeAttrMap, the removal will fail.
+func (m NodeAttrMap) RemoveName(name String) error {
+	if _, ok := m.TypeAttrByName(name); ok {
+		return errors.Errorf("cannot remove type attribute %v", name)
+	}
+	return m.dynamic.RemoveName(name)
+}
+
+// RemoveIndex removes an attribute at the given index from the node.
+// if the attribute is in the NodeTypeAttrMap, the removal will fail.
+func (m NodeAttrMap) RemoveIndex(index int) error {
+	mlen := m.Len()
+	index, ok := GetTrueIndex(mlen, index)
+	if ok {
+		tamlen := m.NodeTypeAttrMap.Len()
+		if index < tamlen {
+			return errors.Errorf("cannot remove type attribute at index %d", index)
+		}
+		return m.dynamic.RemoveIndex(index - tamlen)
+	}
+	return IndexError{index, mlen}
+}
+
+// Remove will remove a node from the dynamic NodeMap.  If the node is present
+// in the NodeTypeAttrMap, the removal will fail.
+func (m NodeAttrMap) Remove(node Node) error {
+	if _, ok := m.NodeTypeAttrMap.TypeAttrByName(node.Name()); ok {
+		return errors.Errorf("cannot remove attribute from NodeTypeAttrMap")
+	}
+	return m.dynamic.Remove(node)
+}
@@ -1,7 +1,12 @@
 package skink
 
 import (
+	"fmt"
 	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/skillian/errors"
 )
 
 // NodeDef structs are used by Skink internally as a standard form that
@@ -21,6 +26,23 @@ type NodeDef struct {
 
 	// Value holds a basic string of data
 	Value string
+
+	// XML records how the NodeDef was represented in an XML document.  It
+	// is nil unless the document was loaded with XMLOptions.Fidelity.
+	XML *XMLInfo
+
+	// Warnings are the messages added with AddWarning that haven't been
+	// collected by a Skink context yet.
+	Warnings []string
+
+	// OriginalName is the name that the NodeDef had in its document if it
+	// was renamed because a sibling already had it (see UniqueChildName).
+	// It's empty if the NodeDef wasn't renamed.
+	OriginalName String
+
+	// interpolated is true once Value has been interpolated (see
+	// InterpolateNodeDef) so that it isn't interpolated again.
+	interpolated bool
 }
 
 var (
@@ -56,9 +78,106 @@ func (n *NodeDef) NewChild(name String, classuri *url.URL) *NodeDef {
 // unique names for child nodes.
 func (n *NodeDef) FindChild(name String) *NodeDef {
 	for _, child := range n.Children {
-		if child.Name.Cmp(name) == 0 {
+		if child.Name.Equal(name) {
 			return child
 		}
 	}
 	return nil
 }
+
+// Clone creates a deep copy of the NodeDef and its children.  The copy's
+// Parent is set to parent but the copy is not added to parent's Children.
+// ClassURIs are shared between the NodeDef and its copy.
+func (n *NodeDef) Clone(parent *NodeDef) *NodeDef {
+	clone := NewNodeDef(n.Name, parent, n.ClassURI)
+	clone.Value = n.Value
+	clone.OriginalName = n.OriginalName
+	clone.interpolated = n.interpolated
+	clones := make(map[*NodeDef]*NodeDef, len(n.Children))
+	for _, child := range n.Children {
+		childclone := child.Clone(clone)
+		clones[child] = childclone
+		clone.Children = append(clone.Children, childclone)
+	}
+	clone.XML = n.XML.clone(clones)
+	return clone
+}
+
+// UniqueChildName gets a name based on name that none of the NodeDef's
+// children have.  If no child has name, name is returned, otherwise a number
+// is appended to it (starting at 2) until the name is unique.
+func (n *NodeDef) UniqueChildName(name String) String {
+	numbered := name
+	for number := 2; n.FindChild(numbered) != nil; number++ {
+		numbered = MakeString(fmt.Sprintf("%s%d", name, number))
+	}
+	return numbered
+}
+
+// ExportNodeDef converts a Node tree back into a NodeDef tree.  The NodeDefs'
+// ClassURIs are the URIs their Nodes' Classes were registered under (see
+// GetClassURI) and the Values of Nodes that implement the Value interface are
+// formatted with fmt.Sprint.
+func ExportNodeDef(node Node) (*NodeDef, error) {
+	return exportNodeDef(node, nil)
+}
+
+func exportNodeDef(node Node, parent *NodeDef) (*NodeDef, error) {
+	classuri, ok := GetClassURI(node.Class())
+	if !ok {
+		return nil, errors.Errorf(
+			"Class %v of Node %v is not registered",
+			node.Class(), GetPath(node))
+	}
+	nodedef := NewNodeDef(node.Name(), parent, classuri)
+	if v, ok := node.(Value); ok {
+		nodedef.Value = fmt.Sprint(v.Value())
+	}
+	for _, child := range childNodes(node) {
+		childdef, err := exportNodeDef(child, nodedef)
+		if err != nil {
+			return nil, err
+		}
+		nodedef.Children = append(nodedef.Children, childdef)
+	}
+	return nodedef, nil
+}
+
+// Normalize converts a NodeDef tree in place into a canonical form so that
+// trees that only differ by formatting compare equal: children are sorted by
+// name (then by ClassURI and Value), ClassURIs are lower-cased (unless Skink
+// is case-sensitive, see SetCaseSensitive) and Values that are only
+// whitespace are emptied.  Clone the tree first to keep the original.
+func Normalize(def *NodeDef) {
+	if def.ClassURI != nil && !CaseSensitive() {
+		lower := strings.ToLower(def.ClassURI.String())
+		if classuri, err := url.Parse(lower); err == nil {
+			def.ClassURI = classuri
+		}
+	}
+	if strings.TrimSpace(def.Value) == "" {
+		def.Value = ""
+	}
+	for _, child := range def.Children {
+		Normalize(child)
+	}
+	sort.SliceStable(def.Children, func(i, j int) bool {
+		a, b := def.Children[i], def.Children[j]
+		if c := a.Name.Cmp(b.Name); c != 0 {
+			return c < 0
+		}
+		if c := strings.Compare(classURIString(a), classURIString(b)); c != 0 {
+			return c < 0
+		}
+		return a.Value < b.Value
+	})
+}
+
+// classURIString gets the NodeDef's ClassURI as a string or an empty string if
+// it has no ClassURI.
+func classURIString(def *NodeDef) string {
+	if def.ClassURI == nil {
+		return ""
+	}
+	return def.ClassURI.String()
+}
@@ -15,13 +15,51 @@ type NodeMap interface {
 	// false but a node with the same name exists, an error is returned.
 	AddNode(node Node, overwrite bool) error
 
+	// AddNodes adds all of the nodes into the NodeMap like AddNode but grows
+	// the NodeMap only once.  If overwrite is false and any of the nodes'
+	// names already exist (or are repeated within nodes), an error is
+	// returned and none of the nodes are added.
+	AddNodes(nodes []Node, overwrite bool) error
+
+	// InsertNode inserts a node into the NodeMap at the given index, shifting
+	// the Nodes at and after the index back.  An index equal to the NodeMap's
+	// Len appends the Node.  If a node with the same name already exists, an
+	// error is returned.
+	InsertNode(index int, node Node) error
+
+	// Move moves the Node at index from to index to, shifting the Nodes in
+	// between.
+	Move(from, to int) error
+
+	// Clone creates an independent copy of the NodeMap with the same Nodes
+	// (the Nodes themselves are not copied).  Changes to either NodeMap do
+	// not affect the other and hooks are not copied.  Views of other
+	// NodeMaps (e.g. from FilterNodeMap or Overlay) are copied into a new
+	// NodeMap with their current contents.
+	Clone() NodeMap
+
+	// Merge adds the Nodes of other into the NodeMap in other's order, just
+	// like AddNodes.  Nodes whose names already exist are replaced in place
+	// if overwrite is true, otherwise an error is returned and nothing is
+	// merged.
+	Merge(other NodeMap, overwrite bool) error
+
 	// Contains checks if the NodeMap contains the given Node.
 	Contains(node Node) bool
 
+	// ContainsName checks if the NodeMap contains a Node with the given
+	// name.
+	ContainsName(name String) bool
+
 	// GetName gets a Node by its name.  If found, it is returned, otherwise,
 	// the returned Node is nil and an error is returned.
 	GetName(name String) (Node, error)
 
+	// GetAll gets every Node with the given name in order.  Only NodeMaps
+	// that allow duplicate names (see NewMultiNodeMap) can have more than
+	// one.  If there are none, the result is empty.
+	GetAll(name String) []Node
+
 	// GetIndex gets a Node by its index within the ordered NodeMap.  If the
 	// sequence is in bounds, the Node is returned.  Otherwise, an error is
 	// returned.
@@ -33,6 +71,33 @@ type NodeMap interface {
 	// Nodes returns the NodeMap's contents as a slice of Nodes.
 	Nodes() []Node
 
+	// NodesInto writes the NodeMap's Nodes into the provided slice without
+	// allocating.  The number of Nodes written is returned; if it's less
+	// than Len, the slice was too short.
+	NodesInto(nodes []Node) (written int)
+
+	// Range calls f with each Node (and its name) in the NodeMap in order
+	// until f returns false.  Unlike Nodes, Range doesn't copy the NodeMap's
+	// contents so it should be preferred for just iterating.  The NodeMap
+	// should not be modified during the call to Range.
+	Range(f func(name String, n Node) bool)
+
+	// OnAdd registers a hook that is called after a Node is added to the
+	// NodeMap.  The returned function unregisters the hook.
+	OnAdd(hook NodeMapHook) (remove func())
+
+	// OnRemove registers a hook that is called after a Node is removed from
+	// the NodeMap (including when it's overwritten by AddNode).  The
+	// returned function unregisters the hook.
+	OnRemove(hook NodeMapHook) (remove func())
+
+	// Rename renames the Node named old to new (see Renamer) and updates the
+	// NodeMap so that the Node is found by its new name.  If a different
+	// Node named new already exists, or the Node doesn't implement Renamer,
+	// an error is returned.  OnRemove hooks are called before the Node is
+	// renamed and OnAdd hooks after.
+	Rename(old, new String) error
+
 	// RemoveName removes a node by name.  If the node does not exist directly
 	// within the NodeMap, an error is returned.
 	RemoveName(name String) error
@@ -46,10 +111,30 @@ type NodeMap interface {
 	Remove(node Node) error
 }
 
+// NodeMapHook is called with a NodeMap and a Node that was added to or
+// removed from it.  Hooks are called synchronously by the goroutine that
+// modified the NodeMap so they should be quick.
+type NodeMapHook func(m NodeMap, n Node)
+
 // nodemap is the reference implementation of the NodeMap interface.
 type nodemap struct {
 	index map[string]int
 	pairs []namenode
+
+	onAdd    []*NodeMapHook
+	onRemove []*NodeMapHook
+
+	// caseSensitive makes the nodemap key its Nodes by their names' exact
+	// values instead of their lower-case values.
+	caseSensitive bool
+
+	// allowDuplicates lets the nodemap hold more than one Node with the
+	// same name.  The index then refers to the first of them.
+	allowDuplicates bool
+
+	// outer is the NodeMap that embeds this nodemap (if any) so that it's
+	// the NodeMap passed to hooks.
+	outer NodeMap
 }
 
 type namenode struct {
@@ -69,28 +154,188 @@ func NewNodeMap(capacity int) NodeMap {
 	}
 }
 
+// NewCaseSensitiveNodeMap creates a NodeMap just like NewNodeMap except that
+// the names of its Nodes are case-sensitive, so "Accept" and "accept" are
+// different Nodes.  It's meant for Nodes whose names are defined outside of
+// Skink and whose case is significant (e.g. environment variable names).
+func NewCaseSensitiveNodeMap(capacity int) NodeMap {
+	return &nodemap{
+		index:         makeNodeMapIndex(capacity),
+		pairs:         makeNodeMapPairs(capacity),
+		caseSensitive: true,
+	}
+}
+
+// NewMultiNodeMap creates a NodeMap just like NewNodeMap except that it can
+// hold more than one Node with the same name (e.g. repeated elements in an
+// XML document).  Adding a Node without overwrite appends it even if its name
+// exists.  GetName gets the first Node with a name and GetAll gets all of
+// them.  Adding with overwrite, Rename and RemoveName affect the first.
+func NewMultiNodeMap(capacity int) NodeMap {
+	return &nodemap{
+		index:           makeNodeMapIndex(capacity),
+		pairs:           makeNodeMapPairs(capacity),
+		allowDuplicates: true,
+	}
+}
+
 func (m *nodemap) AddNode(node Node, overwrite bool) error {
 	m.init()
-	key := node.Name().lower
+	key := m.key(node.Name())
 	pair, ok := m.getnn(key)
-	if ok && (!overwrite) {
-		return errors.Errorf("node with name %v already exists", node.Name())
+	if ok && !overwrite {
+		if !m.allowDuplicates {
+			return errors.Errorf("node with name %v already exists", node.Name())
+		}
+		m.pairs = append(m.pairs, namenode{name: key, node: node})
+		m.notify(m.onAdd, node)
+		return nil
 	}
-	if !ok {
+	var old Node
+	if ok {
+		old = pair.node
+	} else {
 		pair = m.newnn(key)
 	}
-	pair.name = node.Name().lower
+	pair.name = key
 	pair.node = node
+	if old != nil {
+		m.notify(m.onRemove, old)
+	}
+	m.notify(m.onAdd, node)
 	return nil
 }
 
+func (m *nodemap) AddNodes(nodes []Node, overwrite bool) error {
+	if err := m.prepareAddNodes(nodes, overwrite); err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if err := m.AddNode(node, overwrite); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareAddNodes checks that the nodes can all be added and grows the
+// nodemap to fit them.
+func (m *nodemap) prepareAddNodes(nodes []Node, overwrite bool) error {
+	m.init()
+	if !overwrite && !m.allowDuplicates {
+		seen := make(map[string]struct{}, len(nodes))
+		for _, node := range nodes {
+			key := m.key(node.Name())
+			_, exists := m.index[key]
+			_, repeated := seen[key]
+			if exists || repeated {
+				return errors.Errorf(
+					"node with name %v already exists", node.Name())
+			}
+			seen[key] = struct{}{}
+		}
+	}
+	if need := len(m.pairs) + len(nodes); need > cap(m.pairs) {
+		pairs := make([]namenode, len(m.pairs), need)
+		copy(pairs, m.pairs)
+		m.pairs = pairs
+	}
+	return nil
+}
+
+func (m *nodemap) InsertNode(index int, node Node) error {
+	m.init()
+	length := m.Len()
+	if index != length {
+		var ok bool
+		if index, ok = GetTrueIndex(length, index); !ok {
+			return IndexError{index, length}
+		}
+	}
+	key := m.key(node.Name())
+	if _, ok := m.index[key]; ok && !m.allowDuplicates {
+		return errors.Errorf("node with name %v already exists", node.Name())
+	}
+	m.pairs = append(m.pairs, namenode{})
+	copy(m.pairs[index+1:], m.pairs[index:])
+	m.pairs[index] = namenode{name: key, node: node}
+	m.reindex(index, len(m.pairs))
+	m.notify(m.onAdd, node)
+	return nil
+}
+
+func (m *nodemap) Move(from, to int) error {
+	length := m.Len()
+	from, ok := GetTrueIndex(length, from)
+	if !ok {
+		return IndexError{from, length}
+	}
+	if to, ok = GetTrueIndex(length, to); !ok {
+		return IndexError{to, length}
+	}
+	pair := m.pairs[from]
+	if from < to {
+		copy(m.pairs[from:to], m.pairs[from+1:to+1])
+		m.pairs[to] = pair
+		m.reindex(from, to+1)
+	} else {
+		copy(m.pairs[to+1:from+1], m.pairs[to:from])
+		m.pairs[to] = pair
+		m.reindex(to, from+1)
+	}
+	return nil
+}
+
+// reindex updates the index of the pairs from start up to (but not
+// including) end.  If the nodemap allows duplicates, the whole index is
+// updated so that it refers to the first of each name.
+func (m *nodemap) reindex(start, end int) {
+	if m.allowDuplicates {
+		for i := len(m.pairs) - 1; i >= 0; i-- {
+			m.index[m.pairs[i].name] = i
+		}
+		return
+	}
+	for i := start; i < end; i++ {
+		m.index[m.pairs[i].name] = i
+	}
+}
+
+func (m *nodemap) Clone() NodeMap {
+	return m.clone()
+}
+
+// clone copies the nodemap without its hooks.
+func (m *nodemap) clone() *nodemap {
+	c := &nodemap{
+		index:           make(map[string]int, len(m.index)),
+		pairs:           make([]namenode, len(m.pairs), cap(m.pairs)),
+		caseSensitive:   m.caseSensitive,
+		allowDuplicates: m.allowDuplicates,
+	}
+	copy(c.pairs, m.pairs)
+	for key, index := range m.index {
+		c.index[key] = index
+	}
+	return c
+}
+
+func (m *nodemap) Merge(other NodeMap, overwrite bool) error {
+	return m.AddNodes(other.Nodes(), overwrite)
+}
+
 func (m *nodemap) Contains(node Node) bool {
-	pair, ok := m.getnn(node.Name().lower)
+	pair, ok := m.getnn(m.key(node.Name()))
 	return ok && pair.node == node
 }
 
+func (m *nodemap) ContainsName(name String) bool {
+	_, ok := m.index[m.key(name)]
+	return ok
+}
+
 func (m *nodemap) GetName(name String) (Node, error) {
-	key := name.lower
+	key := m.key(name)
 	pair, ok := m.getnn(key)
 	if !ok {
 		return nil, NodeNotFound{nil, name}
@@ -98,6 +343,24 @@ func (m *nodemap) GetName(name String) (Node, error) {
 	return pair.node, nil
 }
 
+func (m *nodemap) GetAll(name String) []Node {
+	key := m.key(name)
+	index, ok := m.index[key]
+	if !ok {
+		return nil
+	}
+	if !m.allowDuplicates {
+		return []Node{m.pairs[index].node}
+	}
+	nodes := make([]Node, 0, 1)
+	for _, pair := range m.pairs[index:] {
+		if pair.name == key {
+			nodes = append(nodes, pair.node)
+		}
+	}
+	return nodes
+}
+
 func (m *nodemap) GetIndex(index int) (Node, error) {
 	index, ok := GetTrueIndex(m.Len(), index)
 	if !ok {
@@ -145,8 +408,42 @@ func (m *nodemap) NodesInto(nodes []Node) (written int) {
 	return minimum
 }
 
+func (m *nodemap) Range(f func(name String, n Node) bool) {
+	for _, pair := range m.pairs {
+		if !f(pair.node.Name(), pair.node) {
+			return
+		}
+	}
+}
+
+func (m *nodemap) Rename(old, new String) error {
+	index, ok := m.index[m.key(old)]
+	if !ok {
+		return NodeNotFound{Name: old}
+	}
+	if _, ok := m.index[m.key(new)]; ok && m.key(new) != m.key(old) && !m.allowDuplicates {
+		return errors.Errorf("node with name %v already exists", new)
+	}
+	pair := &m.pairs[index]
+	renamer, ok := pair.node.(Renamer)
+	if !ok {
+		return errors.Errorf(
+			"node %v (type: %T) cannot be renamed", old, pair.node)
+	}
+	m.notify(m.onRemove, pair.node)
+	renamer.SetName(new)
+	delete(m.index, pair.name)
+	pair.name = m.key(new)
+	m.index[pair.name] = index
+	if m.allowDuplicates {
+		m.reindex(0, len(m.pairs))
+	}
+	m.notify(m.onAdd, pair.node)
+	return nil
+}
+
 func (m *nodemap) RemoveName(name String) error {
-	index, ok := m.index[name.lower]
+	index, ok := m.index[m.key(name)]
 	if !ok {
 		return NodeNotFound{Name: name}
 	}
@@ -161,18 +458,30 @@ func (m *nodemap) RemoveIndex(index int) (err error) {
 	pair := m.pairs[index]
 	m.pairs = append(m.pairs[:index], m.pairs[index+1:]...)
 	delete(m.index, pair.name)
-	for _, pair := range m.pairs[index:] {
-		m.index[pair.name]--
+	if m.allowDuplicates {
+		m.reindex(0, len(m.pairs))
+	} else {
+		for _, pair := range m.pairs[index:] {
+			m.index[pair.name]--
+		}
 	}
+	m.notify(m.onRemove, pair.node)
 	return nil
 }
 
 func (m *nodemap) Remove(node Node) (err error) {
 	name := node.Name()
-	pair, ok := m.getnn(name.lower)
+	pair, ok := m.getnn(m.key(name))
 	if !ok {
 		return NodeNotFound{Name: name}
 	}
+	if pair.node != node && m.allowDuplicates {
+		for i, pair := range m.pairs {
+			if pair.node == node {
+				return m.RemoveIndex(i)
+			}
+		}
+	}
 	if pair.node != node {
 		return errors.Errorf(
 			"node with name %q exists (%v) but is different (from: %v)",
@@ -181,6 +490,48 @@ func (m *nodemap) Remove(node Node) (err error) {
 	return m.RemoveName(name)
 }
 
+func (m *nodemap) OnAdd(hook NodeMapHook) func() {
+	return addNodeMapHook(&m.onAdd, hook)
+}
+
+func (m *nodemap) OnRemove(hook NodeMapHook) func() {
+	return addNodeMapHook(&m.onRemove, hook)
+}
+
+// notify calls the hooks with a Node that was added or removed.
+func (m *nodemap) notify(hooks []*NodeMapHook, node Node) {
+	for _, hook := range hooks {
+		if m.outer != nil {
+			(*hook)(m.outer, node)
+		} else {
+			(*hook)(m, node)
+		}
+	}
+}
+
+// addNodeMapHook appends a hook to hooks and returns the function that
+// removes it again.
+func addNodeMapHook(hooks *[]*NodeMapHook, hook NodeMapHook) func() {
+	p := &hook
+	*hooks = append(*hooks, p)
+	return func() {
+		for i, h := range *hooks {
+			if h == p {
+				*hooks = append((*hooks)[:i:i], (*hooks)[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// key gets the key of a name in the index.
+func (m *nodemap) key(name String) string {
+	if m.caseSensitive {
+		return name.value
+	}
+	return name.Lower()
+}
+
 // init ensures the map is initialized with non-nil values.
 func (m *nodemap) init() {
 	if m.index == nil {
@@ -216,7 +567,7 @@ func makeNodeMapIndex(capacity int) map[string]int {
 	if capacity < 0 {
 		return make(map[string]int, DefaultNodeMapCapacity)
 	}
-	return make(map[string]int)
+	return make(map[string]int, capacity)
 }
 
 func makeNodeMapPairs(capacity int) []namenode {
@@ -237,7 +588,20 @@ func GetTrueIndex(length, index int) (out int, ok bool) {
 	if index < 0 {
 		index = length + index
 	}
-	return index, index >= 0 || index < length
+	return index, index >= 0 && index < length
+}
+
+// rangeNodesInto implements NodesInto with a NodeMap's Range method.
+func rangeNodesInto(m NodeMap, nodes []Node) (written int) {
+	if len(nodes) == 0 {
+		return 0
+	}
+	m.Range(func(_ String, n Node) bool {
+		nodes[written] = n
+		written++
+		return written < len(nodes)
+	})
+	return written
 }
 
 func minint(a, b int) int {
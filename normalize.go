@@ -0,0 +1,51 @@
+package skink
+
+import (
+	"sync/atomic"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer normalizes the Unicode representation of a string so that
+// strings that look the same but were composed differently (e.g. "é" as a
+// single code point or as "e" followed by a combining accent) are equal.
+type Normalizer func(s string) string
+
+var (
+	// NormalizeNFC normalizes to Unicode Normalization Form C.  It's the
+	// default.
+	NormalizeNFC Normalizer = norm.NFC.String
+
+	// NormalizeNFKC normalizes to Unicode Normalization Form KC, which
+	// also replaces compatibility characters such as ligatures ("ﬁ") and
+	// full-width letters with their canonical equivalents.
+	NormalizeNFKC Normalizer = norm.NFKC.String
+
+	// NormalizeNone doesn't normalize at all.
+	NormalizeNone Normalizer = func(s string) string { return s }
+
+	// defaultNormalizer is initialized with a function for the same reason
+	// as defaultCaseFolder.
+	defaultNormalizer = func() *atomic.Value {
+		v := new(atomic.Value)
+		v.Store(NormalizeNFC)
+		return v
+	}()
+)
+
+// SetDefaultNormalizer sets the Normalizer that Strings' values are
+// normalized with before they're folded.  The values of StringNodes are kept
+// as they are and only normalized to be compared.  Like SetDefaultCaseFolder, it
+// should be called before any configuration is loaded.
+func SetDefaultNormalizer(normalizer Normalizer) {
+	if normalizer == nil {
+		normalizer = NormalizeNFC
+	}
+	defaultNormalizer.Store(normalizer)
+}
+
+// DefaultNormalizer gets the Normalizer that Strings' values are normalized
+// with.
+func DefaultNormalizer() Normalizer {
+	return defaultNormalizer.Load().(Normalizer)
+}
@@ -0,0 +1,209 @@
+package skink
+
+import "github.com/skillian/errors"
+
+// overlayNodeMap layers one NodeMap over another.
+type overlayNodeMap struct {
+	top    NodeMap
+	bottom NodeMap
+}
+
+// Overlay creates a NodeMap that layers top over bottom:  Nodes are looked up
+// in top first and fall through to bottom.  Nodes in bottom with the same
+// name as a Node in top are hidden.  The Nodes are ordered with top's Nodes
+// first followed by bottom's remaining Nodes.
+//
+// All modifications go to top and bottom is never modified, so Nodes that
+// only exist in bottom can be shadowed (by adding a Node with the same name
+// and overwrite set) but not removed, renamed or moved.
+func Overlay(top, bottom NodeMap) NodeMap {
+	return overlayNodeMap{top: top, bottom: bottom}
+}
+
+func (m overlayNodeMap) AddNode(node Node, overwrite bool) error {
+	if !overwrite && m.bottomHas(node.Name()) {
+		return errors.Errorf("node with name %v already exists", node.Name())
+	}
+	return m.top.AddNode(node, overwrite)
+}
+
+func (m overlayNodeMap) AddNodes(nodes []Node, overwrite bool) error {
+	if !overwrite {
+		for _, node := range nodes {
+			if m.bottomHas(node.Name()) {
+				return errors.Errorf(
+					"node with name %v already exists", node.Name())
+			}
+		}
+	}
+	return m.top.AddNodes(nodes, overwrite)
+}
+
+// InsertNode inserts a Node into top.  Because top's Nodes come first, the
+// index can be at most top's length.
+func (m overlayNodeMap) InsertNode(index int, node Node) error {
+	if m.bottomHas(node.Name()) {
+		return errors.Errorf("node with name %v already exists", node.Name())
+	}
+	return m.top.InsertNode(index, node)
+}
+
+// Move moves a Node within top.
+func (m overlayNodeMap) Move(from, to int) error {
+	return m.top.Move(from, to)
+}
+
+// Clone creates an Overlay of clones of both layers.
+func (m overlayNodeMap) Clone() NodeMap {
+	return Overlay(m.top.Clone(), m.bottom.Clone())
+}
+
+// Merge adds other's Nodes to top.
+func (m overlayNodeMap) Merge(other NodeMap, overwrite bool) error {
+	return m.AddNodes(other.Nodes(), overwrite)
+}
+
+func (m overlayNodeMap) Contains(node Node) bool {
+	if n, err := m.top.GetName(node.Name()); err == nil {
+		return n == node
+	}
+	return m.bottom.Contains(node)
+}
+
+func (m overlayNodeMap) ContainsName(name String) bool {
+	return m.top.ContainsName(name) || m.bottom.ContainsName(name)
+}
+
+func (m overlayNodeMap) GetName(name String) (Node, error) {
+	if node, err := m.top.GetName(name); err == nil {
+		return node, nil
+	}
+	return m.bottom.GetName(name)
+}
+
+// GetAll gets the Nodes with the name from top or, if top has none, from
+// bottom.
+func (m overlayNodeMap) GetAll(name String) []Node {
+	if nodes := m.top.GetAll(name); len(nodes) > 0 {
+		return nodes
+	}
+	return m.bottom.GetAll(name)
+}
+
+func (m overlayNodeMap) GetIndex(index int) (Node, error) {
+	length := m.Len()
+	index, ok := GetTrueIndex(length, index)
+	if !ok {
+		return nil, IndexError{index, length}
+	}
+	if toplen := m.top.Len(); index < toplen {
+		return m.top.GetIndex(index)
+	}
+	var found Node
+	i := m.top.Len()
+	m.rangeBottom(func(_ String, n Node) bool {
+		if i == index {
+			found = n
+			return false
+		}
+		i++
+		return true
+	})
+	return found, nil
+}
+
+func (m overlayNodeMap) Len() int {
+	length := m.top.Len()
+	m.rangeBottom(func(String, Node) bool {
+		length++
+		return true
+	})
+	return length
+}
+
+func (m overlayNodeMap) Nodes() []Node {
+	nodes := make([]Node, 0, m.top.Len()+m.bottom.Len())
+	m.Range(func(_ String, n Node) bool {
+		nodes = append(nodes, n)
+		return true
+	})
+	return nodes
+}
+
+func (m overlayNodeMap) NodesInto(nodes []Node) int {
+	return rangeNodesInto(m, nodes)
+}
+
+func (m overlayNodeMap) Range(f func(name String, n Node) bool) {
+	stopped := false
+	m.top.Range(func(name String, n Node) bool {
+		stopped = !f(name, n)
+		return !stopped
+	})
+	if !stopped {
+		m.rangeBottom(f)
+	}
+}
+
+// rangeBottom ranges over bottom's Nodes that aren't hidden by top's.
+func (m overlayNodeMap) rangeBottom(f func(name String, n Node) bool) {
+	m.bottom.Range(func(name String, n Node) bool {
+		if _, err := m.top.GetName(name); err == nil {
+			return true
+		}
+		return f(name, n)
+	})
+}
+
+// bottomHas checks if a Node with the name exists only in bottom.
+func (m overlayNodeMap) bottomHas(name String) bool {
+	if _, err := m.top.GetName(name); err == nil {
+		return false
+	}
+	_, err := m.bottom.GetName(name)
+	return err == nil
+}
+
+// OnAdd registers the hook with both layers.  It's called with the Overlay's
+// NodeMap.
+func (m overlayNodeMap) OnAdd(hook NodeMapHook) func() {
+	rebound := func(_ NodeMap, n Node) { hook(m, n) }
+	removeTop := m.top.OnAdd(rebound)
+	removeBottom := m.bottom.OnAdd(rebound)
+	return func() {
+		removeTop()
+		removeBottom()
+	}
+}
+
+// OnRemove registers the hook with both layers.  It's called with the
+// Overlay's NodeMap.
+func (m overlayNodeMap) OnRemove(hook NodeMapHook) func() {
+	rebound := func(_ NodeMap, n Node) { hook(m, n) }
+	removeTop := m.top.OnRemove(rebound)
+	removeBottom := m.bottom.OnRemove(rebound)
+	return func() {
+		removeTop()
+		removeBottom()
+	}
+}
+
+func (m overlayNodeMap) Rename(old, new String) error {
+	if m.bottomHas(new) {
+		return errors.Errorf("node with name %v already exists", new)
+	}
+	return m.top.Rename(old, new)
+}
+
+func (m overlayNodeMap) RemoveName(name String) error {
+	return m.top.RemoveName(name)
+}
+
+// RemoveIndex removes a Node from top.
+func (m overlayNodeMap) RemoveIndex(index int) error {
+	return m.top.RemoveIndex(index)
+}
+
+func (m overlayNodeMap) Remove(node Node) error {
+	return m.top.Remove(node)
+}
@@ -0,0 +1,178 @@
+package skink
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+// PatchOpKind is the kind of operation a PatchOp performs.
+type PatchOpKind int
+
+const (
+	// PatchAdd adds the PatchOp's Children to the NodeDef at its Path.  It
+	// is an error if the NodeDef already has a child with the same name as
+	// one of the added children.
+	PatchAdd PatchOpKind = iota
+
+	// PatchRemove removes the NodeDef at the PatchOp's Path.
+	PatchRemove
+
+	// PatchReplace replaces the Value of the NodeDef at the PatchOp's Path
+	// and, if the PatchOp has Children, replaces the NodeDef's children with
+	// them.
+	PatchReplace
+)
+
+// String implements fmt.Stringer.
+func (k PatchOpKind) String() string {
+	switch k {
+	case PatchAdd:
+		return "add"
+	case PatchRemove:
+		return "remove"
+	case PatchReplace:
+		return "replace"
+	}
+	return fmt.Sprintf("PatchOpKind(%d)", int(k))
+}
+
+// PatchOp is a single operation in a Patch.
+type PatchOp struct {
+	Kind PatchOpKind
+
+	// Path is the path of the NodeDef the operation applies to, relative
+	// to the root the Patch is applied to.  An empty Path refers to the root
+	// itself.
+	Path string
+
+	// Value is the new Value of a PatchReplace operation.
+	Value string
+
+	// Children are the NodeDefs added by PatchAdd or the NodeDefs that
+	// replace the existing children in PatchReplace.
+	Children []*NodeDef
+}
+
+// Patch is an ordered list of operations that modify a NodeDef tree.
+type Patch []PatchOp
+
+var patchPathAttrName = MakeString("path")
+
+// ParsePatch parses a patch document (that was loaded like any other
+// configuration document) into a Patch.  Each child of the document's root
+// is an operation whose kind is the fragment of its ClassURI (i.e. the XML
+// element's name) and whose Path is its "path" attribute.  For example:
+//
+//	<patch>
+//	  <add path="servers"><web listen=":8080" /></add>
+//	  <replace path="servers.api.listen">:9090</replace>
+//	  <remove path="servers.old" />
+//	</patch>
+func ParsePatch(nodedef *NodeDef) (Patch, error) {
+	patch := make(Patch, 0, len(nodedef.Children))
+	for _, opdef := range nodedef.Children {
+		op := PatchOp{}
+		if opdef.ClassURI == nil {
+			return nil, errors.Errorf(
+				"patch operation %v has no ClassURI", opdef.Name)
+		}
+		switch kind := strings.ToLower(opdef.ClassURI.Fragment); kind {
+		case "add":
+			op.Kind = PatchAdd
+		case "remove":
+			op.Kind = PatchRemove
+		case "replace":
+			op.Kind = PatchReplace
+		default:
+			return nil, errors.Errorf(
+				"unknown patch operation %q on %v", kind, opdef.Name)
+		}
+		hasPath := false
+		for _, child := range opdef.Children {
+			if classURIsEqual(child.ClassURI, StringClassURI) &&
+				child.Name.Equal(patchPathAttrName) {
+				op.Path = child.Value
+				hasPath = true
+				continue
+			}
+			op.Children = append(op.Children, child)
+		}
+		if !hasPath {
+			return nil, errors.Errorf(
+				"patch operation %v has no %v", opdef.Name, patchPathAttrName)
+		}
+		op.Value = strings.TrimSpace(opdef.Value)
+		patch = append(patch, op)
+	}
+	return patch, nil
+}
+
+// ApplyPatch applies the operations in patch to a copy of the root NodeDef
+// tree in order.  If any operation fails, the error is returned and none of
+// the operations are applied.  root is never modified.
+func ApplyPatch(root *NodeDef, patch Patch) (*NodeDef, error) {
+	root = root.Clone(root.Parent)
+	for i, op := range patch {
+		if err := op.apply(root); err != nil {
+			return nil, errors.ErrorfWithCause(
+				err,
+				"failed to apply patch operation %d (%v %q): %v",
+				i, op.Kind, op.Path, err)
+		}
+	}
+	return root, nil
+}
+
+func (op PatchOp) apply(root *NodeDef) error {
+	target := root
+	if op.Path != "" {
+		p, err := CompilePath(op.Path)
+		if err != nil {
+			return err
+		}
+		if target, err = p.ResolveNodeDef(root); err != nil {
+			return err
+		}
+	}
+	switch op.Kind {
+	case PatchAdd:
+		for _, child := range op.Children {
+			if target.FindChild(child.Name) != nil {
+				return errors.Errorf(
+					"%v already has a child named %v",
+					target.Name, child.Name)
+			}
+			target.Children = append(target.Children, child.Clone(target))
+		}
+	case PatchRemove:
+		if target == root {
+			return errors.Errorf("cannot remove the root NodeDef")
+		}
+		target.Parent.Children = withoutChild(target.Parent.Children, target)
+	case PatchReplace:
+		target.Value = op.Value
+		if len(op.Children) > 0 {
+			target.Children = make([]*NodeDef, len(op.Children))
+			for i, child := range op.Children {
+				target.Children[i] = child.Clone(target)
+			}
+		}
+	default:
+		return errors.Errorf("invalid patch operation kind: %v", op.Kind)
+	}
+	return nil
+}
+
+// withoutChild gets the NodeDefs other than child.  Only child itself is
+// removed, not its siblings with the same name.
+func withoutChild(nodedefs []*NodeDef, child *NodeDef) []*NodeDef {
+	filtered := make([]*NodeDef, 0, len(nodedefs))
+	for _, nodedef := range nodedefs {
+		if nodedef != child {
+			filtered = append(filtered, nodedef)
+		}
+	}
+	return filtered
+}
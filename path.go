@@ -0,0 +1,362 @@
+package skink
+
+import (
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+// NodePathEscape is the character that escapes a NodePathSeparator (or
+// itself) within a node name in a path so that names like "a.b" can still be
+// addressed.
+const NodePathEscape = `\`
+
+// EscapeNodeName escapes any NodePathSeparator or NodePathEscape characters
+// in a node's name so that it can be used as a single component of a path.
+func EscapeNodeName(name string) string {
+	if !strings.ContainsAny(name, NodePathSeparator+NodePathEscape) {
+		return name
+	}
+	parts := make([]byte, 0, len(name)+2)
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; c {
+		case NodePathSeparator[0], NodePathEscape[0]:
+			parts = append(parts, NodePathEscape[0], c)
+		default:
+			parts = append(parts, c)
+		}
+	}
+	return string(parts)
+}
+
+// JoinNodePath builds a path from the given node names, escaping each of them
+// so that the path can later be split back into the same names by
+// SplitNodePath.
+func JoinNodePath(names ...string) string {
+	escaped := make([]string, len(names))
+	for i, name := range names {
+		escaped[i] = EscapeNodeName(name)
+	}
+	return strings.Join(escaped, NodePathSeparator)
+}
+
+// SplitNodePath splits a path into its unescaped node names.  An escaped
+// separator ("\.") is kept as part of the name and an escaped escape ("\\")
+// becomes a single backslash.  A trailing, dangling escape is kept as-is.
+func SplitNodePath(path string) []string {
+	if !strings.Contains(path, NodePathEscape) {
+		return strings.Split(path, NodePathSeparator)
+	}
+	names := make([]string, 0, strings.Count(path, NodePathSeparator)+1)
+	name := make([]byte, 0, len(path))
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case NodePathEscape[0]:
+			if i+1 < len(path) {
+				i++
+				name = append(name, path[i])
+			} else {
+				name = append(name, c)
+			}
+		case NodePathSeparator[0]:
+			names = append(names, string(name))
+			name = name[:0]
+		default:
+			name = append(name, c)
+		}
+	}
+	return append(names, string(name))
+}
+
+// JoinPath builds a path from the given Strings' values like JoinNodePath.
+func JoinPath(segments ...String) string {
+	names := make([]string, len(segments))
+	for i, segment := range segments {
+		names[i] = segment.String()
+	}
+	return JoinNodePath(names...)
+}
+
+// SplitPath splits a path into its unescaped Node names like SplitNodePath,
+// but unlike SplitNodePath, empty names and a trailing, dangling escape are
+// errors just like they are for GetChildByPath.
+func SplitPath(path string) ([]String, error) {
+	if path == "" {
+		return nil, errors.Errorf("empty Node path")
+	}
+	if strings.HasSuffix(path, NodePathEscape) &&
+		(len(path)-len(strings.TrimRight(path, NodePathEscape)))%2 != 0 {
+		return nil, errors.Errorf("dangling escape at the end of path %q", path)
+	}
+	parts := SplitNodePath(path)
+	segments := make([]String, len(parts))
+	for i, part := range parts {
+		if part == "" {
+			return nil, errors.Errorf(
+				"empty Node name in path %q at segment %d",
+				path, i)
+		}
+		segments[i] = MakeString(part)
+	}
+	return segments, nil
+}
+
+// pathNames gets the names of the nodes from the root of node's tree down to
+// node itself.
+func pathNames(node Node) []String {
+	names := make([]String, 0, DefaultNodeMapCapacity)
+	iter := FindParents(node, TruePred)
+	for parent, ok := iter.Next(); ok; parent, ok = iter.Next() {
+		names = append(names, parent.Name())
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return names
+}
+
+// NodePathGlobAny is a path glob segment that matches any number (including
+// zero) of path segments.
+const NodePathGlobAny = "**"
+
+// MatchPath checks if the full path of node (as returned by GetPath) matches
+// a glob pattern.  The pattern is split into segments just like a path (see
+// SplitNodePath) and each segment is matched case-insensitively against the
+// corresponding Node name using the syntax of path.Match.  Only escaped
+// separators are unescaped when the pattern is split; other escapes (e.g.
+// "\*") are left for path.Match to match literally.  A segment of
+// NodePathGlobAny matches any number of Nodes, so "app.**.listen" matches
+// "app.listen", "app.servers.web.listen", etc.
+func MatchPath(pattern string, node Node) (bool, error) {
+	segments, err := compilePathGlob(pattern)
+	if err != nil {
+		return false, err
+	}
+	return matchPathGlob(segments, pathNames(node)), nil
+}
+
+// FindByPathGlob finds the Nodes under root (including root itself) whose full
+// paths match the glob pattern.  See MatchPath for the pattern syntax.
+func FindByPathGlob(root Node, pattern string) (NodeIterator, error) {
+	segments, err := compilePathGlob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return FindNodes(root, func(n Node) bool {
+		return matchPathGlob(segments, pathNames(n))
+	}), nil
+}
+
+// compilePathGlob splits a path glob pattern into Strings and makes sure each
+// of its segments is a valid pattern.
+func compilePathGlob(pattern string) ([]String, error) {
+	parts := splitPathGlob(pattern)
+	segments := make([]String, len(parts))
+	for i, part := range parts {
+		segments[i] = MakeString(part)
+		if _, err := (String{}).MatchGlob(segments[i]); err != nil {
+			return nil, errors.ErrorfWithCause(
+				err,
+				"invalid path pattern %q: %v",
+				pattern, err)
+		}
+	}
+	return segments, nil
+}
+
+// splitPathGlob splits a path glob pattern into its segments like
+// SplitNodePath, but only escaped separators are unescaped so that escaped
+// glob characters stay escaped for MatchGlob.
+func splitPathGlob(pattern string) []string {
+	if !strings.Contains(pattern, NodePathEscape) {
+		return strings.Split(pattern, NodePathSeparator)
+	}
+	parts := make([]string, 0, strings.Count(pattern, NodePathSeparator)+1)
+	part := make([]byte, 0, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case NodePathEscape[0]:
+			if i+1 < len(pattern) {
+				i++
+				if pattern[i] != NodePathSeparator[0] {
+					part = append(part, c)
+				}
+				part = append(part, pattern[i])
+			} else {
+				part = append(part, c)
+			}
+		case NodePathSeparator[0]:
+			parts = append(parts, string(part))
+			part = part[:0]
+		default:
+			part = append(part, c)
+		}
+	}
+	return append(parts, string(part))
+}
+
+func matchPathGlob(segments []String, names []String) bool {
+	for len(segments) > 0 {
+		if segments[0].String() == NodePathGlobAny {
+			for i := 0; i <= len(names); i++ {
+				if matchPathGlob(segments[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if ok, _ := names[0].MatchGlob(segments[0]); !ok {
+			return false
+		}
+		segments, names = segments[1:], names[1:]
+	}
+	return len(names) == 0
+}
+
+// Path is a compiled path to a Node.  Its segments are split, unescaped and
+// validated once by CompilePath so that it can be resolved repeatedly.
+type Path struct {
+	// up is the number of levels to go up from the Node the Path is
+	// resolved from before descending through names.
+	up    int
+	names []String
+}
+
+// CompilePath compiles a path string into a Path.  See GetChildByPath for the
+// path syntax.
+func CompilePath(path string) (Path, error) {
+	if path == "" {
+		return Path{}, errors.Errorf("empty Node path")
+	}
+	relative := strings.TrimLeft(path, NodePathSeparator)
+	p := Path{up: len(path) - len(relative)}
+	if relative == "" {
+		return p, nil
+	}
+	parts := SplitNodePath(relative)
+	p.names = make([]String, len(parts))
+	for i, part := range parts {
+		if part == "" {
+			return Path{}, errors.Errorf(
+				"empty Node name in path %q at segment %d",
+				path, i)
+		}
+		p.names[i] = MakeString(part)
+	}
+	return p, nil
+}
+
+// MustCompilePath compiles a path and panics if the path is invalid.  It is
+// meant to be used in package-level var blocks.
+func MustCompilePath(path string) Path {
+	p, err := CompilePath(path)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Names gets the names that the Path descends through.
+func (p Path) Names() []String {
+	return p.names
+}
+
+// Up gets the number of levels the Path goes up before descending through its
+// Names.
+func (p Path) Up() int {
+	return p.up
+}
+
+// Resolve follows the Path from node to the Node it refers to.
+func (p Path) Resolve(node Node) (Node, error) {
+	for i := p.up; i > 0; i-- {
+		parent := node.Parent()
+		if parent == nil {
+			return nil, errors.Errorf(
+				"path %q goes above root Node %v", p, GetPath(node))
+		}
+		node = parent
+	}
+	for _, name := range p.names {
+		children := node.Children()
+		if children == nil {
+			return nil, NodeNotFound{Parent: node, Name: name}
+		}
+		child, err := children.GetName(name)
+		if err != nil {
+			return nil, NodeNotFound{Parent: node, Name: name}
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// ResolveNodeDef follows the Path from nodedef to the NodeDef it refers to.
+func (p Path) ResolveNodeDef(nodedef *NodeDef) (*NodeDef, error) {
+	for i := p.up; i > 0; i-- {
+		if nodedef.Parent == nil {
+			return nil, errors.Errorf(
+				"path %q goes above root NodeDef %v", p, nodedef.Name)
+		}
+		nodedef = nodedef.Parent
+	}
+	for _, name := range p.names {
+		child := nodedef.FindChild(name)
+		if child == nil {
+			return nil, errors.Errorf(
+				"NodeDef %v not found in %v", name, nodedef.Name)
+		}
+		nodedef = child
+	}
+	return nodedef, nil
+}
+
+// String gets the path back in its string form.  The empty Path (which
+// resolves to the Node it is resolved from) is an empty string.
+func (p Path) String() string {
+	names := make([]string, len(p.names))
+	for i, name := range p.names {
+		names[i] = name.String()
+	}
+	return strings.Repeat(NodePathSeparator, p.up) + JoinNodePath(names...)
+}
+
+// CommonAncestor finds the nearest Node that is an ancestor of (or is) both a
+// and b.  If a and b are not in the same tree, nil, false is returned.
+func CommonAncestor(a, b Node) (Node, bool) {
+	ancestors := make(map[Node]struct{}, DefaultNodeMapCapacity)
+	iter := FindParents(a, TruePred)
+	for parent, ok := iter.Next(); ok; parent, ok = iter.Next() {
+		ancestors[parent] = struct{}{}
+	}
+	return FindParents(b, func(n Node) bool {
+		_, ok := ancestors[n]
+		return ok
+	}).Next()
+}
+
+// RelativePath gets the Path that resolves from the Node from to the Node to.
+// The Path goes up from from to the nearest common ancestor of the two and
+// then back down to to.  The Path from a Node to itself is empty.
+func RelativePath(from, to Node) (Path, error) {
+	ancestor, ok := CommonAncestor(from, to)
+	if !ok {
+		return Path{}, errors.Errorf(
+			"Nodes %v and %v are not in the same tree",
+			GetPath(from), GetPath(to))
+	}
+	p := Path{}
+	for node := from; node != ancestor; node = node.Parent() {
+		p.up++
+	}
+	for node := to; node != ancestor; node = node.Parent() {
+		p.names = append(p.names, node.Name())
+	}
+	for i, j := 0, len(p.names)-1; i < j; i, j = i+1, j-1 {
+		p.names[i], p.names[j] = p.names[j], p.names[i]
+	}
+	return p, nil
+}
@@ -0,0 +1,556 @@
+package skink
+
+import (
+	"sync"
+
+	"github.com/skillian/errors"
+)
+
+// pnode is a node of an immutable AVL tree.  The same structure is used both
+// for trees ordered by key (the name index of a PersistentNodeMap) and for
+// trees ordered implicitly by position (the order of its Nodes).  Trees are
+// never modified; every change copies the path from the root to the change
+// and shares the rest.
+type pnode struct {
+	key         string
+	node        Node
+	left, right *pnode
+	height      int
+	size        int
+}
+
+func pheight(t *pnode) int {
+	if t == nil {
+		return 0
+	}
+	return t.height
+}
+
+func psize(t *pnode) int {
+	if t == nil {
+		return 0
+	}
+	return t.size
+}
+
+func pmake(key string, node Node, left, right *pnode) *pnode {
+	height := pheight(left)
+	if h := pheight(right); h > height {
+		height = h
+	}
+	return &pnode{
+		key:    key,
+		node:   node,
+		left:   left,
+		right:  right,
+		height: height + 1,
+		size:   psize(left) + psize(right) + 1,
+	}
+}
+
+// pbalance makes a new node, rotating if its subtrees' heights differ by
+// more than one.
+func pbalance(key string, node Node, left, right *pnode) *pnode {
+	lh, rh := pheight(left), pheight(right)
+	switch {
+	case lh > rh+1:
+		if pheight(left.left) >= pheight(left.right) {
+			return pmake(left.key, left.node, left.left,
+				pmake(key, node, left.right, right))
+		}
+		return pmake(left.right.key, left.right.node,
+			pmake(left.key, left.node, left.left, left.right.left),
+			pmake(key, node, left.right.right, right))
+	case rh > lh+1:
+		if pheight(right.right) >= pheight(right.left) {
+			return pmake(right.key, right.node,
+				pmake(key, node, left, right.left), right.right)
+		}
+		return pmake(right.left.key, right.left.node,
+			pmake(key, node, left, right.left.left),
+			pmake(right.key, right.node, right.left.right, right.right))
+	}
+	return pmake(key, node, left, right)
+}
+
+// pdeleteMin removes the first node of a tree and returns it with the new
+// tree.
+func pdeleteMin(t *pnode) (min, rest *pnode) {
+	if t.left == nil {
+		return t, t.right
+	}
+	min, left := pdeleteMin(t.left)
+	return min, pbalance(t.key, t.node, left, t.right)
+}
+
+// pjoin joins two trees where every node in left comes before every node in
+// right.
+func pjoin(left, right *pnode) *pnode {
+	if right == nil {
+		return left
+	}
+	min, rest := pdeleteMin(right)
+	return pbalance(min.key, min.node, left, rest)
+}
+
+func pnameGet(t *pnode, key string) (Node, bool) {
+	for t != nil {
+		switch {
+		case key < t.key:
+			t = t.left
+		case key > t.key:
+			t = t.right
+		default:
+			return t.node, true
+		}
+	}
+	return nil, false
+}
+
+func pnamePut(t *pnode, key string, node Node) *pnode {
+	if t == nil {
+		return pmake(key, node, nil, nil)
+	}
+	switch {
+	case key < t.key:
+		return pbalance(t.key, t.node, pnamePut(t.left, key, node), t.right)
+	case key > t.key:
+		return pbalance(t.key, t.node, t.left, pnamePut(t.right, key, node))
+	}
+	return pmake(key, node, t.left, t.right)
+}
+
+func pnameDelete(t *pnode, key string) *pnode {
+	if t == nil {
+		return nil
+	}
+	switch {
+	case key < t.key:
+		return pbalance(t.key, t.node, pnameDelete(t.left, key), t.right)
+	case key > t.key:
+		return pbalance(t.key, t.node, t.left, pnameDelete(t.right, key))
+	}
+	return pjoin(t.left, t.right)
+}
+
+func porderGet(t *pnode, index int) *pnode {
+	for t != nil {
+		leftsize := psize(t.left)
+		switch {
+		case index < leftsize:
+			t = t.left
+		case index > leftsize:
+			index -= leftsize + 1
+			t = t.right
+		default:
+			return t
+		}
+	}
+	return nil
+}
+
+func porderInsert(t *pnode, index int, key string, node Node) *pnode {
+	if t == nil {
+		return pmake(key, node, nil, nil)
+	}
+	leftsize := psize(t.left)
+	if index <= leftsize {
+		return pbalance(t.key, t.node, porderInsert(t.left, index, key, node), t.right)
+	}
+	return pbalance(t.key, t.node, t.left, porderInsert(t.right, index-leftsize-1, key, node))
+}
+
+func porderSet(t *pnode, index int, key string, node Node) *pnode {
+	leftsize := psize(t.left)
+	switch {
+	case index < leftsize:
+		return pmake(t.key, t.node, porderSet(t.left, index, key, node), t.right)
+	case index > leftsize:
+		return pmake(t.key, t.node, t.left, porderSet(t.right, index-leftsize-1, key, node))
+	}
+	return pmake(key, node, t.left, t.right)
+}
+
+func porderDelete(t *pnode, index int) *pnode {
+	leftsize := psize(t.left)
+	switch {
+	case index < leftsize:
+		return pbalance(t.key, t.node, porderDelete(t.left, index), t.right)
+	case index > leftsize:
+		return pbalance(t.key, t.node, t.left, porderDelete(t.right, index-leftsize-1))
+	}
+	return pjoin(t.left, t.right)
+}
+
+// porderRange calls f with each node in order until it returns false.
+func porderRange(t *pnode, f func(t *pnode) bool) bool {
+	if t == nil {
+		return true
+	}
+	return porderRange(t.left, f) && f(t) && porderRange(t.right, f)
+}
+
+// PersistentNodeMap is an immutable, ordered collection of uniquely named
+// Nodes.  "Modifying" a PersistentNodeMap returns a new PersistentNodeMap
+// that shares most of its structure with the old one, which is unchanged, so
+// PersistentNodeMaps are safe to share between goroutines and cheap to keep
+// as snapshots.  Only the collection is persistent:  the Nodes themselves
+// are shared.  The zero value is an empty PersistentNodeMap.
+type PersistentNodeMap struct {
+	names *pnode
+	order *pnode
+}
+
+// Len gets the number of Nodes in the PersistentNodeMap.
+func (p PersistentNodeMap) Len() int {
+	return psize(p.order)
+}
+
+// GetName gets a Node by its name.
+func (p PersistentNodeMap) GetName(name String) (Node, error) {
+	node, ok := pnameGet(p.names, name.Lower())
+	if !ok {
+		return nil, NodeNotFound{Name: name}
+	}
+	return node, nil
+}
+
+// GetIndex gets a Node by its index.
+func (p PersistentNodeMap) GetIndex(index int) (Node, error) {
+	length := p.Len()
+	index, ok := GetTrueIndex(length, index)
+	if !ok {
+		return nil, IndexError{index, length}
+	}
+	return porderGet(p.order, index).node, nil
+}
+
+// Range calls f with each Node in order until f returns false.
+func (p PersistentNodeMap) Range(f func(name String, n Node) bool) {
+	porderRange(p.order, func(t *pnode) bool {
+		return f(t.node.Name(), t.node)
+	})
+}
+
+// Nodes gets the Nodes in a new slice.
+func (p PersistentNodeMap) Nodes() []Node {
+	nodes := make([]Node, 0, p.Len())
+	p.Range(func(_ String, n Node) bool {
+		nodes = append(nodes, n)
+		return true
+	})
+	return nodes
+}
+
+// indexOf gets the index of the Node with the key.  It's O(n).
+func (p PersistentNodeMap) indexOf(key string) int {
+	index, i := -1, 0
+	porderRange(p.order, func(t *pnode) bool {
+		if t.key == key {
+			index = i
+			return false
+		}
+		i++
+		return true
+	})
+	return index
+}
+
+// With gets a PersistentNodeMap with the node added to the end.  If a Node
+// with the same name exists, it's replaced in place if overwrite is true,
+// otherwise an error is returned.
+func (p PersistentNodeMap) With(node Node, overwrite bool) (PersistentNodeMap, error) {
+	key := node.Name().Lower()
+	if _, ok := pnameGet(p.names, key); ok {
+		if !overwrite {
+			return p, errors.Errorf(
+				"node with name %v already exists", node.Name())
+		}
+		return PersistentNodeMap{
+			names: pnamePut(p.names, key, node),
+			order: porderSet(p.order, p.indexOf(key), key, node),
+		}, nil
+	}
+	return p.WithAt(p.Len(), node)
+}
+
+// WithAt gets a PersistentNodeMap with the node inserted at the index.
+func (p PersistentNodeMap) WithAt(index int, node Node) (PersistentNodeMap, error) {
+	length := p.Len()
+	if index != length {
+		var ok bool
+		if index, ok = GetTrueIndex(length, index); !ok {
+			return p, IndexError{index, length}
+		}
+	}
+	key := node.Name().Lower()
+	if _, ok := pnameGet(p.names, key); ok {
+		return p, errors.Errorf(
+			"node with name %v already exists", node.Name())
+	}
+	return PersistentNodeMap{
+		names: pnamePut(p.names, key, node),
+		order: porderInsert(p.order, index, key, node),
+	}, nil
+}
+
+// WithoutIndex gets a PersistentNodeMap without the Node at the index.
+func (p PersistentNodeMap) WithoutIndex(index int) (PersistentNodeMap, Node, error) {
+	length := p.Len()
+	index, ok := GetTrueIndex(length, index)
+	if !ok {
+		return p, nil, IndexError{index, length}
+	}
+	t := porderGet(p.order, index)
+	return PersistentNodeMap{
+		names: pnameDelete(p.names, t.key),
+		order: porderDelete(p.order, index),
+	}, t.node, nil
+}
+
+// Without gets a PersistentNodeMap without the Node with the name.
+func (p PersistentNodeMap) Without(name String) (PersistentNodeMap, Node, error) {
+	index := p.indexOf(name.Lower())
+	if index < 0 {
+		return p, nil, NodeNotFound{Name: name}
+	}
+	return p.WithoutIndex(index)
+}
+
+// snapshotNodeMap is a NodeMap whose contents are a PersistentNodeMap.
+type snapshotNodeMap struct {
+	mutex    sync.RWMutex
+	p        PersistentNodeMap
+	onAdd    []*NodeMapHook
+	onRemove []*NodeMapHook
+}
+
+// SnapshotNodeMap is a NodeMap that can take consistent snapshots of itself
+// in constant time.
+type SnapshotNodeMap interface {
+	NodeMap
+
+	// Snapshot gets the NodeMap's current contents.  Later changes to the
+	// NodeMap don't affect the snapshot.
+	Snapshot() PersistentNodeMap
+}
+
+// NewSnapshotNodeMap creates a NodeMap backed by a PersistentNodeMap so that
+// its Snapshots are cheap and consistent even while other goroutines modify
+// it.  Unlike the other NodeMaps, it's safe for concurrent use.  Modifying it
+// is O(log n) except for removing by name and overwriting, which are O(n).
+func NewSnapshotNodeMap() SnapshotNodeMap {
+	return new(snapshotNodeMap)
+}
+
+func (m *snapshotNodeMap) Snapshot() PersistentNodeMap {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.p
+}
+
+// update replaces the PersistentNodeMap with the result of f and then calls
+// the hooks for the added and removed Nodes.
+func (m *snapshotNodeMap) update(f func(p PersistentNodeMap) (PersistentNodeMap, []Node, []Node, error)) error {
+	m.mutex.Lock()
+	p, added, removed, err := f(m.p)
+	if err != nil {
+		m.mutex.Unlock()
+		return err
+	}
+	m.p = p
+	onAdd, onRemove := m.onAdd, m.onRemove
+	m.mutex.Unlock()
+	for _, node := range removed {
+		for _, hook := range onRemove {
+			(*hook)(m, node)
+		}
+	}
+	for _, node := range added {
+		for _, hook := range onAdd {
+			(*hook)(m, node)
+		}
+	}
+	return nil
+}
+
+func (m *snapshotNodeMap) AddNode(node Node, overwrite bool) error {
+	return m.update(func(p PersistentNodeMap) (PersistentNodeMap, []Node, []Node, error) {
+		var removed []Node
+		if old, err := p.GetName(node.Name()); err == nil && overwrite {
+			removed = []Node{old}
+		}
+		p, err := p.With(node, overwrite)
+		return p, []Node{node}, removed, err
+	})
+}
+
+func (m *snapshotNodeMap) AddNodes(nodes []Node, overwrite bool) error {
+	return m.update(func(p PersistentNodeMap) (PersistentNodeMap, []Node, []Node, error) {
+		var removed []Node
+		for _, node := range nodes {
+			if old, err := p.GetName(node.Name()); err == nil && overwrite {
+				removed = append(removed, old)
+			}
+			var err error
+			if p, err = p.With(node, overwrite); err != nil {
+				return p, nil, nil, err
+			}
+		}
+		return p, nodes, removed, nil
+	})
+}
+
+func (m *snapshotNodeMap) InsertNode(index int, node Node) error {
+	return m.update(func(p PersistentNodeMap) (PersistentNodeMap, []Node, []Node, error) {
+		p, err := p.WithAt(index, node)
+		return p, []Node{node}, nil, err
+	})
+}
+
+func (m *snapshotNodeMap) Move(from, to int) error {
+	return m.update(func(p PersistentNodeMap) (PersistentNodeMap, []Node, []Node, error) {
+		length := p.Len()
+		if _, ok := GetTrueIndex(length, to); !ok {
+			return p, nil, nil, IndexError{to, length}
+		}
+		p, node, err := p.WithoutIndex(from)
+		if err != nil {
+			return p, nil, nil, err
+		}
+		if to < 0 {
+			to += length
+		}
+		p, err = p.WithAt(to, node)
+		return p, nil, nil, err
+	})
+}
+
+// Clone creates a new snapshotting NodeMap from a Snapshot in constant time.
+func (m *snapshotNodeMap) Clone() NodeMap {
+	return &snapshotNodeMap{p: m.Snapshot()}
+}
+
+func (m *snapshotNodeMap) Merge(other NodeMap, overwrite bool) error {
+	return m.AddNodes(other.Nodes(), overwrite)
+}
+
+func (m *snapshotNodeMap) Contains(node Node) bool {
+	n, err := m.Snapshot().GetName(node.Name())
+	return err == nil && n == node
+}
+
+func (m *snapshotNodeMap) ContainsName(name String) bool {
+	_, err := m.Snapshot().GetName(name)
+	return err == nil
+}
+
+func (m *snapshotNodeMap) GetName(name String) (Node, error) {
+	return m.Snapshot().GetName(name)
+}
+
+func (m *snapshotNodeMap) GetAll(name String) []Node {
+	if node, err := m.GetName(name); err == nil {
+		return []Node{node}
+	}
+	return nil
+}
+
+func (m *snapshotNodeMap) GetIndex(index int) (Node, error) {
+	return m.Snapshot().GetIndex(index)
+}
+
+func (m *snapshotNodeMap) Len() int {
+	return m.Snapshot().Len()
+}
+
+func (m *snapshotNodeMap) Nodes() []Node {
+	return m.Snapshot().Nodes()
+}
+
+func (m *snapshotNodeMap) NodesInto(nodes []Node) int {
+	return rangeNodesInto(m, nodes)
+}
+
+// Range ranges over a Snapshot, so the NodeMap can be modified during the call
+// to Range.
+func (m *snapshotNodeMap) Range(f func(name String, n Node) bool) {
+	m.Snapshot().Range(f)
+}
+
+func (m *snapshotNodeMap) OnAdd(hook NodeMapHook) func() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	remove := addNodeMapHook(&m.onAdd, hook)
+	return func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		remove()
+	}
+}
+
+func (m *snapshotNodeMap) OnRemove(hook NodeMapHook) func() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	remove := addNodeMapHook(&m.onRemove, hook)
+	return func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		remove()
+	}
+}
+
+func (m *snapshotNodeMap) Rename(old, new String) error {
+	return m.update(func(p PersistentNodeMap) (PersistentNodeMap, []Node, []Node, error) {
+		index := p.indexOf(old.Lower())
+		if index < 0 {
+			return p, nil, nil, NodeNotFound{Name: old}
+		}
+		if new.Lower() != old.Lower() {
+			if _, err := p.GetName(new); err == nil {
+				return p, nil, nil, errors.Errorf(
+					"node with name %v already exists", new)
+			}
+		}
+		node, _ := p.GetIndex(index)
+		renamer, ok := node.(Renamer)
+		if !ok {
+			return p, nil, nil, errors.Errorf(
+				"node %v (type: %T) cannot be renamed", old, node)
+		}
+		p, _, _ = p.WithoutIndex(index)
+		renamer.SetName(new)
+		p, err := p.WithAt(index, node)
+		return p, []Node{node}, []Node{node}, err
+	})
+}
+
+func (m *snapshotNodeMap) RemoveName(name String) error {
+	return m.update(func(p PersistentNodeMap) (PersistentNodeMap, []Node, []Node, error) {
+		p, node, err := p.Without(name)
+		return p, nil, []Node{node}, err
+	})
+}
+
+func (m *snapshotNodeMap) RemoveIndex(index int) error {
+	return m.update(func(p PersistentNodeMap) (PersistentNodeMap, []Node, []Node, error) {
+		p, node, err := p.WithoutIndex(index)
+		return p, nil, []Node{node}, err
+	})
+}
+
+func (m *snapshotNodeMap) Remove(node Node) error {
+	return m.update(func(p PersistentNodeMap) (PersistentNodeMap, []Node, []Node, error) {
+		existing, err := p.GetName(node.Name())
+		if err != nil {
+			return p, nil, nil, err
+		}
+		if existing != node {
+			return p, nil, nil, errors.Errorf(
+				"node with name %q exists (%v) but is different (from: %v)",
+				node.Name(), existing, node)
+		}
+		p, _, err = p.Without(node.Name())
+		return p, nil, []Node{node}, err
+	})
+}
@@ -0,0 +1,87 @@
+package skink
+
+import (
+	"github.com/skillian/errors"
+)
+
+var (
+	pipelineClassValue = nodeclass{
+		name:        MakeString("Pipeline"),
+		base:        &nodeClassValue,
+		allocator:   allocPipelineNode,
+		initializer: initPipelineNode,
+	}
+
+	// PipelineClass is the Class of PipelineNodes.
+	PipelineClass = MustRegisterClassString(
+		"import:nodes#Pipeline", &pipelineClassValue)
+)
+
+// PipelineNode is a Caller that calls its children in order.  The first
+// child is called with the PipelineNode's arguments and each of the others
+// is called with the result of the one before it as its only (positional)
+// argument.  The last child's result is the PipelineNode's result.
+//
+// Each child must either be a Caller or a Value whose value is the path of
+// a Caller elsewhere in the tree (see ResolveCaller).
+type PipelineNode struct {
+	BasicNode
+}
+
+func allocPipelineNode(nodeDef *NodeDef) (Node, error) {
+	return new(PipelineNode), nil
+}
+
+func initPipelineNode(self, parent Node, nodeDef *NodeDef) error {
+	p, ok := self.(*PipelineNode)
+	if !ok {
+		return errors.Errorf(
+			"PipelineClass cannot init %T, only *PipelineNode.", self)
+	}
+	return initBasicNode(&p.BasicNode, parent, nodeDef)
+}
+
+// Call implements Caller.
+func (p *PipelineNode) Call(args NodeMap) (Node, error) {
+	steps := p.Children()
+	if steps == nil || steps.Len() == 0 {
+		return nil, errors.Errorf("Pipeline %v has no steps", GetPath(p))
+	}
+	var result Node
+	for i, step := range steps.Nodes() {
+		c, err := stepCaller(step)
+		if err != nil {
+			return nil, WithNodePath(step, err)
+		}
+		if i == 0 {
+			result, err = c.Call(args)
+		} else {
+			result, err = Call(c, result)
+		}
+		if err != nil {
+			return nil, WithNodePath(step, errors.ErrorfWithCause(
+				err,
+				"Pipeline step %d failed: %v",
+				i, err))
+		}
+	}
+	return result, nil
+}
+
+// stepCaller gets the Caller of a Pipeline step.
+func stepCaller(step Node) (Caller, error) {
+	if c, ok := step.(Caller); ok {
+		return c, nil
+	}
+	v, ok := step.(Value)
+	if !ok {
+		return nil, errors.Errorf(
+			"Pipeline step is not a Caller or a path (type: %T)", step)
+	}
+	path, ok := v.Value().(string)
+	if !ok {
+		return nil, errors.Errorf(
+			"Pipeline step's %T value is not a path", v.Value())
+	}
+	return ResolveCaller(step, path)
+}
@@ -0,0 +1,261 @@
+package skink
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProfileEntry is how long one Node took in each phase of its startup.  The
+// durations are the Node's own and don't include its children's.
+type ProfileEntry struct {
+	// Path is the path of the Node (see GetPath) and ParentPath is the
+	// path of its parent or empty for roots.
+	Path       string
+	ParentPath string
+
+	// Name is the Node's name.
+	Name string
+
+	// ClassURI is the URI of the Node's Class or empty if the Class isn't
+	// registered.
+	ClassURI string
+
+	// Create is how long creating the Node from its NodeDef (its Class's
+	// Alloc and Init) took.
+	Create time.Duration
+
+	// Init and Start are how long the Node's InitNode and StartNode took
+	// (see InitNoder and StartNoder).
+	Init  time.Duration
+	Start time.Duration
+}
+
+// Total gets the sum of the ProfileEntry's durations.
+func (e ProfileEntry) Total() time.Duration {
+	return e.Create + e.Init + e.Start
+}
+
+// StartupProfile is how long the Nodes of a Skink context took to create,
+// initialize and start (see (*Skink).StartupProfile).
+type StartupProfile struct {
+	// Entries are the Nodes' ProfileEntries in the order that they were
+	// first recorded.  Since Nodes' children are created before them,
+	// children come before their parents.
+	Entries []ProfileEntry
+}
+
+// StartupProfile gets how long each Node that the Skink context created,
+// initialized and started took in each phase.  Nodes that are created again
+// at the same path (e.g. by Reload) replace the entries of the previous
+// Nodes.
+func (sk *Skink) StartupProfile() StartupProfile {
+	return sk.profile.snapshot()
+}
+
+// Total gets the sum of the durations of every Node.  Since Nodes are
+// initialized and started concurrently, it can be longer than the startup
+// actually took.
+func (p StartupProfile) Total() (total time.Duration) {
+	for _, e := range p.Entries {
+		total += e.Total()
+	}
+	return total
+}
+
+// Slowest gets (up to) the n entries with the longest Totals, longest first.
+func (p StartupProfile) Slowest(n int) []ProfileEntry {
+	entries := make([]ProfileEntry, len(p.Entries))
+	copy(entries, p.Entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Total() > entries[j].Total()
+	})
+	if n < len(entries) {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// profileNode is a ProfileEntry in the tree of a StartupProfile's entries.
+type profileNode struct {
+	entry    ProfileEntry
+	total    time.Duration
+	children []*profileNode
+}
+
+// tree arranges the StartupProfile's entries by their ParentPaths.  Each
+// level is sorted by the entries' totals including their children's,
+// longest first.
+func (p StartupProfile) tree() []*profileNode {
+	nodes := make(map[string]*profileNode, len(p.Entries))
+	for _, e := range p.Entries {
+		nodes[e.Path] = &profileNode{entry: e}
+	}
+	var roots []*profileNode
+	for _, e := range p.Entries {
+		node := nodes[e.Path]
+		if parent, ok := nodes[e.ParentPath]; ok && e.ParentPath != "" {
+			parent.children = append(parent.children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	var sum func(nodes []*profileNode)
+	sum = func(nodes []*profileNode) {
+		for _, node := range nodes {
+			sum(node.children)
+			node.total = node.entry.Total()
+			for _, child := range node.children {
+				node.total += child.total
+			}
+		}
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return nodes[i].total > nodes[j].total
+		})
+	}
+	sum(roots)
+	return roots
+}
+
+// profileBarWidth is the width of the bars written by WriteText.
+const profileBarWidth = 40
+
+// WriteText writes the StartupProfile as a flame-style text report: every
+// Node is a line with a bar whose length and offset show its share of the
+// Total (including its children) like a (top-down) flame graph, followed by
+// that share's duration and percentage, its indented name and its own
+// durations in each phase.  Each level is sorted longest first.
+func (p StartupProfile) WriteText(w io.Writer) (err error) {
+	bw := bufio.NewWriter(w)
+	defer func() {
+		if flushErr := bw.Flush(); err == nil {
+			err = flushErr
+		}
+	}()
+	total := p.Total()
+	fmt.Fprintf(bw, "%d Nodes took %v\n", len(p.Entries), total)
+	if total <= 0 {
+		return nil
+	}
+	var write func(nodes []*profileNode, offset time.Duration, depth int)
+	write = func(nodes []*profileNode, offset time.Duration, depth int) {
+		for _, node := range nodes {
+			start := int(int64(offset) * profileBarWidth / int64(total))
+			end := int(int64(offset+node.total) * profileBarWidth / int64(total))
+			if end == start {
+				end++
+			}
+			bar := strings.Repeat(" ", start) + strings.Repeat("#", end-start)
+			fmt.Fprintf(
+				bw, "|%-*s| %10v %5.1f%%  %s%s  (create %v, init %v, start %v)\n",
+				profileBarWidth, bar,
+				node.total, float64(node.total)*100/float64(total),
+				strings.Repeat("  ", depth), node.entry.Name,
+				node.entry.Create, node.entry.Init, node.entry.Start)
+			write(node.children, offset, depth+1)
+			offset += node.total
+		}
+	}
+	write(p.tree(), 0, 0)
+	return nil
+}
+
+// WriteFolded writes the StartupProfile as "folded stacks": a line for every
+// Node with its own Total in microseconds after the names of its ancestors
+// and itself, separated by semicolons (e.g. "app;db;pool 1500").  That's the
+// input format of common flame graph tools such as flamegraph.pl.
+func (p StartupProfile) WriteFolded(w io.Writer) (err error) {
+	bw := bufio.NewWriter(w)
+	defer func() {
+		if flushErr := bw.Flush(); err == nil {
+			err = flushErr
+		}
+	}()
+	var write func(nodes []*profileNode, stack []string)
+	write = func(nodes []*profileNode, stack []string) {
+		for _, node := range nodes {
+			names := append(stack[:len(stack):len(stack)],
+				strings.Replace(node.entry.Name, ";", "_", -1))
+			micros := int64(node.entry.Total() / time.Microsecond)
+			fmt.Fprintf(bw, "%s %d\n", strings.Join(names, ";"), micros)
+			write(node.children, names)
+		}
+	}
+	write(p.tree(), nil)
+	return nil
+}
+
+// profileRecorder records the durations of the lifecycle phases of a Skink
+// context's Nodes for StartupProfile.
+type profileRecorder struct {
+	mutex   sync.Mutex
+	entries map[string]*ProfileEntry
+	order   []string
+
+	// created sums how long the children of the Nodes being created took
+	// to create by their parents' paths.  CreateNode creates a Node's
+	// children before it returns, so they're subtracted from the Node's
+	// own Create.
+	created map[string]time.Duration
+}
+
+// record records how long node took in phase.  It doesn't look at node's
+// children so that lazy children (see LazyNodeClass) aren't loaded.
+func (r *profileRecorder) record(node Node, phase LifecyclePhase, duration time.Duration) {
+	path := GetPath(node)
+	parentPath := ""
+	if parent := node.Parent(); parent != nil {
+		parentPath = GetPath(parent)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.entries == nil {
+		r.entries = make(map[string]*ProfileEntry)
+		r.created = make(map[string]time.Duration)
+	}
+	e, ok := r.entries[path]
+	if !ok {
+		r.order = append(r.order, path)
+	}
+	if !ok || phase == CreatePhase {
+		e = &ProfileEntry{
+			Path:       path,
+			ParentPath: parentPath,
+			Name:       node.Name().String(),
+		}
+		if uri, ok := GetClassURI(node.Class()); ok {
+			e.ClassURI = uri.String()
+		}
+		r.entries[path] = e
+	}
+	switch phase {
+	case CreatePhase:
+		e.Create = duration - r.created[path]
+		if e.Create < 0 {
+			e.Create = 0
+		}
+		delete(r.created, path)
+		if parentPath != "" {
+			r.created[parentPath] += duration
+		}
+	case InitPhase:
+		e.Init = duration
+	case StartPhase:
+		e.Start = duration
+	}
+}
+
+// snapshot copies the recorded entries into a StartupProfile.
+func (r *profileRecorder) snapshot() StartupProfile {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	p := StartupProfile{Entries: make([]ProfileEntry, len(r.order))}
+	for i, path := range r.order {
+		p.Entries[i] = *r.entries[path]
+	}
+	return p
+}
@@ -17,6 +17,13 @@ type Node interface {
 	Children() NodeMap
 }
 
+// Renamer is implemented by Nodes whose names can be changed.  Nodes within a
+// NodeMap must be renamed with the NodeMap's Rename method so that the Node
+// can be found by the new name.
+type Renamer interface {
+	SetName(name String)
+}
+
 // InitNoder is implemented by any node that requires initialization after its
 // child Nodes have been initialized.  Sibling nodes are all initialized
 // together but only once they all finish, does the current node get
@@ -31,12 +38,34 @@ type StartNoder interface {
 	StartNode(sk *Skink, root Node) error
 }
 
+// StopNoder is implemented by Nodes that have to undo what they did when
+// they were started (e.g. unsubscribe from events) once their tree is no
+// longer used (see (*Skink).StopNode).
+type StopNoder interface {
+	StopNode(sk *Skink) error
+}
+
 // Value is a special type of node that can represent itself as a Go value.
 type Value interface {
 	Node
 	Value() interface{}
 }
 
+// ValueSetter is implemented by Values whose values can be changed at runtime
+// without rebuilding them.
+type ValueSetter interface {
+	Value
+	SetValue(value interface{}) error
+
+	// OnChange registers a hook that is called after the value is changed.
+	// The returned function unregisters the hook.
+	OnChange(hook ValueChangeHook) (remove func())
+}
+
+// ValueChangeHook is called with a Value whose value changed from old to new.
+// Hooks are called synchronously by the goroutine that changed the value.
+type ValueChangeHook func(v Value, old, new interface{})
+
 // A Class describes a Node type hierarcy.
 type Class interface {
 	// Name gets the name of the Class
@@ -0,0 +1,112 @@
+package skink
+
+// readOnlyNodeMap wraps a NodeMap so that it cannot be modified through the
+// wrapper.
+type readOnlyNodeMap struct {
+	m NodeMap
+}
+
+// ReadOnly wraps a NodeMap so that its mutating methods return a
+// ReadOnlyError.  Changes made to m directly are still visible through the
+// wrapper and hooks can still be registered to observe them.
+func ReadOnly(m NodeMap) NodeMap {
+	if _, ok := m.(readOnlyNodeMap); ok {
+		return m
+	}
+	return readOnlyNodeMap{m: m}
+}
+
+// IsReadOnly checks if the NodeMap was created by ReadOnly or is another
+// read-only view (e.g. from FilterNodeMap).
+func IsReadOnly(m NodeMap) bool {
+	switch m.(type) {
+	case readOnlyNodeMap, filteredNodeMap:
+		return true
+	}
+	return false
+}
+
+func (m readOnlyNodeMap) AddNode(node Node, overwrite bool) error {
+	return ReadOnlyError{Op: "AddNode"}
+}
+
+func (m readOnlyNodeMap) AddNodes(nodes []Node, overwrite bool) error {
+	return ReadOnlyError{Op: "AddNodes"}
+}
+
+func (m readOnlyNodeMap) InsertNode(index int, node Node) error {
+	return ReadOnlyError{Op: "InsertNode"}
+}
+
+func (m readOnlyNodeMap) Move(from, to int) error {
+	return ReadOnlyError{Op: "Move"}
+}
+
+// Clone creates a read-only copy of the NodeMap.
+func (m readOnlyNodeMap) Clone() NodeMap {
+	return ReadOnly(m.m.Clone())
+}
+
+func (m readOnlyNodeMap) Merge(other NodeMap, overwrite bool) error {
+	return ReadOnlyError{Op: "Merge"}
+}
+
+func (m readOnlyNodeMap) Contains(node Node) bool {
+	return m.m.Contains(node)
+}
+
+func (m readOnlyNodeMap) ContainsName(name String) bool {
+	return m.m.ContainsName(name)
+}
+
+func (m readOnlyNodeMap) GetName(name String) (Node, error) {
+	return m.m.GetName(name)
+}
+
+func (m readOnlyNodeMap) GetAll(name String) []Node {
+	return m.m.GetAll(name)
+}
+
+func (m readOnlyNodeMap) GetIndex(index int) (Node, error) {
+	return m.m.GetIndex(index)
+}
+
+func (m readOnlyNodeMap) Len() int {
+	return m.m.Len()
+}
+
+func (m readOnlyNodeMap) Nodes() []Node {
+	return m.m.Nodes()
+}
+
+func (m readOnlyNodeMap) NodesInto(nodes []Node) int {
+	return m.m.NodesInto(nodes)
+}
+
+func (m readOnlyNodeMap) Range(f func(name String, n Node) bool) {
+	m.m.Range(f)
+}
+
+func (m readOnlyNodeMap) OnAdd(hook NodeMapHook) func() {
+	return m.m.OnAdd(func(_ NodeMap, n Node) { hook(m, n) })
+}
+
+func (m readOnlyNodeMap) OnRemove(hook NodeMapHook) func() {
+	return m.m.OnRemove(func(_ NodeMap, n Node) { hook(m, n) })
+}
+
+func (m readOnlyNodeMap) Rename(old, new String) error {
+	return ReadOnlyError{Op: "Rename"}
+}
+
+func (m readOnlyNodeMap) RemoveName(name String) error {
+	return ReadOnlyError{Op: "RemoveName"}
+}
+
+func (m readOnlyNodeMap) RemoveIndex(index int) error {
+	return ReadOnlyError{Op: "RemoveIndex"}
+}
+
+func (m readOnlyNodeMap) Remove(node Node) error {
+	return ReadOnlyError{Op: "Remove"}
+}
@@ -0,0 +1,96 @@
+package skink
+
+import (
+	"net/url"
+
+	"github.com/skillian/errors"
+)
+
+// Reload loads the URIs of the Skink context's Roots again (see
+// CreateNodeFromURI) and replaces each of those roots with a new tree that's
+// created, initialized and started from the reloaded configuration.  Roots
+// that weren't loaded from a URI are kept as they are.  If any of the new
+// trees fails to load, be created, be initialized or be started, none of the
+// roots are replaced and the new trees that were started are stopped again
+// (see StopNode).  Otherwise, the old trees are stopped once they're
+// replaced.  Reloads don't overlap: a Reload waits for the previous one.
+//
+// The returned Changes are the differences between the old and new trees
+// (see DiffTrees) with full paths that start with the roots' names.
+func (sk *Skink) Reload() ([]Change, error) {
+	sk.reloadMutex.Lock()
+	defer sk.reloadMutex.Unlock()
+	sk.mutex.RLock()
+	var olds []Node
+	var uris []*url.URL
+	for _, root := range sk.roots {
+		if uri, ok := sk.rootURIs[root]; ok {
+			olds = append(olds, root)
+			uris = append(uris, uri)
+		}
+	}
+	sk.mutex.RUnlock()
+	news := make([]Node, len(olds))
+	for i, uri := range uris {
+		nodedef, err := sk.CreateNodeDef(uri)
+		if err != nil {
+			return nil, errors.ErrorfWithCause(
+				err,
+				"failed to reload URI %v: %v",
+				uri, err)
+		}
+		if news[i], err = sk.CreateNode(nil, nodedef); err != nil {
+			return nil, err
+		}
+		if err = sk.InitNode(news[i]); err != nil {
+			return nil, err
+		}
+	}
+	var changes []Change
+	for i, root := range news {
+		if err := sk.StartNode(root); err != nil {
+			sk.stopReloaded(news[:i+1], "after a failed reload")
+			return nil, err
+		}
+		changes = append(changes, reloadChanges(olds[i], root)...)
+	}
+	sk.mutex.Lock()
+	for i, old := range olds {
+		for j, root := range sk.roots {
+			if root == old {
+				sk.roots[j] = news[i]
+			}
+		}
+		delete(sk.rootURIs, old)
+		sk.rootURIs[news[i]] = uris[i]
+	}
+	sk.mutex.Unlock()
+	sk.stopReloaded(olds, "after it was reloaded")
+	return changes, nil
+}
+
+// stopReloaded stops the trees replaced by (or created for) a Reload.  The
+// Reload's outcome doesn't depend on it, so failures are only logged.
+func (sk *Skink) stopReloaded(roots []Node, when string) {
+	for _, root := range roots {
+		if err := sk.StopNode(root); err != nil {
+			sk.Warn3("Failed to stop %v %v: %v", GetPath(root), when, err)
+		}
+	}
+}
+
+// reloadChanges diffs a reloaded root and prefixes the Changes' paths with
+// the root's path.
+func reloadChanges(old, new Node) []Change {
+	changes := DiffTrees(old, new)
+	for i := range changes {
+		c := &changes[i]
+		if c.Old != nil {
+			c.OldPath = GetPath(c.Old)
+		}
+		if c.New != nil {
+			c.NewPath = GetPath(c.New)
+		}
+	}
+	return changes
+}
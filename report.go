@@ -0,0 +1,166 @@
+package skink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ErrorReport groups errors by the path of the Node they're about and by
+// their ErrorKind (i.e. the phase that they happened in) so that they can be
+// rendered for people (see WriteText) or tools (see WriteJSON).
+type ErrorReport struct {
+	Groups []ErrorReportGroup `json:"groups"`
+}
+
+// ErrorReportGroup is a group of errors about the same Node in the same
+// phase.
+type ErrorReportGroup struct {
+	// Path is the path of the Node the errors are about or empty if they
+	// aren't about a specific Node.
+	Path string `json:"path"`
+
+	// Phase is the name of the errors' ErrorKind.
+	Phase string `json:"phase"`
+
+	Errors []string `json:"errors"`
+}
+
+// Report makes an ErrorReport of the bundled errors.
+func (ce *ConcurrentErrors) Report() ErrorReport {
+	return MakeErrorReport(ce)
+}
+
+// MakeErrorReport makes an ErrorReport of err.  If err is (or was caused by)
+// a collection of errors such as *ConcurrentErrors or *ValidationErrors,
+// each of its errors is reported separately.
+func MakeErrorReport(err error) ErrorReport {
+	type groupKey struct {
+		path string
+		kind ErrorKind
+	}
+	groups := map[groupKey]*ErrorReportGroup{}
+	keys := make([]groupKey, 0, 4)
+	for _, err := range flattenErrors(err) {
+		path, _ := NodePathOf(err)
+		key := groupKey{path: path, kind: ErrorKindOf(err)}
+		group, ok := groups[key]
+		if !ok {
+			group = &ErrorReportGroup{Path: path, Phase: key.kind.String()}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		group.Errors = append(group.Errors, reportMessage(err))
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].path != keys[j].path {
+			return keys[i].path < keys[j].path
+		}
+		return keys[i].kind < keys[j].kind
+	})
+	report := ErrorReport{Groups: make([]ErrorReportGroup, len(keys))}
+	for i, key := range keys {
+		report.Groups[i] = *groups[key]
+	}
+	return report
+}
+
+// WriteJSON writes the ErrorReport to w as indented JSON.
+func (r ErrorReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteText writes the ErrorReport to w as a tree of Node names with the
+// errors about each Node listed under it.  Errors that aren't about a
+// specific Node are listed first.
+func (r ErrorReport) WriteText(w io.Writer) error {
+	var previous []string
+	for _, group := range r.Groups {
+		var names []string
+		if group.Path != "" {
+			names = SplitNodePath(group.Path)
+		}
+		common := 0
+		for common < len(names) && common < len(previous) &&
+			names[common] == previous[common] {
+			common++
+		}
+		for i := common; i < len(names); i++ {
+			if _, err := fmt.Fprintf(w, "%s%s\n", strings.Repeat("  ", i), names[i]); err != nil {
+				return err
+			}
+		}
+		previous = names
+		indent := strings.Repeat("  ", len(names))
+		for _, msg := range group.Errors {
+			if _, err := fmt.Fprintf(w, "%s- [%s] %s\n", indent, group.Phase, msg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// String renders the ErrorReport with WriteText.
+func (r ErrorReport) String() string {
+	var b strings.Builder
+	r.WriteText(&b)
+	return b.String()
+}
+
+// multiError is implemented by collections of errors such as
+// *ConcurrentErrors and *ValidationErrors.
+type multiError interface {
+	Unwrap() []error
+}
+
+// flattenErrors gets the errors within err's collection of errors
+// (recursively) or just err itself if it doesn't have one.
+func flattenErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	multi := findMultiError(err)
+	if multi == nil {
+		return []error{err}
+	}
+	var errs []error
+	for _, err := range multi.Unwrap() {
+		errs = append(errs, flattenErrors(err)...)
+	}
+	return errs
+}
+
+func findMultiError(err error) multiError {
+	if err == nil {
+		return nil
+	}
+	if multi, ok := err.(multiError); ok {
+		return multi
+	}
+	for _, cause := range errorCauses(err) {
+		if multi := findMultiError(cause); multi != nil {
+			return multi
+		}
+	}
+	return nil
+}
+
+// reportMessage gets the message of err without its Node path, which is
+// already in the report.
+func reportMessage(err error) string {
+	for {
+		switch e := err.(type) {
+		case NodeError:
+			return e.Err.Error()
+		case KindError:
+			err = e.Err
+			continue
+		}
+		return err.Error()
+	}
+}
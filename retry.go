@@ -0,0 +1,134 @@
+package skink
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/skillian/errors"
+)
+
+// Retryable is implemented by errors that know whether the operation that
+// returned them might succeed if it's tried again later (e.g. a network
+// failure) or not (e.g. an invalid configuration).
+type Retryable interface {
+	Retryable() bool
+}
+
+// Temporary is the convention used by the net package's errors.  Errors that
+// implement Temporary but not Retryable are retryable if they're temporary.
+type Temporary interface {
+	Temporary() bool
+}
+
+// retryableError marks an error as retryable or not.
+type retryableError struct {
+	err       error
+	retryable bool
+}
+
+// MarkRetryable marks err as retryable (see IsRetryable).  A nil error stays
+// nil.
+func MarkRetryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return retryableError{err: err, retryable: true}
+}
+
+// MarkPermanent marks err as not retryable, even if one of its causes is.  A
+// nil error stays nil.
+func MarkPermanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return retryableError{err: err, retryable: false}
+}
+
+func (e retryableError) Error() string   { return e.err.Error() }
+func (e retryableError) Retryable() bool { return e.retryable }
+func (e retryableError) Unwrap() error   { return e.err }
+
+// IsRetryable checks if err is retryable.  The first error that implements
+// Retryable or Temporary in err and its causes (depth-first) decides.  Errors
+// that don't have any such cause aren't retryable.
+func IsRetryable(err error) bool {
+	retryable, _ := isRetryable(err)
+	return retryable
+}
+
+func isRetryable(err error) (retryable, ok bool) {
+	switch e := err.(type) {
+	case nil:
+		return false, false
+	case Retryable:
+		return e.Retryable(), true
+	case Temporary:
+		return e.Temporary(), true
+	}
+	for _, cause := range errorCauses(err) {
+		if retryable, ok := isRetryable(cause); ok {
+			return retryable, true
+		}
+	}
+	return false, false
+}
+
+// RetryPolicy controls how many times the operations of a Skink context that
+// fail with retryable errors (see IsRetryable) are tried.  The zero value
+// doesn't retry.
+type RetryPolicy struct {
+	// Attempts is the maximum number of times an operation is tried.
+	// Values less than 1 mean 1.
+	Attempts int
+
+	// Delay is how long to wait before the first retry.  Each following
+	// retry waits twice as long as the previous one, up to MaxDelay (if
+	// it's positive).
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// Do calls f until it succeeds, fails with an error that isn't retryable or
+// the policy's Attempts are used up.  The last error is returned.
+func (p RetryPolicy) Do(f func() error) error {
+	delay := p.Delay
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= p.Attempts || !IsRetryable(err) {
+			return err
+		}
+		logger.Info2("retrying after attempt %d failed: %v", attempt, err)
+		time.Sleep(delay)
+		delay *= 2
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+}
+
+// StartURIStrings takes a collection of URI strings and starts their nodes.
+// Loading each URI is retried according to the Skink context's RetryPolicy.
+// The URIs are loaded, initialized and started in order and the first error
+// stops the rest.
+func (sk *Skink) StartURIStrings(uris ...string) error {
+	for _, uristring := range uris {
+		uri, err := url.Parse(uristring)
+		if err != nil {
+			return MarkPermanent(errors.ErrorfWithCause(
+				err,
+				"failed to parse URI %q: %v",
+				uristring, err))
+		}
+		root, err := sk.CreateNodeFromURI(uri)
+		if err != nil {
+			return err
+		}
+		if err = sk.InitNode(root); err != nil {
+			return err
+		}
+		if err = sk.StartNode(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
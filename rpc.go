@@ -0,0 +1,190 @@
+package skink
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/skillian/errors"
+)
+
+const (
+	// CallPathPrefix is the prefix of the URL paths served by a
+	// CallHandler.
+	CallPathPrefix = "/call/"
+
+	// MaxCallBodySize is the largest request body, in bytes, that a
+	// CallHandler reads.
+	MaxCallBodySize = 1 << 20
+)
+
+// CallHandler is an http.Handler that lets the Callers in a Skink context's
+// trees (such as Methods) be called remotely:
+//
+//	POST /call/app.db.reload
+//	{"timeout": "5s"}
+//
+// The rest of the URL path after CallPathPrefix is the full path of the
+// Caller (see (*Skink).ResolvePath).  The body is the arguments as JSON: an
+// object's members are passed by name (see Arg), an array's elements are
+// passed by position and any other value is passed as the only positional
+// argument.  An empty body passes no arguments.  The arguments are passed as
+// Values of their decoded JSON values, which Callers convert with Coerce
+// (see FuncCaller).
+//
+// The response is a JSON object with the "result" of the call or, if it
+// failed, its "error".  Calls whose arguments can't be bound (see
+// ArgumentErrorKind) fail with 400 Bad Request.  Calls that panic only
+// report that they did; the details are logged.  Results that are Values are
+// written as their values and other results are exported with ExportNodeDef.
+// Secret results (see IsSecret) are redacted.
+type CallHandler struct {
+	sk *Skink
+}
+
+// NewCallHandler creates a CallHandler of the Callers in sk's Roots.  It's
+// meant to be registered under CallPathPrefix:
+//
+//	http.Handle(skink.CallPathPrefix, skink.NewCallHandler(sk))
+func NewCallHandler(sk *Skink) *CallHandler {
+	return &CallHandler{sk: sk}
+}
+
+// callResponse is the JSON body of a CallHandler's responses.
+type callResponse struct {
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// ServeHTTP implements http.Handler.
+func (h *CallHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.Errorf(
+			"method %v is not allowed", r.Method))
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, CallPathPrefix)
+	if path == r.URL.Path || path == "" {
+		writeJSONError(w, http.StatusNotFound, errors.Errorf(
+			"no Node path in %q", r.URL.Path))
+		return
+	}
+	node, err := h.sk.ResolvePath(path)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err)
+		return
+	}
+	c, ok := node.(Caller)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, errors.Errorf(
+			"Node %v is not a Caller (type: %T)",
+			GetPath(node), node))
+		return
+	}
+	args, err := decodeCallArgs(w, r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	var result Node
+	err = Safely(func() (err error) {
+		result, err = h.sk.Call(c, args...)
+		return err
+	})
+	if err != nil {
+		if _, ok := findPanicError(err); ok {
+			h.sk.Error2("Remote call of %v panicked: %v", path, err)
+			writeJSONError(w, http.StatusInternalServerError, errors.Errorf(
+				"call of %v panicked", GetPath(node)))
+			return
+		}
+		h.sk.Debug2("Remote call of %v failed: %v", path, err)
+		status := http.StatusInternalServerError
+		if IsErrorKind(err, ArgumentErrorKind) {
+			status = http.StatusBadRequest
+		}
+		writeJSONError(w, status, WithNodePath(node, err))
+		return
+	}
+	body, err := callResult(result)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, callResponse{Result: body})
+}
+
+// decodeCallArgs decodes the arguments in a CallHandler's request body,
+// which can't be larger than MaxCallBodySize.
+func decodeCallArgs(w http.ResponseWriter, r *http.Request) ([]Node, error) {
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxCallBodySize))
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"failed to read request body: %v",
+			err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var body interface{}
+	if err = json.Unmarshal(data, &body); err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"failed to decode arguments: %v",
+			err)
+	}
+	switch body := body.(type) {
+	case map[string]interface{}:
+		args := make([]Node, 0, len(body))
+		for name, value := range body {
+			args = append(args, Arg(name, NewValueNode(MakeString(name), value)))
+		}
+		return args, nil
+	case []interface{}:
+		args := make([]Node, len(body))
+		for i, value := range body {
+			args[i] = NewValueNode(MakeString(strconv.Itoa(i)), value)
+		}
+		return args, nil
+	}
+	return []Node{NewValueNode(MakeString("0"), body)}, nil
+}
+
+// callResult converts the result of a call into the "result" of a
+// callResponse.
+func callResult(result Node) (interface{}, error) {
+	if result == nil {
+		return nil, nil
+	}
+	if IsSecret(result) {
+		return RedactedValue, nil
+	}
+	if v, ok := result.(Value); ok && result.Children() == nil {
+		return v.Value(), nil
+	}
+	return ExportNodeDef(result)
+}
+
+// writeJSONError writes err as the "error" of a JSON object.
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSONResponse(w, status, callResponse{Error: err.Error()})
+}
+
+// writeJSONResponse writes response as the JSON body of an HTTP response.  If
+// it can't be marshaled, the error is written as the "error" of a JSON object
+// instead.
+func writeJSONResponse(w http.ResponseWriter, status int, response interface{}) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		status = http.StatusInternalServerError
+		data, _ = json.Marshal(callResponse{Error: err.Error()})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,26 @@
+package skink
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Run loads, initializes and starts the URIs with StartURIStrings and then
+// blocks until the process is interrupted (SIGINT) or terminated (SIGTERM).
+// It's meant to be the body of a main function:
+//
+//	if err := skink.GlobalSkink.Run(os.Args[1:]...); err != nil {
+//		log.Fatal(err)
+//	}
+func (sk *Skink) Run(uris ...string) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	if err := sk.StartURIStrings(uris...); err != nil {
+		return err
+	}
+	sig := <-signals
+	sk.Info1("Received %v; exiting", sig)
+	return nil
+}
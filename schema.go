@@ -0,0 +1,144 @@
+package skink
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// WriteXSD writes an XML Schema to w describing the elements of the Classes
+// registered under the given namespace (i.e. the part of their URIs before
+// the fragment).  Each Class becomes an element named after its URI's
+// fragment.  The Class's TypeAttrs (see TypeAttrMapper) with the String Class
+// become attributes (required if the TypeAttr is Required) and the rest are
+// listed in the element's documentation.  Elements allow any other content
+// and attributes because Skink allows dynamically defined children.
+func WriteXSD(w io.Writer, namespace string) error {
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	schema := xsdStart("xs:schema",
+		"xmlns:xs", "http://www.w3.org/2001/XMLSchema",
+		"targetNamespace", namespace,
+		"xmlns", namespace,
+		"elementFormDefault", "qualified")
+	tokens := []xml.Token{schema}
+	for _, uri := range sortedClassURIs(namespace) {
+		tokens = append(tokens, xsdClassElement(uri)...)
+	}
+	tokens = append(tokens, schema.End())
+	for _, token := range tokens {
+		if err := encoder.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+	return encoder.Flush()
+}
+
+// sortedClassURIs gets the URIs of the registered classes in a namespace,
+// sorted.
+func sortedClassURIs(namespace string) []*url.URL {
+	uris := make([]*url.URL, 0, DefaultNodeMapCapacity)
+	for uristring := range RegisteredClasses() {
+		uri, err := url.Parse(uristring)
+		if err != nil || uri.Fragment == "" {
+			continue
+		}
+		space := *uri
+		space.Fragment = ""
+		if space.String() == namespace {
+			uris = append(uris, uri)
+		}
+	}
+	sort.Slice(uris, func(i, j int) bool {
+		return uris[i].Fragment < uris[j].Fragment
+	})
+	return uris
+}
+
+func xsdClassElement(uri *url.URL) []xml.Token {
+	cls, err := GetClassByURI(uri)
+	if err != nil {
+		return nil
+	}
+	var attrs []TypeAttr
+	if mapper, ok := cls.(TypeAttrMapper); ok {
+		attrs = mapper.TypeAttrMap().TypeAttrs()
+	}
+	element := xsdStart("xs:element", "name", uri.Fragment)
+	complexType := xsdStart("xs:complexType", "mixed", "true")
+	tokens := []xml.Token{element}
+	if docs := xsdClassDocs(cls, attrs); docs != "" {
+		annotation := xsdStart("xs:annotation")
+		documentation := xsdStart("xs:documentation")
+		tokens = append(tokens,
+			annotation, documentation, xml.CharData(docs),
+			documentation.End(), annotation.End())
+	}
+	sequence := xsdStart("xs:sequence")
+	tokens = append(tokens,
+		complexType,
+		sequence,
+		xsdStart("xs:any",
+			"minOccurs", "0",
+			"maxOccurs", "unbounded",
+			"processContents", "lax"),
+		xml.EndElement{Name: xml.Name{Local: "xs:any"}},
+		sequence.End())
+	for _, attr := range attrs {
+		if attr.Class != StringClass {
+			continue
+		}
+		use := "optional"
+		if attr.Required {
+			use = "required"
+		}
+		tokens = append(tokens,
+			xsdStart("xs:attribute",
+				"name", attr.Name.String(),
+				"type", "xs:string",
+				"use", use),
+			xml.EndElement{Name: xml.Name{Local: "xs:attribute"}})
+	}
+	tokens = append(tokens,
+		xsdStart("xs:anyAttribute", "processContents", "lax"),
+		xml.EndElement{Name: xml.Name{Local: "xs:anyAttribute"}},
+		complexType.End(),
+		element.End())
+	return tokens
+}
+
+// xsdClassDocs documents a Class's base and its non-String TypeAttrs.
+func xsdClassDocs(cls Class, attrs []TypeAttr) string {
+	lines := make([]string, 0, len(attrs)+1)
+	if base := cls.Base(); base != nil {
+		lines = append(lines, "Base: "+base.Name().String())
+	}
+	for _, attr := range attrs {
+		if attr.Class == StringClass {
+			continue
+		}
+		line := attr.Name.String()
+		if attr.Class != nil {
+			line += " (" + attr.Class.Name().String() + ")"
+		}
+		if attr.Required {
+			line += ", required"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// xsdStart creates a start element with attributes from name/value pairs.
+func xsdStart(name string, attrs ...string) xml.StartElement {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		start.Attr = append(start.Attr, xml.Attr{
+			Name:  xml.Name{Local: attrs[i]},
+			Value: attrs[i+1],
+		})
+	}
+	return start
+}
@@ -0,0 +1,402 @@
+package skink
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/skillian/errors"
+)
+
+var (
+	scriptClassValue = nodeclass{
+		name:        MakeString("Script"),
+		base:        &nodeClassValue,
+		allocator:   allocScriptNode,
+		initializer: initScriptNode,
+	}
+
+	// ScriptClass is the Class of ScriptNodes.
+	ScriptClass = MustRegisterClassString(
+		"import:nodes#Script", &scriptClassValue)
+)
+
+// ScriptNode is a Caller that runs the small script in its NodeDef's Value.
+// Scripts can only read the Node tree and call Callers and the functions
+// registered with RegisterFunc, and they have no loops and can only nest
+// MaxScriptDepth calls of ScriptNodes (including their own), so they always
+// finish.  Each line of a script is one statement:
+//
+//	# Comments start with "#".
+//	$host = db.host            # assign the Node at a path to a variable
+//	$url = join($host, ":80")  # call a function or a Caller at a path
+//	web.reload($url)
+//	return $url                # end the script with a result
+//
+// Expressions are either double-quoted Go string literals, numbers, $names
+// of variables, paths (resolved like ResolveCaller's) or calls of a path or
+// function name with a parenthesized list of arguments.  The script's
+// arguments are its initial variables (e.g. $0 is the first positional
+// argument).  The result of a script without a return statement is nil.
+//
+// The functions are looked up in the Skink context that started the
+// ScriptNode or, if it hasn't been started, the GlobalSkink.
+type ScriptNode struct {
+	BasicNode
+	statements []scriptStatement
+
+	mutex sync.RWMutex
+	sk    *Skink
+}
+
+func allocScriptNode(nodeDef *NodeDef) (Node, error) {
+	return new(ScriptNode), nil
+}
+
+func initScriptNode(self, parent Node, nodeDef *NodeDef) error {
+	s, ok := self.(*ScriptNode)
+	if !ok {
+		return errors.Errorf(
+			"ScriptClass cannot init %T, only *ScriptNode.", self)
+	}
+	statements, err := parseScript(nodeDef.Value)
+	if err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"invalid Script %v: %v",
+			nodeDef.Name, err)
+	}
+	s.statements = statements
+	return initBasicNode(&s.BasicNode, parent, nodeDef)
+}
+
+// StartNode implements StartNoder.
+func (s *ScriptNode) StartNode(sk *Skink, root Node) error {
+	s.mutex.Lock()
+	s.sk = sk
+	s.mutex.Unlock()
+	return nil
+}
+
+// MaxScriptDepth is how deeply calls of ScriptNodes from other ScriptNodes
+// (or themselves) can be nested before the innermost call fails.
+const MaxScriptDepth = 64
+
+// Call implements Caller.
+func (s *ScriptNode) Call(args NodeMap) (Node, error) {
+	return s.call(args, 1)
+}
+
+// call runs the script at the given depth of nested ScriptNode calls.
+func (s *ScriptNode) call(args NodeMap, depth int) (Node, error) {
+	if depth > MaxScriptDepth {
+		return nil, WithNodePath(s, errors.Errorf(
+			"Script calls are nested more than %d deep", MaxScriptDepth))
+	}
+	s.mutex.RLock()
+	sk := s.sk
+	s.mutex.RUnlock()
+	if sk == nil {
+		sk = GlobalSkink
+	}
+	ctx := &scriptContext{
+		sk:     sk,
+		script: s,
+		vars:   make(map[string]Node),
+		depth:  depth,
+	}
+	if args != nil {
+		args.Range(func(name String, arg Node) bool {
+			ctx.vars[name.Lower()] = arg
+			return true
+		})
+	}
+	for _, stmt := range s.statements {
+		result, err := stmt.expr.eval(ctx)
+		if err != nil {
+			return nil, WithNodePath(s, errors.ErrorfWithCause(
+				err,
+				"line %d: %v",
+				stmt.line, err))
+		}
+		if stmt.ret {
+			return result, nil
+		}
+		if stmt.assign != "" {
+			ctx.vars[stmt.assign] = result
+		}
+	}
+	return nil, nil
+}
+
+type scriptContext struct {
+	sk     *Skink
+	script *ScriptNode
+	vars   map[string]Node
+
+	// depth is how many ScriptNode calls are nested, including this one.
+	depth int
+}
+
+// scriptCaller calls a ScriptNode from another ScriptNode's script so that
+// the depth of the nested calls is carried through the Skink context's
+// Interceptors.
+type scriptCaller struct {
+	*ScriptNode
+	depth int
+}
+
+// Call implements Caller.
+func (c scriptCaller) Call(args NodeMap) (Node, error) {
+	return c.ScriptNode.call(args, c.depth)
+}
+
+// nested wraps c in a scriptCaller if it's a ScriptNode.
+func (ctx *scriptContext) nested(c Caller) Caller {
+	if s, ok := c.(*ScriptNode); ok {
+		return scriptCaller{ScriptNode: s, depth: ctx.depth + 1}
+	}
+	return c
+}
+
+type scriptStatement struct {
+	line int
+
+	// assign is the lower-case name of the variable assigned by the
+	// statement.
+	assign string
+
+	// ret is true if the statement is a return statement.
+	ret bool
+
+	expr scriptExpr
+}
+
+type scriptExpr interface {
+	eval(ctx *scriptContext) (Node, error)
+}
+
+type scriptLiteral struct {
+	node Node
+}
+
+func (e scriptLiteral) eval(ctx *scriptContext) (Node, error) {
+	return e.node, nil
+}
+
+type scriptVar struct {
+	name String
+}
+
+func (e scriptVar) eval(ctx *scriptContext) (Node, error) {
+	node, ok := ctx.vars[e.name.Lower()]
+	if !ok {
+		return nil, errors.Errorf("undefined variable $%v", e.name)
+	}
+	return node, nil
+}
+
+type scriptPath struct {
+	path string
+}
+
+func (e scriptPath) eval(ctx *scriptContext) (Node, error) {
+	return resolveNode(ctx.script, e.path)
+}
+
+type scriptCall struct {
+	target string
+	args   []scriptExpr
+}
+
+func (e scriptCall) eval(ctx *scriptContext) (Node, error) {
+	args := make([]Node, len(e.args))
+	for i, arg := range e.args {
+		var err error
+		if args[i], err = arg.eval(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if c, ok := ctx.sk.Func(e.target); ok {
+		return ctx.sk.Call(ctx.nested(c), args...)
+	}
+	c, err := ResolveCaller(ctx.script, e.target)
+	if err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"%v is not a function or Caller: %v",
+			e.target, err)
+	}
+	return ctx.sk.Call(ctx.nested(c), args...)
+}
+
+// parseScript parses a ScriptNode's script.
+func parseScript(source string) ([]scriptStatement, error) {
+	var statements []scriptStatement
+	for i, line := range strings.Split(source, "\n") {
+		tokens, err := scanScriptLine(line)
+		if err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "line %d: %v", i+1, err)
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+		stmt := scriptStatement{line: i + 1}
+		switch {
+		case len(tokens) > 1 && tokens[0].kind == scriptVarToken &&
+			tokens[1].kind == '=':
+			stmt.assign = MakeString(tokens[0].text).Lower()
+			tokens = tokens[2:]
+		case tokens[0].kind == scriptPathToken && tokens[0].text == "return":
+			stmt.ret = true
+			tokens = tokens[1:]
+		}
+		p := scriptParser{tokens: tokens}
+		if stmt.expr, err = p.expr(); err == nil && len(p.tokens) > 0 {
+			err = errors.Errorf("unexpected %q", p.tokens[0].text)
+		}
+		if err != nil {
+			return nil, errors.ErrorfWithCause(
+				err, "line %d: %v", i+1, err)
+		}
+		statements = append(statements, stmt)
+	}
+	return statements, nil
+}
+
+const (
+	scriptPathToken = iota + 256
+	scriptVarToken
+	scriptStringToken
+	scriptNumberToken
+)
+
+type scriptToken struct {
+	// kind is one of the script*Token constants or the punctuation rune
+	// itself.
+	kind int
+	text string
+}
+
+// scanScriptLine splits a line of a script into tokens.
+func scanScriptLine(line string) ([]scriptToken, error) {
+	var tokens []scriptToken
+	isPathRune := func(r rune) bool {
+		return !unicode.IsSpace(r) && !strings.ContainsRune(`()",=#$`, r)
+	}
+	for i := 0; i < len(line); {
+		r := rune(line[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '#':
+			return tokens, nil
+		case strings.ContainsRune("(),=", r):
+			tokens = append(tokens, scriptToken{kind: int(r), text: string(r)})
+			i++
+		case r == '"':
+			j := i + 1
+			for ; j < len(line) && line[j] != '"'; j++ {
+				if line[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(line) {
+				return nil, errors.Errorf("unterminated string")
+			}
+			s, err := strconv.Unquote(line[i : j+1])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, scriptToken{kind: scriptStringToken, text: s})
+			i = j + 1
+		default:
+			kind := scriptPathToken
+			if r == '$' {
+				kind = scriptVarToken
+				i++
+			}
+			j := i
+			for j < len(line) && isPathRune(rune(line[j])) {
+				j++
+			}
+			if j == i {
+				return nil, errors.Errorf("unexpected %q", line[i:])
+			}
+			text := line[i:j]
+			if kind == scriptPathToken &&
+				strings.IndexAny(text[:1], "0123456789-+") == 0 {
+				kind = scriptNumberToken
+			}
+			tokens = append(tokens, scriptToken{kind: kind, text: text})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type scriptParser struct {
+	tokens []scriptToken
+}
+
+func (p *scriptParser) next() (scriptToken, bool) {
+	if len(p.tokens) == 0 {
+		return scriptToken{}, false
+	}
+	t := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return t, true
+}
+
+// expr parses an expression.
+func (p *scriptParser) expr() (scriptExpr, error) {
+	t, ok := p.next()
+	if !ok {
+		return nil, errors.Errorf("missing expression")
+	}
+	switch t.kind {
+	case scriptStringToken:
+		return scriptLiteral{newStringNode(MakeString("literal"), t.text)}, nil
+	case scriptNumberToken:
+		if i, err := strconv.ParseInt(t.text, 0, 64); err == nil {
+			return scriptLiteral{NewValueNode(MakeString("literal"), i)}, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid number %q", t.text)
+		}
+		return scriptLiteral{NewValueNode(MakeString("literal"), f)}, nil
+	case scriptVarToken:
+		return scriptVar{MakeString(t.text)}, nil
+	case scriptPathToken:
+		if len(p.tokens) == 0 || p.tokens[0].kind != '(' {
+			return scriptPath{t.text}, nil
+		}
+		p.next()
+		call := scriptCall{target: t.text}
+		if len(p.tokens) > 0 && p.tokens[0].kind == ')' {
+			p.next()
+			return call, nil
+		}
+		for {
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			t, ok := p.next()
+			if !ok {
+				return nil, errors.Errorf("unterminated call of %v", call.target)
+			}
+			if t.kind == ')' {
+				return call, nil
+			}
+			if t.kind != ',' {
+				return nil, errors.Errorf("unexpected %q", t.text)
+			}
+		}
+	}
+	return nil, errors.Errorf("unexpected %q", t.text)
+}
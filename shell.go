@@ -0,0 +1,337 @@
+package skink
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/skillian/errors"
+)
+
+// Shell is an interactive shell for browsing the Node trees of a Skink
+// context, one command per line:
+//
+//	ls [path]               list a Node's children
+//	cd [path]               change the current Node (no path: the top)
+//	pwd                     print the current Node's path
+//	cat <path>              print a Node's Class and value
+//	tree [path]             print a Node's tree (see Dump)
+//	call <path> [arg]...    call a Caller; "name=value" args are named
+//	warnings                print the Skink context's Warnings
+//	help                    list the commands
+//	exit                    leave the shell
+//
+// Paths are relative to the current Node and resolved with GetChildByPath,
+// so leading NodePathSeparators go up from it.  ".." is the current Node's
+// parent and paths that start with "/" are full paths from the top, which
+// lists the context's Roots (see (*Skink).ResolvePath).  Arguments can be
+// double-quoted Go string literals.
+type Shell struct {
+	sk  *Skink
+	in  *bufio.Scanner
+	out io.Writer
+
+	// cwd is the current Node or nil at the top.
+	cwd Node
+}
+
+// NewShell creates a Shell of sk's Node trees that reads commands from in and
+// writes to out.
+func NewShell(sk *Skink, in io.Reader, out io.Writer) *Shell {
+	return &Shell{sk: sk, in: bufio.NewScanner(in), out: out}
+}
+
+// shellCommand is one of a Shell's commands.
+type shellCommand struct {
+	usage string
+	run   func(sh *Shell, args []string) error
+}
+
+var shellCommands map[string]shellCommand
+
+func init() {
+	shellCommands = map[string]shellCommand{
+		"ls":       {"ls [path]", (*Shell).ls},
+		"cd":       {"cd [path]", (*Shell).cd},
+		"pwd":      {"pwd", (*Shell).pwd},
+		"cat":      {"cat <path>", (*Shell).cat},
+		"tree":     {"tree [path]", (*Shell).tree},
+		"call":     {"call <path> [arg]...", (*Shell).call},
+		"warnings": {"warnings", (*Shell).warnings},
+		"help":     {"help", (*Shell).help},
+	}
+}
+
+// errShellExit is returned by Exec for the exit command.
+var errShellExit = errors.Errorf("exit")
+
+// Run reads and runs commands until the input ends or the exit command is
+// run.  Errors of commands are printed and don't stop the Shell.
+func (sh *Shell) Run() error {
+	for {
+		fmt.Fprintf(sh.out, "%s> ", sh.prompt())
+		if !sh.in.Scan() {
+			fmt.Fprintln(sh.out)
+			return sh.in.Err()
+		}
+		err := sh.Exec(sh.in.Text())
+		if err == errShellExit {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(sh.out, "error:", err)
+		}
+	}
+}
+
+// Exec runs a single command line.
+func (sh *Shell) Exec(line string) error {
+	fields, err := splitShellLine(line)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	if fields[0] == "exit" || fields[0] == "quit" {
+		return errShellExit
+	}
+	cmd, ok := shellCommands[fields[0]]
+	if !ok {
+		return errors.Errorf("unknown command %q (try help)", fields[0])
+	}
+	return cmd.run(sh, fields[1:])
+}
+
+// prompt gets the path of the current Node for the prompt.
+func (sh *Shell) prompt() string {
+	if sh.cwd == nil {
+		return "/"
+	}
+	return GetPath(sh.cwd)
+}
+
+// resolve resolves a path from the current Node.  nil is the top.
+func (sh *Shell) resolve(path string) (Node, error) {
+	switch {
+	case path == "":
+		return sh.cwd, nil
+	case path == "/":
+		return nil, nil
+	case strings.HasPrefix(path, "/"):
+		return sh.sk.ResolvePath(path[1:])
+	case path == "..":
+		if sh.cwd == nil {
+			return nil, nil
+		}
+		return sh.cwd.Parent(), nil
+	case sh.cwd == nil:
+		return sh.sk.ResolvePath(path)
+	}
+	return GetChildByPath(sh.cwd, path)
+}
+
+// optionalPath gets the only (optional) path argument of a command.
+func optionalPath(args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		return "", nil
+	case 1:
+		return args[0], nil
+	}
+	return "", errors.Errorf("too many arguments: %v", args)
+}
+
+func (sh *Shell) ls(args []string) error {
+	path, err := optionalPath(args)
+	if err != nil {
+		return err
+	}
+	node, err := sh.resolve(path)
+	if err != nil {
+		return err
+	}
+	var nodes []Node
+	if node == nil {
+		nodes = sh.sk.Roots()
+	} else {
+		nodes = childNodes(node)
+	}
+	for _, child := range nodes {
+		suffix := ""
+		if len(childNodes(child)) > 0 {
+			suffix = NodePathSeparator
+		}
+		if _, ok := child.(Caller); ok {
+			suffix += "()"
+		}
+		fmt.Fprintf(sh.out, "%s%s\n", EscapeNodeName(child.Name().String()), suffix)
+	}
+	return nil
+}
+
+func (sh *Shell) cd(args []string) error {
+	path, err := optionalPath(args)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		path = "/"
+	}
+	node, err := sh.resolve(path)
+	if err != nil {
+		return err
+	}
+	sh.cwd = node
+	return nil
+}
+
+func (sh *Shell) pwd(args []string) error {
+	fmt.Fprintln(sh.out, sh.prompt())
+	return nil
+}
+
+func (sh *Shell) cat(args []string) error {
+	if len(args) != 1 {
+		return errors.Errorf("usage: %v", shellCommands["cat"].usage)
+	}
+	node, err := sh.resolve(args[0])
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return errors.Errorf("the top has no value")
+	}
+	fmt.Fprintf(sh.out, "path:  %s\n", GetPath(node))
+	if cls := node.Class(); cls != nil {
+		fmt.Fprintf(sh.out, "class: %v", cls.Name())
+		if classuri, ok := GetClassURI(cls); ok {
+			fmt.Fprintf(sh.out, " (%v)", classuri)
+		}
+		fmt.Fprintln(sh.out)
+	}
+	fmt.Fprintf(sh.out, "type:  %T\n", node)
+	if v, ok := node.(Value); ok {
+		if IsSecret(node) {
+			fmt.Fprintf(sh.out, "value: %s\n", RedactedValue)
+		} else {
+			fmt.Fprintf(sh.out, "value: %#v\n", v.Value())
+		}
+	}
+	return nil
+}
+
+func (sh *Shell) tree(args []string) error {
+	path, err := optionalPath(args)
+	if err != nil {
+		return err
+	}
+	node, err := sh.resolve(path)
+	if err != nil {
+		return err
+	}
+	if node != nil {
+		return Dump(sh.out, node)
+	}
+	for _, root := range sh.sk.Roots() {
+		if err = Dump(sh.out, root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sh *Shell) call(args []string) error {
+	if len(args) == 0 {
+		return errors.Errorf("usage: %v", shellCommands["call"].usage)
+	}
+	node, err := sh.resolve(args[0])
+	if err != nil {
+		return err
+	}
+	c, ok := node.(Caller)
+	if !ok {
+		return errors.Errorf("%v is not a Caller (type: %T)", args[0], node)
+	}
+	nodes := make([]Node, len(args)-1)
+	for i, arg := range args[1:] {
+		if eq := strings.IndexByte(arg, '='); eq > 0 {
+			nodes[i] = Arg(arg[:eq], newStringNode(MakeString(arg[:eq]), arg[eq+1:]))
+		} else {
+			nodes[i] = newStringNode(MakeString(strconv.Itoa(i)), arg)
+		}
+	}
+	result, err := sh.sk.Call(c, nodes...)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		fmt.Fprintln(sh.out, "<nil>")
+		return nil
+	}
+	if v, ok := result.(Value); ok && result.Children() == nil {
+		fmt.Fprintf(sh.out, "%#v\n", v.Value())
+		return nil
+	}
+	return Dump(sh.out, result)
+}
+
+func (sh *Shell) warnings(args []string) error {
+	for _, w := range sh.sk.Warnings() {
+		fmt.Fprintln(sh.out, w)
+	}
+	return nil
+}
+
+func (sh *Shell) help(args []string) error {
+	usages := make([]string, 0, len(shellCommands)+1)
+	for _, cmd := range shellCommands {
+		usages = append(usages, cmd.usage)
+	}
+	usages = append(usages, "exit")
+	sort.Strings(usages)
+	for _, usage := range usages {
+		fmt.Fprintln(sh.out, usage)
+	}
+	return nil
+}
+
+// splitShellLine splits a Shell command line into fields separated by
+// spaces.  Double-quoted fields are unquoted with strconv.Unquote.
+func splitShellLine(line string) ([]string, error) {
+	var fields []string
+	for i := 0; i < len(line); {
+		switch {
+		case unicode.IsSpace(rune(line[i])):
+			i++
+		case line[i] == '"':
+			j := i + 1
+			for ; j < len(line) && line[j] != '"'; j++ {
+				if line[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(line) {
+				return nil, errors.Errorf("unterminated string")
+			}
+			s, err := strconv.Unquote(line[i : j+1])
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, s)
+			i = j + 1
+		default:
+			j := i
+			for j < len(line) && !unicode.IsSpace(rune(line[j])) {
+				j++
+			}
+			fields = append(fields, line[i:j])
+			i = j
+		}
+	}
+	return fields, nil
+}
@@ -3,11 +3,15 @@ package skink
 import (
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/skillian/errors"
 	"github.com/skillian/logging"
@@ -21,12 +25,111 @@ type Skink struct {
 
 	roots []Node
 
+	// rootURIs are the URIs that the Roots were loaded from (see Reload).
+	rootURIs map[Node]*url.URL
+
 	HTTPClient http.Client
 	*logging.Logger
 	Package string
 	TempDir string
 
+	// ErrorPolicy controls whether InitNode and StartNode stop after the
+	// first Node that fails.  Child Skink contexts inherit it.
+	ErrorPolicy ErrorPolicy
+
+	// LogAdapter, if not nil, receives a LifecycleEvent for every Node
+	// that's created, initialized or started.  Child Skink contexts
+	// inherit it.
+	LogAdapter LogAdapter
+
+	// InitTimeout and StartTimeout, if positive, limit how long InitNode
+	// and StartNode wait for the Nodes to finish.  When they expire, a
+	// *TimeoutError of the Nodes that haven't finished is returned (and
+	// logged) and no more Nodes are initialized or started, but the Nodes
+	// that are still running aren't interrupted.
+	InitTimeout  time.Duration
+	StartTimeout time.Duration
+
+	// DumpGoroutinesOnTimeout adds a dump of every goroutine's stack to
+	// TimeoutErrors.
+	DumpGoroutinesOnTimeout bool
+
+	// RetryPolicy controls how many times CreateNodeDef tries to load a
+	// URI that fails with a retryable error (see IsRetryable).
+	RetryPolicy RetryPolicy
+
+	// AdminAuthorize, if not nil, authorizes the requests to the Skink
+	// context's AdminHandlers (see AdminHandler.Authorize).  Unless it's
+	// set, AdminServerNodes only listen on loopback addresses.  Child Skink
+	// contexts inherit it.
+	AdminAuthorize func(r *http.Request) error
+
 	uriloaders map[string][]*uriloader
+	uriwriters map[string][]*uriwriter
+
+	pathIndex *pathIndex
+
+	environment string
+
+	encryptionKey []byte
+
+	warnings []Warning
+
+	// funcs holds the functions registered with RegisterFunc keyed by their
+	// lower-case names.
+	funcs map[string]Caller
+
+	// interceptors wrap the calls dispatched by Call (see Intercept).
+	interceptors []Interceptor
+
+	// subscriptions are the event bus's handlers (see Subscribe).
+	subscriptions []*subscription
+
+	// profile records how long each Node took to start (see
+	// StartupProfile).
+	profile profileRecorder
+
+	// reloadMutex is held for the whole of a Reload so that reloads don't
+	// overlap.
+	reloadMutex sync.Mutex
+}
+
+// ErrorPolicy controls what InitNode and StartNode do after a Node fails.
+type ErrorPolicy int
+
+const (
+	// DefaultErrorPolicy makes InitNode fail fast and StartNode continue.
+	DefaultErrorPolicy ErrorPolicy = iota
+
+	// FailFastErrorPolicy makes InitNode and StartNode stop initializing or
+	// starting Nodes after the first one fails.  Nodes that were already
+	// being initialized or started concurrently are allowed to finish.
+	FailFastErrorPolicy
+
+	// ContinueErrorPolicy makes InitNode and StartNode keep initializing or
+	// starting the Nodes that don't depend on the ones that failed and
+	// return all of the errors together at the end.  InitNode never
+	// initializes a Node whose children failed.
+	ContinueErrorPolicy
+)
+
+// failFast checks if the ErrorPolicy stops the given phase (InitErrorKind or
+// StartErrorKind) after the first error.
+func (p ErrorPolicy) failFast(phase ErrorKind) bool {
+	switch p {
+	case FailFastErrorPolicy:
+		return true
+	case ContinueErrorPolicy:
+		return false
+	}
+	return phase == InitErrorKind
+}
+
+// uriwriter defines a function that can be called to persist a NodeDef tree
+// to the given URI.
+type uriwriter struct {
+	writer  func(*url.URL, *NodeDef) error
+	schemes []string
 }
 
 // uriloader defines a function that can be called to convert the data in the
@@ -76,9 +179,13 @@ func createSkink(parent *Skink, pkg string) (*Skink, error) {
 		Package:    pkg,
 		TempDir:    tempdir,
 		uriloaders: make(map[string][]*uriloader),
+		uriwriters: make(map[string][]*uriwriter),
 	}
 	sk.RegisterURILoader(sk.loadhttp, nil, "http", "https")
 	sk.RegisterURILoader(LoadXMLFile, CanLoadXMLFile, "file")
+	sk.RegisterURILoader(sk.LoadEncryptedXMLFile, CanLoadEncryptedXMLFile, "file")
+	sk.RegisterURIWriter(WriteXMLFile, "file")
+	sk.RegisterURIWriter(sk.WriteEncryptedXMLFile, "file")
 	return sk, nil
 }
 
@@ -101,6 +208,13 @@ func (sk *Skink) CreateChild(pkg string) (*Skink, error) {
 	if err != nil {
 		return nil, err
 	}
+	child.ErrorPolicy = sk.ErrorPolicy
+	child.LogAdapter = sk.LogAdapter
+	child.InitTimeout = sk.InitTimeout
+	child.StartTimeout = sk.StartTimeout
+	child.DumpGoroutinesOnTimeout = sk.DumpGoroutinesOnTimeout
+	child.RetryPolicy = sk.RetryPolicy
+	child.AdminAuthorize = sk.AdminAuthorize
 	sk.children = append(sk.children, child)
 	return child, nil
 }
@@ -145,7 +259,48 @@ func (sk *Skink) RegisterURILoader(loader func(*url.URL) (*NodeDef, error), filt
 	}
 }
 
-// CreateNodeFromURI creates a Node by loading from the given URI.
+// RegisterURIWriter registers a function that can write NodeDef trees to URIs
+// with the provided list of URI schemes.  Multiple writers can be defined for
+// the same scheme.  Just like URI loaders, the most recently registered
+// writer is tried first and the next is only tried if it returns an error.
+func (sk *Skink) RegisterURIWriter(writer func(*url.URL, *NodeDef) error, schemes ...string) {
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	uw := &uriwriter{writer: writer, schemes: schemes}
+	for _, scheme := range schemes {
+		sk.uriwriters[scheme] = append(sk.uriwriters[scheme], uw)
+	}
+}
+
+// WriteNodeDef writes a NodeDef tree to the given URI with the URI writers
+// registered for the URI's scheme.
+func (sk *Skink) WriteNodeDef(uri *url.URL, def *NodeDef) error {
+	sk.mutex.RLock()
+	writers := sk.uriwriters[uri.Scheme]
+	sk.mutex.RUnlock()
+	if len(writers) == 0 {
+		return errors.Errorf(
+			"no URI writer registered for scheme: %s",
+			uri.Scheme)
+	}
+	var lasterr error
+	for i := range writers {
+		uw := writers[len(writers)-1-i]
+		err := uw.writer(uri, def)
+		if err == nil {
+			return nil
+		}
+		lasterr = errors.ErrorfWithCauseAndContext(
+			err,
+			lasterr,
+			"failed to write URI %v: %v",
+			uri, err)
+	}
+	return lasterr
+}
+
+// CreateNodeFromURI creates a Node by loading from the given URI.  The Node
+// is added to the Skink context's Roots and remembers its URI (see Reload).
 func (sk *Skink) CreateNodeFromURI(uri *url.URL) (Node, error) {
 	nodedef, err := sk.CreateNodeDef(uri)
 	if err != nil {
@@ -154,21 +309,85 @@ func (sk *Skink) CreateNodeFromURI(uri *url.URL) (Node, error) {
 			"failed to load URI %v: %v",
 			uri, err)
 	}
-	return sk.CreateNode(nil, nodedef)
+	root, err := sk.CreateNode(nil, nodedef)
+	if err != nil {
+		return nil, err
+	}
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	sk.roots = append(sk.roots, root)
+	if sk.rootURIs == nil {
+		sk.rootURIs = make(map[Node]*url.URL)
+	}
+	sk.rootURIs[root] = uri
+	return root, nil
+}
+
+// Roots gets the root Nodes that were loaded into the Skink context, in the
+// order they were loaded.
+func (sk *Skink) Roots() []Node {
+	sk.mutex.RLock()
+	defer sk.mutex.RUnlock()
+	roots := make([]Node, len(sk.roots))
+	copy(roots, sk.roots)
+	return roots
+}
+
+// ResolvePath resolves a full path (as returned by GetPath) to a Node in one
+// of the Skink context's Roots.  The path's first name is the name of the
+// root and the rest of it is resolved from that root with GetChildByPath.
+func (sk *Skink) ResolvePath(path string) (Node, error) {
+	names := SplitNodePath(path)
+	rootName := MakeString(names[0])
+	for _, root := range sk.Roots() {
+		if !root.Name().Equal(rootName) {
+			continue
+		}
+		if len(names) == 1 {
+			return root, nil
+		}
+		return GetChildByPath(root, JoinNodePath(names[1:]...))
+	}
+	return nil, MakeNodeNotFoundByName(nil, rootName)
 }
 
 // CreateNodeDef creates a NodeDef tree from the configuration in the specified
 // file.  That NodeDef is not initialized or converted to Nodes in any way
-// by the createNodeDef function.
+// by the createNodeDef function.  If the Skink context has an environment
+// (see SetEnvironment), that environment's overlays are applied to the
+// NodeDef tree.  Finally, the "${path}"s in the tree's Values are
+// interpolated (see (*Skink).InterpolateNodeDef).
 func (sk *Skink) CreateNodeDef(uri *url.URL) (*NodeDef, error) {
+	var nodedef *NodeDef
+	err := sk.RetryPolicy.Do(func() (err error) {
+		nodedef, err = sk.loadNodeDef(uri)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sk.collectWarnings(nodedef, true)
+	if nodedef, err = sk.applyEnvironment(uri, nodedef); err != nil {
+		return nil, err
+	}
+	if err = sk.InterpolateNodeDef(nodedef); err != nil {
+		return nil, err
+	}
+	return nodedef, nil
+}
+
+// loadNodeDef loads a NodeDef tree from a URI with the URI loaders registered
+// for its scheme.  Failures are returned as a *LoadError.
+func (sk *Skink) loadNodeDef(uri *url.URL) (*NodeDef, error) {
 	schemes, ok := sk.getURILoadersForScheme(uri.Scheme)
 	if !ok {
-		return nil, errors.Errorf(
+		return nil, wrapLoadError(uri, errors.Errorf(
 			"no URI loader registered for scheme: %s",
-			uri.Scheme)
+			uri.Scheme))
 	}
 	logger.Debug2("URI Loaders for scheme %v: %v", uri.Scheme, schemes)
 	var lasterr error
+	var loaderErrs []error
 	for i := range schemes {
 		ul := schemes[len(schemes)-1-i]
 		if ul.filter != nil && !ul.filter(uri) {
@@ -178,6 +397,7 @@ func (sk *Skink) CreateNodeDef(uri *url.URL) (*NodeDef, error) {
 		if err == nil {
 			return nodedef, nil
 		}
+		loaderErrs = append(loaderErrs, err)
 		lasterr = errors.ErrorfWithCauseAndContext(
 			err,
 			lasterr,
@@ -187,21 +407,44 @@ func (sk *Skink) CreateNodeDef(uri *url.URL) (*NodeDef, error) {
 	if lasterr == nil {
 		lasterr = errors.Errorf("no URI loader loaded %v", uri)
 	}
-	return nil, lasterr
+	if len(loaderErrs) == 1 {
+		// The only loader's LoadError (e.g. LoadXML's) is returned as-is
+		// instead of being wrapped in another.
+		if le, ok := loaderErrs[0].(*LoadError); ok {
+			return nil, wrapLoadError(uri, le)
+		}
+	}
+	return nil, wrapLoadError(uri, lasterr)
 }
 
 // CreateNode creates a node under the given parent from the given NodeDef.
-// CreateNode recursively creates the nodes under nodeDef, too.
+// CreateNode recursively creates the nodes under nodeDef, too.  Errors are
+// annotated with the path of the NodeDef that failed (see WithNodeDefPath).
 func (sk *Skink) CreateNode(parent Node, nodeDef *NodeDef) (Node, error) {
+	start := time.Now()
+	node, err := sk.createNode(parent, nodeDef)
+	err = WithNodeDefPath(nodeDef, err)
+	if node != nil {
+		sk.logLifecycle(node, CreatePhase, start, err)
+	} else {
+		sk.logNodeDefLifecycle(nodeDef, CreatePhase, start, err)
+	}
+	return node, err
+}
+
+func (sk *Skink) createNode(parent Node, nodeDef *NodeDef) (Node, error) {
 	cls, err := GetClassByURI(nodeDef.ClassURI)
 	if err != nil {
 		if _, ok := err.(ClassNotFound); ok {
+			nodeDef.AddWarning(
+				"Class %v is not registered; creating it dynamically",
+				nodeDef.ClassURI)
 			cls, err = CreateDynamicClass(nodeDef.ClassURI)
 			if err != nil {
-				return nil, errors.ErrorfWithCause(
+				return nil, WithErrorKind(ClassErrorKind, errors.ErrorfWithCause(
 					err,
 					"failed to create class dynamically: %v",
-					err)
+					err))
 			}
 		} else {
 			return nil, errors.ErrorfWithCause(
@@ -210,85 +453,238 @@ func (sk *Skink) CreateNode(parent Node, nodeDef *NodeDef) (Node, error) {
 				nodeDef, err)
 		}
 	}
-	node, err := cls.Alloc(nodeDef)
+	var node Node
+	err = Safely(func() (err error) {
+		node, err = cls.Alloc(nodeDef)
+		return err
+	})
 	if err != nil {
-		return nil, errors.ErrorfWithCause(
+		return nil, WithErrorKind(InitErrorKind, errors.ErrorfWithCause(
 			err,
 			"failed to allocate Node from Class %v: %v",
-			cls.Name(), err)
+			cls.Name(), err))
 	}
-	err = cls.Init(node, parent, nodeDef)
-	// cls.Init should have set the node's parent.
+	// cls.Init should set the node's parent.
+	err = Safely(func() error {
+		return cls.Init(node, parent, nodeDef)
+	})
+	sk.collectWarnings(nodeDef, false)
 	if err != nil {
-		return nil, errors.ErrorfWithCause(
+		return nil, WithErrorKind(InitErrorKind, errors.ErrorfWithCause(
 			err,
 			"failed to initialize Node %v from Class %v: %v",
-			node, cls, err)
+			node, cls, err))
 	}
-	for _, childDef := range nodeDef.Children {
-		child, err := sk.CreateNode(node, childDef)
-		if err != nil {
-			return nil, err
-		}
-		if err = node.Children().AddNode(child, false); err != nil {
-			return nil, errors.ErrorfWithCause(
-				err,
-				"error adding child Node %v to parent Node %v: %v",
-				child, parent, err)
-		}
+	if len(nodeDef.Children) == 0 {
+		return node, nil
+	}
+	if basic, ok := node.(*BasicNode); ok && IsSubclass(cls, LazyNodeClass) {
+		basic.NodeChildren = sk.NewNodeDefNodeMap(node, nodeDef.Children)
+		return node, nil
+	}
+	children, err := sk.createNodes(node, nodeDef.Children)
+	if err != nil {
+		return nil, err
+	}
+	if err = node.Children().AddNodes(children, false); err != nil {
+		return nil, errors.ErrorfWithCause(
+			err,
+			"error adding child Nodes to parent Node %v: %v",
+			node, err)
 	}
 	return node, nil
 }
 
 // InitNode initializes a node (after initializing all of if its child Nodes).
+// The Nodes that fail to initialize are returned together in a
+// *ValidationErrors.  See ErrorPolicy for what happens after a Node fails to
+// initialize and InitTimeout for how long InitNode waits.
 func (sk *Skink) InitNode(node Node) error {
+	state := &initState{
+		failFast: sk.ErrorPolicy.failFast(InitErrorKind),
+		pending:  newPendingNodes(),
+		errors:   NewValidationErrors(),
+	}
+	if sk.InitTimeout <= 0 {
+		sk.initNode(node, state)
+		return state.err()
+	}
+	done := make(chan struct{})
+	go func() {
+		sk.initNode(node, state)
+		close(done)
+	}()
+	if !waitTimeout(done, sk.InitTimeout) {
+		atomic.StoreInt32(&state.stopped, 1)
+		return sk.timeoutError(InitPhase, sk.InitTimeout, state.pending)
+	}
+	return state.err()
+}
+
+// initState is shared by the initNode calls of a single InitNode.
+type initState struct {
+	failFast bool
+	failed   int32
+	stopped  int32
+	pending  *pendingNodes
+	errors   *ValidationErrors
+}
+
+// errInitSkipped is returned by initNode when a Node (or one of its
+// descendants) failed to initialize (or wasn't initialized because of
+// another failure) so that its parent isn't initialized either.  The actual
+// errors are in the initState.
+var errInitSkipped = errors.Errorf("Node was not initialized")
+
+// err gets the errors of the InitNode.
+func (state *initState) err() error {
+	if state.errors.Len() == 0 {
+		return nil
+	}
+	return state.errors
+}
+
+// stop checks if no more Nodes should be initialized.
+func (state *initState) stop() bool {
+	return atomic.LoadInt32(&state.stopped) != 0 ||
+		(state.failFast && atomic.LoadInt32(&state.failed) != 0)
+}
+
+// initNode initializes node like InitNode.
+func (sk *Skink) initNode(node Node, state *initState) error {
 	if node == nil {
 		return nil
 	}
-	err := ForEachInSlice(node.Children().Nodes(), sk.InitNode)
-	if err != nil {
-		return errors.ErrorfWithCause(
-			err,
-			"failed to initialize node %v: %v",
-			node, err)
+	if state.stop() {
+		return errInitSkipped
+	}
+	err := ForEachInSlice(childNodes(node), func(child Node) error {
+		return sk.initNode(child, state)
+	})
+	if err != nil || state.stop() {
+		return errInitSkipped
 	}
 	if initnoder, ok := node.(InitNoder); ok {
-		return initnoder.InitNode(sk)
+		start := state.pending.begin(node)
+		err := Safely(func() error {
+			return initnoder.InitNode(sk)
+		})
+		state.pending.end(node)
+		sk.logLifecycle(node, InitPhase, start, err)
+		if err != nil {
+			atomic.StoreInt32(&state.failed, 1)
+			state.errors.Add(GetPath(node), WithErrorKind(InitErrorKind, err))
+			return errInitSkipped
+		}
 	}
 	return nil
 }
 
-// StartNode starts a node and all of its child Nodes.
+// StartNode starts a node and all of its child Nodes.  See ErrorPolicy for
+// what happens after a Node fails to start and StartTimeout for how long
+// StartNode waits.
 func (sk *Skink) StartNode(root Node) error {
 	nodes := FindNodes(root, TruePred)
 	wg := sync.WaitGroup{}
 	ce := NewConcurrentErrors()
+	failFast := sk.ErrorPolicy.failFast(StartErrorKind)
+	failed := int32(0)
+	stopped := int32(0)
+	stop := func() bool {
+		return atomic.LoadInt32(&stopped) != 0 ||
+			(failFast && atomic.LoadInt32(&failed) != 0)
+	}
+	pending := newPendingNodes()
 	for {
-		child, ok := nodes()
+		if stop() {
+			break
+		}
+		child, ok := nodes.Next()
 		if !ok {
 			break
 		}
 		if startnoder, ok := child.(StartNoder); ok {
 			wg.Add(1)
-			go func(sn StartNoder) {
+			go func(node Node, sn StartNoder) {
+				defer wg.Done()
+				if stop() {
+					return
+				}
 				logger.Debug1("Starting node %#v", sn)
-				if err := sn.StartNode(sk, root); err != nil {
-					ce.Add(err)
+				start := pending.begin(node)
+				err := Safely(func() error {
+					return sn.StartNode(sk, root)
+				})
+				pending.end(node)
+				sk.logLifecycle(node, StartPhase, start, err)
+				if err != nil {
+					atomic.StoreInt32(&failed, 1)
+					ce.Add(WithErrorKind(StartErrorKind, WithNodePath(node, err)))
 				}
-				wg.Done()
-			}(startnoder)
+			}(child, startnoder)
 		}
 	}
-	wg.Wait()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	if !waitTimeout(done, sk.StartTimeout) {
+		atomic.StoreInt32(&stopped, 1)
+		return sk.timeoutError(StartPhase, sk.StartTimeout, pending)
+	}
 	if ce.Len() == 0 {
 		return nil
 	}
 	return ce
 }
 
-// StartURIStrings takes a collection of URI strings and starts their nodes.
-func (sk *Skink) StartURIStrings(uris ...string) error {
-	return errors.Errorf("StartURIStrings is not yet implemented")
+// StopNode stops a Node and all of its child Nodes that implement StopNoder,
+// one after the other.  Every Node is stopped even if others fail to; the
+// errors are returned together in a *ConcurrentErrors.
+func (sk *Skink) StopNode(root Node) error {
+	ce := NewConcurrentErrors()
+	nodes := FindNodes(root, TruePred)
+	for node, ok := nodes.Next(); ok; node, ok = nodes.Next() {
+		stopnoder, ok := node.(StopNoder)
+		if !ok {
+			continue
+		}
+		logger.Debug1("Stopping node %v", GetPath(node))
+		err := Safely(func() error {
+			return stopnoder.StopNode(sk)
+		})
+		if err != nil {
+			ce.Add(WithNodePath(node, err))
+		}
+	}
+	if ce.Len() == 0 {
+		return nil
+	}
+	return ce
+}
+
+// snapshotName is the name of the NodeDef returned by Snapshot.
+var snapshotName = MakeString("Snapshot")
+
+// Snapshot converts the live Node trees of the Skink context's Roots
+// (including any Nodes added or changed since they were loaded) back into
+// NodeDefs (see ExportNodeDef).  The exported roots are the children of the
+// returned NodeDef, which is a plain Node named "Snapshot".
+func (sk *Skink) Snapshot() (*NodeDef, error) {
+	classuri, _ := GetClassURI(NodeClass)
+	snapshot := NewNodeDef(snapshotName, nil, classuri)
+	for _, root := range sk.Roots() {
+		def, err := exportNodeDef(root, snapshot)
+		if err != nil {
+			return nil, errors.ErrorfWithCause(
+				err,
+				"failed to snapshot root %v: %v",
+				root.Name(), err)
+		}
+		snapshot.Children = append(snapshot.Children, def)
+	}
+	return snapshot, nil
 }
 
 func (sk *Skink) getURILoadersForScheme(scheme string) ([]*uriloader, bool) {
@@ -299,16 +695,53 @@ func (sk *Skink) getURILoadersForScheme(scheme string) ([]*uriloader, bool) {
 }
 
 // loadhttp downloads a file via HTTP to a temporary file and then tries to
-// use (*Skink).createNodeDef to load that file.  This way, URI loaders only
-// need to be able to load from the file URI scheme.
+// use (*Skink).loadNodeDef to load that file.  This way, URI loaders only
+// need to be able to load from the file URI scheme.  Only timeouts,
+// temporary network errors and server errors (5xx) are retryable.
 func (sk *Skink) loadhttp(uri *url.URL) (nodedef *NodeDef, err error) {
+	resp, err := sk.HTTPClient.Get(uri.String())
+	if err != nil {
+		ne, ok := err.(net.Error)
+		retryable := ok && (ne.Timeout() || ne.Temporary())
+		err = errors.ErrorfWithCause(
+			err,
+			"failed to get URI: %v: %v",
+			uri, err)
+		if retryable {
+			return nil, MarkRetryable(err)
+		}
+		return nil, MarkPermanent(err)
+	}
+	defer CatchDeferred(&err, func() error {
+		_, err := io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}, resp.Body.Close)
+	if resp.StatusCode < http.StatusOK ||
+		resp.StatusCode >= http.StatusMultipleChoices {
+		if resp.StatusCode == http.StatusNotFound ||
+			resp.StatusCode == http.StatusGone {
+			// The cause lets callers tell that the document doesn't
+			// exist (see isNotExist).
+			err = errors.ErrorfWithCause(
+				os.ErrNotExist,
+				"failed to get URI %v: %v",
+				uri, resp.Status)
+		} else {
+			err = errors.Errorf("failed to get URI %v: %v", uri, resp.Status)
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return nil, MarkRetryable(err)
+		}
+		return nil, MarkPermanent(err)
+	}
 	path := path.Join(sk.TempDir, uri.Host, uri.Path)
-	err = os.MkdirAll(path, os.ModeDir)
+	dir := filepath.Dir(path)
+	err = os.MkdirAll(dir, os.ModeDir|0700)
 	if err != nil {
 		return nil, errors.ErrorfWithCause(
 			err,
 			"failed to create temporary directory %q to download %v: %v",
-			path, uri, err)
+			dir, uri, err)
 	}
 	file, err := os.Create(path)
 	if err != nil {
@@ -317,23 +750,18 @@ func (sk *Skink) loadhttp(uri *url.URL) (nodedef *NodeDef, err error) {
 			"failed to open file %q for writing: %v",
 			path, err)
 	}
-	defer CatchDeferred(&err, file.Close)
-	resp, err := sk.HTTPClient.Get(uri.String())
+	_, err = io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
+		os.Remove(path)
 		return nil, errors.ErrorfWithCause(
 			err,
-			"failed to get URI: %v: %v",
-			uri, err)
-	}
-	defer CatchDeferred(&err, func() error {
-		_, err := io.Copy(ioutil.Discard, resp.Body)
-		return err
-	}, resp.Body.Close)
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
-		return nil, err
+			"failed to download %v to %q: %v",
+			uri, path, err)
 	}
-	return sk.CreateNodeDef(&url.URL{
+	return sk.loadNodeDef(&url.URL{
 		Scheme:   "file",
 		Path:     path,
 		Fragment: uri.Fragment,
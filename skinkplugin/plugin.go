@@ -0,0 +1,98 @@
+// Package skinkplugin loads Go plugins (see the plugin package) that extend
+// a Skink context with their Classes, functions, URI loaders, etc.  It's
+// separate from package skink so that only the programs that load plugins
+// link the plugin runtime.
+package skinkplugin
+
+import (
+	"path/filepath"
+	"plugin"
+	"sync"
+
+	"github.com/skillian/errors"
+	"github.com/skillian/skink"
+)
+
+// RegisterSymbol is the name of the function that plugins loaded with Load
+// must export.
+const RegisterSymbol = "Register"
+
+var (
+	// loaded are the absolute paths of the plugins that were loaded into
+	// each Skink context.  loadedMutex is held while a plugin is loaded so
+	// that the same plugin isn't loaded twice at once, but plugins'
+	// Register functions can still use the context.
+	loaded      = make(map[*skink.Skink]map[string]bool)
+	loadedMutex sync.Mutex
+)
+
+// Load opens the Go plugin at path and calls its exported Register function
+// with the Skink context so that it can register its Classes, functions, URI
+// loaders, etc. without recompiling the program.  Register must be either a
+// func(*skink.Skink) or a func(*skink.Skink) error:
+//
+//	package main
+//
+//	func Register(sk *skink.Skink) {
+//		skink.MustRegisterClassString("import:acme#Widget", widgetClass)
+//	}
+//
+// Plugins must be loaded before the configurations that use their Classes.
+// Loading the same plugin into a Skink context again does nothing, but since
+// Classes are registered globally, plugins that are loaded into more than
+// one Skink context must only register their Classes once.
+func Load(sk *skink.Skink, path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"failed to get absolute path of plugin %v: %v",
+			path, err)
+	}
+	loadedMutex.Lock()
+	defer loadedMutex.Unlock()
+	if loaded[sk][abs] {
+		return nil
+	}
+	p, err := plugin.Open(abs)
+	if err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"failed to open plugin %v: %v",
+			path, err)
+	}
+	sym, err := p.Lookup(RegisterSymbol)
+	if err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"plugin %v has no %v function: %v",
+			path, RegisterSymbol, err)
+	}
+	var register func(*skink.Skink) error
+	switch f := sym.(type) {
+	case func(*skink.Skink):
+		register = func(sk *skink.Skink) error {
+			f(sk)
+			return nil
+		}
+	case func(*skink.Skink) error:
+		register = f
+	default:
+		return errors.Errorf(
+			"%v of plugin %v must be a func(*skink.Skink) or "+
+				"func(*skink.Skink) error, not %T",
+			RegisterSymbol, path, sym)
+	}
+	if err = skink.Safely(func() error { return register(sk) }); err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"failed to register plugin %v: %v",
+			path, err)
+	}
+	if loaded[sk] == nil {
+		loaded[sk] = make(map[string]bool)
+	}
+	loaded[sk][abs] = true
+	sk.Info1("Loaded plugin %v", path)
+	return nil
+}
@@ -0,0 +1,123 @@
+package skinktest
+
+import (
+	"bytes"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/skillian/skink"
+)
+
+// TB is the part of testing.TB that the helpers use.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// MustCreate creates a root Node from def with sk or fails the test.
+func MustCreate(t TB, sk *skink.Skink, def *skink.NodeDef) skink.Node {
+	t.Helper()
+	root, err := sk.CreateNode(nil, def)
+	if err != nil {
+		t.Fatalf("failed to create Node from %v: %v", def.Name, err)
+	}
+	return root
+}
+
+// MustStart creates a root Node from def with sk and then initializes and
+// starts it or fails the test.
+func MustStart(t TB, sk *skink.Skink, def *skink.NodeDef) skink.Node {
+	t.Helper()
+	root := MustCreate(t, sk, def)
+	if err := sk.InitNode(root); err != nil {
+		t.Fatalf("failed to init %v: %v", def.Name, err)
+	}
+	if err := sk.StartNode(root); err != nil {
+		t.Fatalf("failed to start %v: %v", def.Name, err)
+	}
+	return root
+}
+
+// MustLoad loads a root Node from uri (e.g. one returned by (*Loader).Add)
+// into sk's Roots or fails the test.
+func MustLoad(t TB, sk *skink.Skink, uri *url.URL) skink.Node {
+	t.Helper()
+	root, err := sk.CreateNodeFromURI(uri)
+	if err != nil {
+		t.Fatalf("failed to load %v: %v", uri, err)
+	}
+	return root
+}
+
+// AssertPath gets the Node at path under node (see skink.GetChildByPath) or
+// fails the test.
+func AssertPath(t TB, node skink.Node, path string) skink.Node {
+	t.Helper()
+	child, err := skink.GetChildByPath(node, path)
+	if err != nil {
+		t.Fatalf("no Node %v under %v: %v", path, skink.GetPath(node), err)
+	}
+	return child
+}
+
+// AssertValue checks that the Node at path under node is a skink.Value whose
+// value is deeply equal to want.
+func AssertValue(t TB, node skink.Node, path string, want interface{}) {
+	t.Helper()
+	child := AssertPath(t, node, path)
+	v, ok := child.(skink.Value)
+	if !ok {
+		t.Errorf("%v is not a Value (type: %T)", skink.GetPath(child), child)
+		return
+	}
+	if got := v.Value(); !reflect.DeepEqual(got, want) {
+		t.Errorf("%v = %#v, want %#v", skink.GetPath(child), got, want)
+	}
+}
+
+// AssertChildren checks that node's children have the given names in order.
+// Names are compared like skink.Strings.
+func AssertChildren(t TB, node skink.Node, names ...string) {
+	t.Helper()
+	var got []string
+	if children := node.Children(); children != nil {
+		children.Range(func(name skink.String, n skink.Node) bool {
+			got = append(got, name.String())
+			return true
+		})
+	}
+	ok := len(got) == len(names)
+	for i := 0; ok && i < len(got); i++ {
+		ok = skink.MakeString(got[i]).Equal(skink.MakeString(names[i]))
+	}
+	if !ok {
+		t.Errorf("children of %v = %v, want %v", skink.GetPath(node), got, names)
+	}
+}
+
+// AssertTree checks that node's tree, exported with skink.ExportNodeDef, is
+// the same as want after both are normalized (see skink.Normalize).  The
+// differences are listed in the failure (see skink.DiffNodeDefs).
+func AssertTree(t TB, node skink.Node, want *skink.NodeDef) {
+	t.Helper()
+	got, err := skink.ExportNodeDef(node)
+	if err != nil {
+		t.Errorf("failed to export %v: %v", skink.GetPath(node), err)
+		return
+	}
+	skink.Normalize(got)
+	want = want.Clone(nil)
+	skink.Normalize(want)
+	if bytes.Equal(skink.Fingerprint(got), skink.Fingerprint(want)) {
+		return
+	}
+	changes := skink.DiffNodeDefs(want, got)
+	lines := make([]string, len(changes))
+	for i, c := range changes {
+		lines[i] = c.String()
+	}
+	t.Errorf("tree of %v differs:\n\t%s",
+		skink.GetPath(node), strings.Join(lines, "\n\t"))
+}
@@ -0,0 +1,96 @@
+// Package skinktest has helpers for testing skink Classes and the code that
+// uses them without configuration files: a fluent NodeDef Builder, an
+// in-memory URI loader (see Loader), fake Nodes and NodeMaps and assertion
+// helpers.
+//
+//	def := skinktest.B("app").
+//		Child("db", "import:nodes#String").Value("postgres://").Up().
+//		Child("cache", "import:nodes#Cache").
+//			Child("target", "").Value("app.db.query").
+//		Build()
+//	root := skinktest.MustStart(t, sk, def)
+package skinktest
+
+import (
+	"net/url"
+
+	"github.com/skillian/skink"
+)
+
+// DefaultClassURI is the ClassURI of the NodeDefs that a Builder creates
+// without one.
+const DefaultClassURI = "import:nodes#Node"
+
+// Builder builds a NodeDef tree one NodeDef at a time.  Each Builder refers
+// to one NodeDef in the tree: Child returns the Builder of the new child and
+// Up returns the Builder of the parent so that calls can be chained.
+type Builder struct {
+	def    *skink.NodeDef
+	parent *Builder
+}
+
+// B creates a Builder of a new root NodeDef named name.
+func B(name string) *Builder {
+	return &Builder{def: skink.NewNodeDef(
+		skink.MakeString(name), nil, mustParseClassURI(""))}
+}
+
+// Child adds a child NodeDef of the Class at classURI (or DefaultClassURI if
+// it's empty) and returns its Builder.  It panics if classURI isn't a valid
+// URI.
+func (b *Builder) Child(name, classURI string) *Builder {
+	child := b.def.NewChild(skink.MakeString(name), mustParseClassURI(classURI))
+	return &Builder{def: child, parent: b}
+}
+
+// Value sets the NodeDef's Value.
+func (b *Builder) Value(value string) *Builder {
+	b.def.Value = value
+	return b
+}
+
+// Class sets the NodeDef's ClassURI.  It panics if classURI isn't a valid
+// URI.
+func (b *Builder) Class(classURI string) *Builder {
+	b.def.ClassURI = mustParseClassURI(classURI)
+	return b
+}
+
+// Up gets the Builder of the NodeDef's parent.  The root's Builder returns
+// itself.
+func (b *Builder) Up() *Builder {
+	if b.parent == nil {
+		return b
+	}
+	return b.parent
+}
+
+// Root gets the Builder of the root NodeDef.
+func (b *Builder) Root() *Builder {
+	for b.parent != nil {
+		b = b.parent
+	}
+	return b
+}
+
+// NodeDef gets the Builder's own NodeDef.
+func (b *Builder) NodeDef() *skink.NodeDef {
+	return b.def
+}
+
+// Build gets the root NodeDef of the tree, no matter which of its Builders
+// it's called on.
+func (b *Builder) Build() *skink.NodeDef {
+	return b.Root().def
+}
+
+func mustParseClassURI(classURI string) *url.URL {
+	if classURI == "" {
+		classURI = DefaultClassURI
+	}
+	u, err := url.Parse(classURI)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
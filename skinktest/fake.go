@@ -0,0 +1,245 @@
+package skinktest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/skillian/skink"
+)
+
+// FakeNode is a Node of skink.NodeClass for building trees by hand, e.g. as
+// the parent or root passed to a Class's Init or a Node's StartNode.  It
+// implements skink.InitNoder and skink.StartNoder, counting the calls and
+// returning InitErr and StartErr.
+type FakeNode struct {
+	skink.BasicNode
+
+	// InitErr and StartErr are returned by InitNode and StartNode.
+	InitErr  error
+	StartErr error
+
+	mutex     sync.Mutex
+	inits     int
+	starts    int
+	startedBy skink.Node
+}
+
+// NewFakeNode creates a FakeNode named name with the given children, whose
+// parents are set to it.  The children must be FakeNodes or FakeValues.
+func NewFakeNode(name string, children ...skink.Node) *FakeNode {
+	n := &FakeNode{}
+	n.NodeClass = skink.NodeClass
+	n.NodeName = skink.MakeString(name)
+	n.NodeChildren = skink.NewNodeMap(len(children))
+	n.Add(children...)
+	return n
+}
+
+// parentSetter is implemented by the fake Nodes so that Add can set their
+// parents.
+type parentSetter interface {
+	setParent(parent skink.Node)
+}
+
+func (n *FakeNode) setParent(parent skink.Node) { n.NodeParent = parent }
+
+// Add adds children to the FakeNode and sets their parents.  It panics if a
+// child isn't a FakeNode or FakeValue or if its name is already used.
+func (n *FakeNode) Add(children ...skink.Node) *FakeNode {
+	addFakeChildren(n, n.NodeChildren, children)
+	return n
+}
+
+func addFakeChildren(parent skink.Node, m skink.NodeMap, children []skink.Node) {
+	for _, child := range children {
+		ps, ok := child.(parentSetter)
+		if !ok {
+			panic(fmt.Sprintf("cannot add %T to a fake Node", child))
+		}
+		ps.setParent(parent)
+		if err := m.AddNode(child, false); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// InitNode implements skink.InitNoder.
+func (n *FakeNode) InitNode(sk *skink.Skink) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.inits++
+	return n.InitErr
+}
+
+// StartNode implements skink.StartNoder.
+func (n *FakeNode) StartNode(sk *skink.Skink, root skink.Node) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.starts++
+	n.startedBy = root
+	return n.StartErr
+}
+
+// Inits gets how many times InitNode was called.
+func (n *FakeNode) Inits() int {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.inits
+}
+
+// Starts gets how many times StartNode was called.
+func (n *FakeNode) Starts() int {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.starts
+}
+
+// StartedBy gets the root that StartNode was last called with.
+func (n *FakeNode) StartedBy() skink.Node {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.startedBy
+}
+
+// FakeValue is a FakeNode that's also a skink.Value of Val.
+type FakeValue struct {
+	FakeNode
+	Val interface{}
+}
+
+// NewFakeValue creates a FakeValue named name with the value val.
+func NewFakeValue(name string, val interface{}, children ...skink.Node) *FakeValue {
+	v := &FakeValue{Val: val}
+	v.NodeClass = skink.NodeClass
+	v.NodeName = skink.MakeString(name)
+	v.NodeChildren = skink.NewNodeMap(len(children))
+	v.Add(children...)
+	return v
+}
+
+// Add adds children to the FakeValue like (*FakeNode).Add.
+func (v *FakeValue) Add(children ...skink.Node) *FakeValue {
+	addFakeChildren(v, v.NodeChildren, children)
+	return v
+}
+
+// Value implements skink.Value.
+func (v *FakeValue) Value() interface{} { return v.Val }
+
+// FakeNodeMap wraps a NodeMap to record the calls that change it and to fail
+// them with Err, so that code that modifies a Node's children can be tested
+// against failures.  Calls that don't change the NodeMap are passed
+// through.
+type FakeNodeMap struct {
+	skink.NodeMap
+
+	// Err, if not nil, is returned by the calls that would change the
+	// NodeMap instead of changing it.
+	Err error
+
+	mutex sync.Mutex
+	calls []string
+}
+
+// NewFakeNodeMap wraps m in a FakeNodeMap.  If m is nil, an empty NodeMap is
+// wrapped.
+func NewFakeNodeMap(m skink.NodeMap) *FakeNodeMap {
+	if m == nil {
+		m = skink.NewNodeMap(-1)
+	}
+	return &FakeNodeMap{NodeMap: m}
+}
+
+// Calls gets descriptions of the calls that (tried to) change the NodeMap,
+// in order, such as "AddNode(db, false)".
+func (m *FakeNodeMap) Calls() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	calls := make([]string, len(m.calls))
+	copy(calls, m.calls)
+	return calls
+}
+
+// record records a call and gets the error to fail it with.
+func (m *FakeNodeMap) record(format string, args ...interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.calls = append(m.calls, fmt.Sprintf(format, args...))
+	return m.Err
+}
+
+// AddNode implements skink.NodeMap.
+func (m *FakeNodeMap) AddNode(node skink.Node, overwrite bool) error {
+	if err := m.record("AddNode(%v, %v)", node.Name(), overwrite); err != nil {
+		return err
+	}
+	return m.NodeMap.AddNode(node, overwrite)
+}
+
+// AddNodes implements skink.NodeMap.
+func (m *FakeNodeMap) AddNodes(nodes []skink.Node, overwrite bool) error {
+	names := make([]string, len(nodes))
+	for i, node := range nodes {
+		names[i] = node.Name().String()
+	}
+	if err := m.record("AddNodes(%v, %v)", names, overwrite); err != nil {
+		return err
+	}
+	return m.NodeMap.AddNodes(nodes, overwrite)
+}
+
+// InsertNode implements skink.NodeMap.
+func (m *FakeNodeMap) InsertNode(index int, node skink.Node) error {
+	if err := m.record("InsertNode(%d, %v)", index, node.Name()); err != nil {
+		return err
+	}
+	return m.NodeMap.InsertNode(index, node)
+}
+
+// Move implements skink.NodeMap.
+func (m *FakeNodeMap) Move(from, to int) error {
+	if err := m.record("Move(%d, %d)", from, to); err != nil {
+		return err
+	}
+	return m.NodeMap.Move(from, to)
+}
+
+// Merge implements skink.NodeMap.
+func (m *FakeNodeMap) Merge(other skink.NodeMap, overwrite bool) error {
+	if err := m.record("Merge(%d, %v)", other.Len(), overwrite); err != nil {
+		return err
+	}
+	return m.NodeMap.Merge(other, overwrite)
+}
+
+// Rename implements skink.NodeMap.
+func (m *FakeNodeMap) Rename(old, new skink.String) error {
+	if err := m.record("Rename(%v, %v)", old, new); err != nil {
+		return err
+	}
+	return m.NodeMap.Rename(old, new)
+}
+
+// RemoveName implements skink.NodeMap.
+func (m *FakeNodeMap) RemoveName(name skink.String) error {
+	if err := m.record("RemoveName(%v)", name); err != nil {
+		return err
+	}
+	return m.NodeMap.RemoveName(name)
+}
+
+// RemoveIndex implements skink.NodeMap.
+func (m *FakeNodeMap) RemoveIndex(index int) error {
+	if err := m.record("RemoveIndex(%d)", index); err != nil {
+		return err
+	}
+	return m.NodeMap.RemoveIndex(index)
+}
+
+// Remove implements skink.NodeMap.
+func (m *FakeNodeMap) Remove(node skink.Node) error {
+	if err := m.record("Remove(%v)", node.Name()); err != nil {
+		return err
+	}
+	return m.NodeMap.Remove(node)
+}
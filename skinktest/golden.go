@@ -0,0 +1,24 @@
+package skinktest
+
+import (
+	"flag"
+
+	"github.com/skillian/skink"
+)
+
+// Update makes AssertGolden (re)write golden files instead of comparing
+// trees with them:
+//
+//	go test ./... -skinktest.update
+var Update = flag.Bool(
+	"skinktest.update", false, "update golden files instead of comparing with them")
+
+// AssertGolden checks that the canonical form of the tree under node is the
+// same as the tree in the golden file at path (see skink.CompareGolden),
+// which is conventionally under testdata, e.g. "testdata/app.golden.json".
+func AssertGolden(t TB, node skink.Node, path string) {
+	t.Helper()
+	if err := skink.CompareGolden(node, path, *Update); err != nil {
+		t.Errorf("%v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package skinktest
+
+import (
+	"net/url"
+	"sync"
+
+	"github.com/skillian/errors"
+	"github.com/skillian/skink"
+)
+
+// Scheme is the URI scheme of the NodeDef trees in a Loader, e.g. "mem:app".
+const Scheme = "mem"
+
+// Loader is an in-memory URI loader and writer of NodeDef trees by name so
+// that code that loads configuration from URIs can be tested without files.
+// Trees are cloned when they're added, loaded and written so that tests
+// can't change each other's trees.
+type Loader struct {
+	mutex sync.RWMutex
+	defs  map[string]*skink.NodeDef
+}
+
+// NewLoader creates an empty Loader.
+func NewLoader() *Loader {
+	return &Loader{defs: make(map[string]*skink.NodeDef)}
+}
+
+// URI gets the URI of the tree named name in a Loader.
+func URI(name string) *url.URL {
+	return &url.URL{Scheme: Scheme, Opaque: name}
+}
+
+// Add adds (or replaces) the tree named name and gets its URI.
+func (l *Loader) Add(name string, def *skink.NodeDef) *url.URL {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.defs[name] = def.Clone(nil)
+	return URI(name)
+}
+
+// Load loads a tree by the name in its URI.  It's a URI loader for
+// (*skink.Skink).RegisterURILoader.
+func (l *Loader) Load(uri *url.URL) (*skink.NodeDef, error) {
+	name := skink.GetURIPath(uri)
+	l.mutex.RLock()
+	def, ok := l.defs[name]
+	l.mutex.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("no NodeDef tree named %q", name)
+	}
+	return def.Clone(nil), nil
+}
+
+// Write replaces the tree named by uri with def.  It's a URI writer for
+// (*skink.Skink).RegisterURIWriter.
+func (l *Loader) Write(uri *url.URL, def *skink.NodeDef) error {
+	l.Add(skink.GetURIPath(uri), def)
+	return nil
+}
+
+// Install registers the Loader as sk's URI loader and writer of Scheme.
+func (l *Loader) Install(sk *skink.Skink) {
+	sk.RegisterURILoader(l.Load, nil, Scheme)
+	sk.RegisterURIWriter(l.Write, Scheme)
+}
+
+// NewSkink creates a child Skink context of skink.GlobalSkink with a new
+// Loader installed.
+func NewSkink(t TB) (*skink.Skink, *Loader) {
+	t.Helper()
+	sk, err := skink.GlobalSkink.CreateChild("skinktest")
+	if err != nil {
+		t.Fatalf("failed to create Skink context: %v", err)
+	}
+	l := NewLoader()
+	l.Install(sk)
+	return sk, l
+}
@@ -0,0 +1,95 @@
+package skink
+
+import (
+	"sort"
+
+	"github.com/skillian/errors"
+)
+
+// sortedNodeMap is a NodeMap that keeps its Nodes ordered by name (see
+// String.Cmp) instead of by insertion order.
+type sortedNodeMap struct {
+	nodemap
+}
+
+// NewSortedNodeMap creates a NodeMap that keeps its Nodes sorted by name so
+// that iterating over it is deterministic.  The capacity is interpreted just
+// like NewNodeMap's.  Because the order is determined by the Nodes' names,
+// InsertNode and Move always fail.
+func NewSortedNodeMap(capacity int) NodeMap {
+	m := &sortedNodeMap{
+		nodemap: nodemap{
+			index: makeNodeMapIndex(capacity),
+			pairs: makeNodeMapPairs(capacity),
+		},
+	}
+	m.outer = m
+	return m
+}
+
+// AddNode adds the Node at its sorted position.
+func (m *sortedNodeMap) AddNode(node Node, overwrite bool) error {
+	if _, ok := m.index[m.key(node.Name())]; ok {
+		// Overwriting keeps the same name and therefore the same position.
+		return m.nodemap.AddNode(node, overwrite)
+	}
+	return m.nodemap.InsertNode(m.search(node.Name()), node)
+}
+
+func (m *sortedNodeMap) AddNodes(nodes []Node, overwrite bool) error {
+	if err := m.prepareAddNodes(nodes, overwrite); err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if err := m.AddNode(node, overwrite); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *sortedNodeMap) Clone() NodeMap {
+	c := &sortedNodeMap{nodemap: *m.nodemap.clone()}
+	c.outer = c
+	return c
+}
+
+func (m *sortedNodeMap) Merge(other NodeMap, overwrite bool) error {
+	return m.AddNodes(other.Nodes(), overwrite)
+}
+
+func (m *sortedNodeMap) InsertNode(index int, node Node) error {
+	return errors.Errorf(
+		"cannot insert %v at index %d into a sorted NodeMap",
+		node.Name(), index)
+}
+
+func (m *sortedNodeMap) Move(from, to int) error {
+	return errors.Errorf(
+		"cannot move index %d to %d in a sorted NodeMap", from, to)
+}
+
+// Rename renames the Node and moves it to its new sorted position.
+func (m *sortedNodeMap) Rename(old, new String) error {
+	if err := m.nodemap.Rename(old, new); err != nil {
+		return err
+	}
+	from := m.index[m.key(new)]
+	to := 0
+	for i, pair := range m.pairs {
+		if i != from && pair.node.Name().Cmp(new) < 0 {
+			to++
+		}
+	}
+	if from == to {
+		return nil
+	}
+	return m.nodemap.Move(from, to)
+}
+
+// search gets the index that a Node with the given name would be inserted at.
+func (m *sortedNodeMap) search(name String) int {
+	return sort.Search(len(m.pairs), func(i int) bool {
+		return m.pairs[i].node.Name().Cmp(name) > 0
+	})
+}
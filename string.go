@@ -1,8 +1,12 @@
 package skink
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"net/url"
+	"path"
 	"strings"
+	"sync"
 
 	"github.com/skillian/errors"
 )
@@ -10,21 +14,106 @@ import (
 // String is a custom string type Skink uses to get custom comparison behavior
 type String struct {
 	value string
+
+	// lower is the folded value of the String or empty if it's the same as
+	// value.  Most names are already lower-case and the CaseFolders return
+	// their input as-is when there's nothing to fold, so only the Strings
+	// that aren't hold a second string (see Lower).
 	lower string
 }
 
-// MakeString converts a Go string into a skink String.
+// MakeString converts a Go string into a skink String whose value is
+// normalized with the default Normalizer (see SetDefaultNormalizer) and whose
+// Lower value is folded with the default CaseFolder (see
+// SetDefaultCaseFolder).  If string
+// interning is on (see SetStringInterning), the String is interned.
 func MakeString(value string) String {
-	return String{value: value, lower: strings.ToLower(value)}
+	if StringInterning() {
+		return InternString(value)
+	}
+	return MakeStringWithFolder(value, DefaultCaseFolder())
 }
 
 // Cmp performs a case-insensitive comparison of the two strings.
 func (s String) Cmp(other String) int {
-	return strings.Compare(s.lower, other.lower)
+	return strings.Compare(s.Lower(), other.Lower())
+}
+
+// Equal checks if the two strings are equal, ignoring case.  It's faster than
+// comparing the result of Cmp with 0:  Strings of different lengths are
+// unequal without comparing their contents.
+func (s String) Equal(other String) bool {
+	return s.Lower() == other.Lower()
+}
+
+// Hash gets a hash of the case-insensitive value of the String.  Strings that
+// are Equal have the same Hash.  It's calculated on every call so that
+// Strings that are never hashed (most of them) don't pay for it.
+func (s String) Hash() uint64 {
+	return hashString(s.Lower())
+}
+
+// hashString calculates the FNV-1a hash of s.
+func hashString(s string) uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime
+	}
+	return h
+}
+
+// HasPrefixFold checks if the String begins with prefix, ignoring case.
+func (s String) HasPrefixFold(prefix String) bool {
+	return strings.HasPrefix(s.Lower(), prefix.Lower())
+}
+
+// HasSuffixFold checks if the String ends with suffix, ignoring case.
+func (s String) HasSuffixFold(suffix String) bool {
+	return strings.HasSuffix(s.Lower(), suffix.Lower())
+}
+
+// ContainsFold checks if substr is within the String, ignoring case.
+func (s String) ContainsFold(substr String) bool {
+	return strings.Contains(s.Lower(), substr.Lower())
+}
+
+// MatchGlob checks if the String matches a glob pattern (using the syntax of
+// path.Match), ignoring case.  An error is only returned if the pattern is
+// malformed.
+func (s String) MatchGlob(pattern String) (bool, error) {
+	ok, err := path.Match(pattern.Lower(), s.Lower())
+	if err != nil {
+		return false, errors.ErrorfWithCause(
+			err,
+			"invalid pattern %q: %v",
+			pattern.value, err)
+	}
+	return ok, nil
+}
+
+// EscapeGlob escapes the characters of s that are special in glob patterns
+// (see MatchGlob) so that the pattern only matches s itself.
+func EscapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
 }
 
 // Lower gets the string in an all-lower case form.
 func (s String) Lower() string {
+	if s.lower == "" {
+		return s.value
+	}
 	return s.lower
 }
 
@@ -33,28 +122,142 @@ func (s String) String() string {
 	return s.value
 }
 
+// MarshalText implements encoding.TextMarshaler.  A String is marshaled as
+// its original (not lower-cased) value.
+func (s String) MarshalText() ([]byte, error) {
+	return []byte(s.value), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *String) UnmarshalText(text []byte) error {
+	*s = MakeString(string(text))
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.  A String is marshaled as a JSON
+// string of its original value.
+func (s String) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *String) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.ErrorfWithCause(
+			err, "failed to unmarshal String from %q: %v", data, err)
+	}
+	*s = MakeString(value)
+	return nil
+}
+
 // StringNode is a skink String that implements the Node interface.
 type StringNode struct {
 	name   String
 	parent Node
 	String
+
+	// mutex guards String and onChange against concurrent SetValue and
+	// OnChange calls.
+	mutex    sync.RWMutex
+	onChange []*ValueChangeHook
+}
+
+// newStringNode creates a parentless StringNode.
+func newStringNode(name String, value string) *StringNode {
+	return &StringNode{name: name, String: makeValueString(value)}
 }
 
 // Name gets the name of the string in the configuration
-func (s StringNode) Name() String { return s.name }
+func (s *StringNode) Name() String { return s.name }
 
 // Parent gets the string's parent Node
-func (s StringNode) Parent() Node { return s.parent }
+func (s *StringNode) Parent() Node { return s.parent }
 
 // Class gets the class of the string (StringClass)
-func (s StringNode) Class() Class { return StringClass }
+func (s *StringNode) Class() Class { return StringClass }
 
 // Children returns a nil NodeMap (a string literal cannot have any child
 // nodes).
-func (s StringNode) Children() NodeMap { return nil }
+func (s *StringNode) Children() NodeMap { return nil }
 
 // Value gets the string value as a Go string.
-func (s StringNode) Value() interface{} { return s.value }
+func (s *StringNode) Value() interface{} {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.value
+}
+
+// SetValue sets the StringNode's value from a string, String or a Value whose
+// value is a string and then calls the OnChange hooks.
+func (s *StringNode) SetValue(value interface{}) error {
+	var str String
+	switch value := value.(type) {
+	case string:
+		str = makeValueString(value)
+	case String:
+		str = value
+	case Value:
+		v, ok := value.Value().(string)
+		if !ok {
+			return errors.Errorf(
+				"cannot set StringNode %v to %T value of %v",
+				s.name, value.Value(), value.Name())
+		}
+		str = makeValueString(v)
+	default:
+		return errors.Errorf(
+			"cannot set StringNode %v to %T", s.name, value)
+	}
+	s.mutex.Lock()
+	old := s.value
+	s.String = str
+	hooks := make([]*ValueChangeHook, len(s.onChange))
+	copy(hooks, s.onChange)
+	s.mutex.Unlock()
+	for _, hook := range hooks {
+		(*hook)(s, old, str.value)
+	}
+	return nil
+}
+
+// OnChange registers a hook called after SetValue changes the StringNode's
+// value.  The hooks are called outside of the StringNode's lock, so they can
+// call SetValue or OnChange themselves.
+func (s *StringNode) OnChange(hook ValueChangeHook) func() {
+	p := &hook
+	s.mutex.Lock()
+	s.onChange = append(s.onChange, p)
+	s.mutex.Unlock()
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for i, h := range s.onChange {
+			if h == p {
+				s.onChange = append(s.onChange[:i:i], s.onChange[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// MarshalJSON implements json.Marshaler.  A StringNode is a leaf so it's
+// marshaled as just its value; its name is the key of the object that
+// contains it.
+func (s *StringNode) MarshalJSON() ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.String.MarshalJSON()
+}
+
+// MarshalXML implements xml.Marshaler.  A StringNode is marshaled as an
+// element named after the StringNode whose character data is its value.
+func (s *StringNode) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if s.name.value != "" {
+		start.Name = xml.Name{Local: s.name.value}
+	}
+	return e.EncodeElement(s.Value(), start)
+}
 
 var (
 	stringClassURIValue = url.URL{
@@ -87,7 +290,9 @@ func (c stringClassType) Alloc(nodeDef *NodeDef) (Node, error) {
 func (c stringClassType) Init(self, parent Node, nodeDef *NodeDef) error {
 	if sn, ok := self.(*StringNode); ok {
 		sn.name = nodeDef.Name
-		sn.String = MakeString(nodeDef.Value)
+		sn.parent = parent
+		sn.String = makeValueString(nodeDef.Value)
+		return nil
 	}
 	return errors.Errorf("StringClass cannot init %T, only StringNode.", self)
 }
@@ -0,0 +1,130 @@
+package skink
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeoutError is returned by InitNode and StartNode when the Skink context's
+// InitTimeout or StartTimeout expires before all of the Nodes finish.
+type TimeoutError struct {
+	Phase   LifecyclePhase
+	Timeout time.Duration
+
+	// Pending are the Nodes that hadn't finished when the timeout expired,
+	// longest-running first.
+	Pending []PendingNode
+
+	// Goroutines is a dump of every goroutine's stack when the timeout
+	// expired if the Skink context's DumpGoroutinesOnTimeout is set.
+	Goroutines string
+}
+
+// PendingNode is a Node that hadn't finished a lifecycle phase when a
+// TimeoutError happened.
+type PendingNode struct {
+	Path    string
+	Running time.Duration
+}
+
+// Error implements the error interface.
+func (e *TimeoutError) Error() string {
+	pending := make([]string, len(e.Pending))
+	for i, p := range e.Pending {
+		pending[i] = fmt.Sprintf("%s (running for %v)", p.Path, p.Running)
+	}
+	return fmt.Sprintf(
+		"timed out after %v waiting for Nodes to %s; %d still running: %s",
+		e.Timeout, e.Phase, len(e.Pending), strings.Join(pending, ", "))
+}
+
+// ErrorKind implements ErrorKinder.
+func (e *TimeoutError) ErrorKind() ErrorKind {
+	if e.Phase == InitPhase {
+		return InitErrorKind
+	}
+	return StartErrorKind
+}
+
+// pendingNodes keeps track of the Nodes that are in the middle of a
+// lifecycle phase and when they started it.
+type pendingNodes struct {
+	mutex sync.Mutex
+	nodes map[Node]time.Time
+}
+
+func newPendingNodes() *pendingNodes {
+	return &pendingNodes{nodes: make(map[Node]time.Time)}
+}
+
+func (p *pendingNodes) begin(node Node) time.Time {
+	start := time.Now()
+	p.mutex.Lock()
+	p.nodes[node] = start
+	p.mutex.Unlock()
+	return start
+}
+
+func (p *pendingNodes) end(node Node) {
+	p.mutex.Lock()
+	delete(p.nodes, node)
+	p.mutex.Unlock()
+}
+
+// snapshot gets the pending Nodes, longest-running first.
+func (p *pendingNodes) snapshot() []PendingNode {
+	now := time.Now()
+	p.mutex.Lock()
+	pending := make([]PendingNode, 0, len(p.nodes))
+	for node, start := range p.nodes {
+		pending = append(pending, PendingNode{
+			Path:    GetPath(node),
+			Running: now.Sub(start),
+		})
+	}
+	p.mutex.Unlock()
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Running != pending[j].Running {
+			return pending[i].Running > pending[j].Running
+		}
+		return pending[i].Path < pending[j].Path
+	})
+	return pending
+}
+
+// waitTimeout waits for done to be closed.  If timeout is positive and
+// expires first, false is returned.
+func waitTimeout(done <-chan struct{}, timeout time.Duration) bool {
+	if timeout <= 0 {
+		<-done
+		return true
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// timeoutError makes and logs the TimeoutError of a phase.
+func (sk *Skink) timeoutError(phase LifecyclePhase, timeout time.Duration, pending *pendingNodes) error {
+	err := &TimeoutError{
+		Phase:   phase,
+		Timeout: timeout,
+		Pending: pending.snapshot(),
+	}
+	logger.Error1("%v", err)
+	if sk.DumpGoroutinesOnTimeout {
+		buf := make([]byte, 1<<20)
+		err.Goroutines = string(buf[:runtime.Stack(buf, true)])
+		logger.Error1("goroutines:\n%s", err.Goroutines)
+	}
+	return err
+}
@@ -0,0 +1,259 @@
+package skink
+
+import (
+	"github.com/skillian/errors"
+)
+
+// ValueValidator is implemented by Classes whose Nodes parse their NodeDefs'
+// Values so that ValidateNodeDef can check the Values without allocating the
+// Nodes.
+type ValueValidator interface {
+	ValidateValue(value string) error
+}
+
+// NodeDefReferrer is implemented by Classes whose Nodes refer to other Nodes
+// by path (see CompilePath).  NodeDefReferences gets the paths that a Node
+// created from the NodeDef would refer to, relative to that NodeDef.
+type NodeDefReferrer interface {
+	NodeDefReferences(nodedef *NodeDef) []string
+}
+
+// ValidateNodeDef checks a whole NodeDef tree without creating any Nodes from
+// it:
+//
+//   - Every NodeDef must have a ClassURI that's either registered or can be
+//     used to create a dynamic Class (i.e. it has a fragment).
+//   - Every Required TypeAttr of a Class (see TypeAttrMapper) must have a
+//     matching child NodeDef.
+//   - Classes that implement ValueValidator must accept their NodeDefs'
+//     Values.
+//   - The references of Classes that implement NodeDefReferrer must resolve
+//     to NodeDefs in the tree and must not form cycles.
+//   - NodeDefs must not be their own ancestors, either through their Parent
+//     links or their Children.  If they are, the rest of the tree isn't
+//     checked.
+//
+// Cycles are reported as CycleErrors.
+//
+// All of the problems found are returned together in a *ValidationErrors as
+// ValidationErrorKind errors.  The Issues found by the registered LintRules
+// (see Lint) are added to the Skink context's Warnings.
+func (sk *Skink) ValidateNodeDef(def *NodeDef) error {
+	ve := NewValidationErrors()
+	if validateNodeDefStructure(def, ve) {
+		refs := make(map[*NodeDef][]*NodeDef)
+		validateNodeDef(def, refs, ve)
+		validateNodeDefReferenceCycles(def, refs, ve)
+		for _, issue := range Lint(def) {
+			sk.AddWarning(issue.Path, "%s (%s)", issue.Message, issue.Rule)
+		}
+	}
+	if ve.Len() == 0 {
+		return nil
+	}
+	return ve
+}
+
+// invalid adds a ValidationErrorKind error about the NodeDef at path.
+func invalid(ve *ValidationErrors, path string, err error) {
+	ve.Add(path, WithErrorKind(ValidationErrorKind, err))
+}
+
+func validateNodeDef(def *NodeDef, refs map[*NodeDef][]*NodeDef, ve *ValidationErrors) {
+	path := nodeDefPath(def)
+	cls, err := validateNodeDefClass(def)
+	if err != nil {
+		invalid(ve, path, err)
+	}
+	if cls != nil {
+		if mapper, ok := cls.(TypeAttrMapper); ok {
+			for _, attr := range mapper.TypeAttrMap().TypeAttrs() {
+				if attr.Required && def.FindChild(attr.Name) == nil {
+					invalid(ve, path, errors.Errorf(
+						"missing required attribute %v of Class %v",
+						attr.Name, cls.Name()))
+				}
+			}
+		}
+		if validator, ok := cls.(ValueValidator); ok {
+			if err := validator.ValidateValue(def.Value); err != nil {
+				invalid(ve, path, errors.ErrorfWithCause(
+					err,
+					"invalid value %q: %v",
+					def.Value, err))
+			}
+		}
+		if referrer, ok := cls.(NodeDefReferrer); ok {
+			for _, ref := range referrer.NodeDefReferences(def) {
+				target, err := validateNodeDefReference(def, ref)
+				if err != nil {
+					invalid(ve, path, errors.ErrorfWithCause(
+						err,
+						"unresolved reference %q: %v",
+						ref, err))
+					continue
+				}
+				refs[def] = append(refs[def], target)
+			}
+		}
+	}
+	for _, child := range def.Children {
+		validateNodeDef(child, refs, ve)
+	}
+}
+
+// validateNodeDefStructure checks that def isn't its own ancestor through
+// its Parent links and that no NodeDef in its tree is its own descendant.
+// If either is, the rest of validation would recurse forever so false is
+// returned.
+func validateNodeDefStructure(def *NodeDef, ve *ValidationErrors) bool {
+	seen := make(map[*NodeDef]bool)
+	names := make([]string, 0, 8)
+	for parent := def; parent != nil; parent = parent.Parent {
+		names = append(names, parent.Name.String())
+		if seen[parent] {
+			invalid(ve, def.Name.String(), errors.ErrorfWithCause(
+				CycleError{Paths: names},
+				"NodeDef's Parents form a cycle: %v",
+				CycleError{Paths: names}))
+			return false
+		}
+		seen[parent] = true
+	}
+	return validateNodeDefChildren(def, make([]*NodeDef, 0, 8), ve)
+}
+
+// validateNodeDefChildren checks that none of def's descendants are already
+// on the stack of its ancestors.
+func validateNodeDefChildren(def *NodeDef, stack []*NodeDef, ve *ValidationErrors) bool {
+	for i, ancestor := range stack {
+		if ancestor != def {
+			continue
+		}
+		paths := make([]string, 0, len(stack)-i+1)
+		for j := i; j < len(stack); j++ {
+			paths = append(paths, nodeDefStackPath(stack[:j+1]))
+		}
+		paths = append(paths, nodeDefStackPath(append(stack, def)))
+		invalid(ve, paths[0], errors.ErrorfWithCause(
+			CycleError{Paths: paths},
+			"NodeDef is its own descendant: %v",
+			CycleError{Paths: paths}))
+		return false
+	}
+	stack = append(stack, def)
+	ok := true
+	for _, child := range def.Children {
+		if !validateNodeDefChildren(child, stack, ve) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// nodeDefStackPath gets the path of the last NodeDef in the stack from the
+// names of the NodeDefs in the stack (rather than its Parent links, which
+// might be wrong).
+func nodeDefStackPath(stack []*NodeDef) string {
+	names := make([]string, len(stack))
+	for i, def := range stack {
+		names[i] = def.Name.String()
+	}
+	return JoinNodePath(names...)
+}
+
+// validateNodeDefReferenceCycles reports the cycles in the references
+// between the NodeDefs in root's tree.
+func validateNodeDefReferenceCycles(root *NodeDef, refs map[*NodeDef][]*NodeDef, ve *ValidationErrors) {
+	if len(refs) == 0 {
+		return
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[*NodeDef]int, len(refs))
+	stack := make([]*NodeDef, 0, 8)
+	var visit func(def *NodeDef)
+	visit = func(def *NodeDef) {
+		states[def] = visiting
+		stack = append(stack, def)
+		for _, target := range refs[def] {
+			switch states[target] {
+			case unvisited:
+				visit(target)
+			case visiting:
+				paths := make([]string, 0, len(stack)+1)
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == target {
+						for _, def := range stack[i:] {
+							paths = append(paths, nodeDefPath(def))
+						}
+						break
+					}
+				}
+				paths = append(paths, nodeDefPath(target))
+				invalid(ve, nodeDefPath(def), errors.ErrorfWithCause(
+					CycleError{Paths: paths},
+					"reference cycle: %v",
+					CycleError{Paths: paths}))
+			}
+		}
+		stack = stack[:len(stack)-1]
+		states[def] = visited
+	}
+	var walk func(def *NodeDef)
+	walk = func(def *NodeDef) {
+		if states[def] == unvisited {
+			visit(def)
+		}
+		for _, child := range def.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+}
+
+// validateNodeDefClass gets the Class that CreateNode would use for def.  If
+// the Class would be created dynamically, its base Class is returned
+// instead.
+func validateNodeDefClass(def *NodeDef) (Class, error) {
+	if def.ClassURI == nil {
+		return nil, errors.Errorf("NodeDef has no Class URI")
+	}
+	cls, err := GetClassByURI(def.ClassURI)
+	if err == nil {
+		return cls, nil
+	}
+	if _, ok := err.(ClassNotFound); !ok {
+		return nil, err
+	}
+	if def.ClassURI.Fragment == "" {
+		return nil, errors.Errorf(
+			"Class %v is not registered and has no fragment to name "+
+				"a dynamic Class",
+			def.ClassURI)
+	}
+	return GetBaseClassFromURI(def.ClassURI), nil
+}
+
+func validateNodeDefReference(def *NodeDef, ref string) (*NodeDef, error) {
+	path, err := CompilePath(ref)
+	if err != nil {
+		return nil, err
+	}
+	return path.ResolveNodeDef(def)
+}
+
+// nodeDefPath gets the path of a NodeDef from its root for error messages.
+func nodeDefPath(def *NodeDef) string {
+	names := make([]string, 0, 8)
+	for ; def != nil; def = def.Parent {
+		names = append(names, def.Name.String())
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return JoinNodePath(names...)
+}
@@ -0,0 +1,89 @@
+package skink
+
+import (
+	"fmt"
+)
+
+// Warning is a problem that didn't stop a NodeDef from being loaded or a Node
+// from being created (e.g. a deprecated Class URI or a value that was
+// coerced lossily) but that the configuration's author should know about.
+type Warning struct {
+	// Path is the path of the Node (or NodeDef) that the Warning is about.
+	Path string
+
+	Message string
+}
+
+// String implements fmt.Stringer.
+func (w Warning) String() string {
+	if w.Path == "" {
+		return w.Message
+	}
+	return w.Path + ": " + w.Message
+}
+
+// AddWarning adds a Warning about the NodeDef.  URI loaders and Classes'
+// Init methods, which don't have access to the Skink context, use it to
+// report warnings, which the Skink context then collects after the NodeDef
+// is loaded (see CreateNodeDef) or its Node is created (see CreateNode).
+func (n *NodeDef) AddWarning(format string, args ...interface{}) {
+	n.Warnings = append(n.Warnings, fmt.Sprintf(format, args...))
+}
+
+// AddWarning adds a Warning to the Skink context.  InitNoders and StartNoders
+// use it to report warnings directly.  Warnings are logged as they're added.
+func (sk *Skink) AddWarning(path, format string, args ...interface{}) {
+	sk.addWarnings(Warning{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (sk *Skink) addWarnings(warnings ...Warning) {
+	if len(warnings) == 0 {
+		return
+	}
+	for _, w := range warnings {
+		logger.Warn1("%v", w)
+	}
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	sk.warnings = append(sk.warnings, warnings...)
+}
+
+// Warnings gets the Warnings collected by the Skink context in the order they
+// were added.
+func (sk *Skink) Warnings() []Warning {
+	sk.mutex.RLock()
+	defer sk.mutex.RUnlock()
+	warnings := make([]Warning, len(sk.warnings))
+	copy(warnings, sk.warnings)
+	return warnings
+}
+
+// ClearWarnings discards the Warnings collected by the Skink context.
+func (sk *Skink) ClearWarnings() {
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	sk.warnings = nil
+}
+
+// collectWarnings moves the warnings of nodedef (and its descendants if deep
+// is true) into the Skink context.
+func (sk *Skink) collectWarnings(nodedef *NodeDef, deep bool) {
+	var warnings []Warning
+	var collect func(def *NodeDef)
+	collect = func(def *NodeDef) {
+		if len(def.Warnings) > 0 {
+			path := nodeDefPath(def)
+			for _, msg := range def.Warnings {
+				warnings = append(warnings, Warning{Path: path, Message: msg})
+			}
+			def.Warnings = nil
+		}
+		if deep {
+			for _, child := range def.Children {
+				collect(child)
+			}
+		}
+	}
+	collect(nodedef)
+	sk.addWarnings(warnings...)
+}
@@ -1,8 +1,8 @@
 package skink
 
 import (
+	"bytes"
 	"encoding/xml"
-	"fmt"
 	"io"
 	"net/url"
 	"os"
@@ -12,9 +12,86 @@ import (
 	"github.com/skillian/errors"
 )
 
+// XMLOptions configure how XML documents are loaded.
+type XMLOptions struct {
+	// Fidelity records how each NodeDef was represented in the document
+	// (see XMLInfo) so that WriteXML can write an equivalent document back
+	// after the NodeDef tree is modified.
+	Fidelity bool
+
+	// AllowDuplicates keeps the names of repeated child elements as they
+	// are instead of numbering them (see NodeDef.UniqueChildName).  The
+	// NodeDefs' parents must then be of Classes whose children's NodeMaps
+	// allow duplicates (e.g. MultiNodeClass).
+	AllowDuplicates bool
+}
+
+// XMLInfo records how a NodeDef was represented in an XML document.  It is
+// only recorded when loading with XMLOptions.Fidelity.
+type XMLInfo struct {
+	// Name is the name of the element or attribute.  Its Space is the
+	// namespace URL, not the prefix.
+	Name xml.Name
+
+	// Attr is true if the NodeDef was an attribute instead of an element.
+	Attr bool
+
+	// Namespaces are the namespace declarations (xmlns attributes) of the
+	// element.
+	Namespaces []xml.Attr
+
+	// Content is the element's content in document order.
+	Content []XMLContent
+}
+
+// XMLContent is either character data, a comment or a child element within
+// an element.
+type XMLContent struct {
+	// Token is the xml.CharData or xml.Comment if Child is nil.
+	Token xml.Token
+
+	// Child is the NodeDef of a child element.
+	Child *NodeDef
+}
+
+// clone copies the XMLInfo and replaces the Children in its Content with
+// their clones.
+func (x *XMLInfo) clone(clones map[*NodeDef]*NodeDef) *XMLInfo {
+	if x == nil {
+		return nil
+	}
+	clone := *x
+	clone.Content = make([]XMLContent, len(x.Content))
+	for i, content := range x.Content {
+		if content.Child != nil {
+			content.Child = clones[content.Child]
+		}
+		clone.Content[i] = content
+	}
+	return &clone
+}
+
 // LoadXMLFile loads an XML file from the given URI path into a collection of
 // NodeDefs.
 func LoadXMLFile(uri *url.URL) (nodedef *NodeDef, err error) {
+	return LoadXMLFileWithOptions(uri, XMLOptions{})
+}
+
+// XMLFileLoader creates a URI loader function (see RegisterURILoader) that
+// loads XML files with the given options.
+func XMLFileLoader(options XMLOptions) func(*url.URL) (*NodeDef, error) {
+	return func(uri *url.URL) (*NodeDef, error) {
+		return LoadXMLFileWithOptions(uri, options)
+	}
+}
+
+// LoadXML loads an XML document from r into a NodeDef tree.
+func LoadXML(r io.Reader, options XMLOptions) (*NodeDef, error) {
+	return newXMLFileLoader(r, options).Load()
+}
+
+// LoadXMLFileWithOptions is like LoadXMLFile but loads with the given options.
+func LoadXMLFileWithOptions(uri *url.URL, options XMLOptions) (nodedef *NodeDef, err error) {
 	if !CanLoadXMLFile(uri) {
 		return nil, errors.Errorf("cannot load URI %v", uri)
 	}
@@ -26,12 +103,9 @@ func LoadXMLFile(uri *url.URL) (nodedef *NodeDef, err error) {
 			uri.Path, err)
 	}
 	defer CatchDeferred(&err, file.Close)
-	nodedef, err = newXMLFileLoader(file).Load()
+	nodedef, err = LoadXML(file, options)
 	if err != nil {
-		return nil, errors.ErrorfWithCause(
-			err,
-			"failed to load URI %v: %v",
-			uri, err)
+		return nil, wrapLoadError(uri, err)
 	}
 	return nodedef, err
 }
@@ -52,16 +126,38 @@ func CanLoadXMLFile(uri *url.URL) bool {
 	return true
 }
 
+// WriteXMLFile writes a NodeDef tree to the XML file at the given URI path
+// with WriteXML.  Only file URIs with an ".xml" extension can be written.
+func WriteXMLFile(uri *url.URL, nodedef *NodeDef) (err error) {
+	if !CanLoadXMLFile(uri) {
+		return errors.Errorf("cannot write URI %v", uri)
+	}
+	file, err := os.Create(GetURIPath(uri))
+	if err != nil {
+		return errors.ErrorfWithCause(
+			err,
+			"failed to open file %v for writing: %v",
+			uri.Path, err)
+	}
+	defer CatchDeferred(&err, file.Close)
+	return WriteXML(file, nodedef)
+}
+
 type xmlFileLoader struct {
+	options  XMLOptions
+	position *positionReader
 	decoder  *xml.Decoder
 	elements []xml.StartElement
 	nodedefs []*NodeDef
 	rootdef  *NodeDef
 }
 
-func newXMLFileLoader(r io.Reader) *xmlFileLoader {
+func newXMLFileLoader(r io.Reader, options XMLOptions) *xmlFileLoader {
+	position := newPositionReader(r)
 	return &xmlFileLoader{
-		decoder:  xml.NewDecoder(r),
+		options:  options,
+		position: position,
+		decoder:  xml.NewDecoder(position),
 		elements: make([]xml.StartElement, 0, 8),
 		nodedefs: make([]*NodeDef, 0, 8),
 		rootdef:  nil,
@@ -76,7 +172,7 @@ func (loader *xmlFileLoader) Load() (*NodeDef, error) {
 			if err == io.EOF {
 				return loader.rootdef, nil
 			}
-			return nil, err
+			return nil, loader.loadError(err)
 		}
 		switch e := token.(type) {
 
@@ -84,7 +180,7 @@ func (loader *xmlFileLoader) Load() (*NodeDef, error) {
 			loader.elements = append(loader.elements, e)
 			nodedef, err := loader.createNodeDef(e)
 			if err != nil {
-				return nil, err
+				return nil, loader.loadError(err)
 			}
 			if len(loader.nodedefs) == 0 {
 				loader.rootdef = nodedef
@@ -98,14 +194,34 @@ func (loader *xmlFileLoader) Load() (*NodeDef, error) {
 		case xml.CharData:
 			parent := loader.getParentNodeDef()
 			if parent == nil {
-				return nil, errors.Errorf(
-					"CDATA cannot be the root node in a Skink configuration.")
+				if len(bytes.TrimSpace(e)) == 0 {
+					// whitespace around the root element.
+					continue
+				}
+				return nil, loader.loadError(errors.Errorf(
+					"CDATA cannot be the root node in a Skink configuration."))
 			}
 			parent.Value += string([]byte(e))
+			if parent.XML != nil {
+				parent.XML.Content = append(
+					parent.XML.Content, XMLContent{Token: e.Copy()})
+			}
+
+		case xml.Comment:
+			parent := loader.getParentNodeDef()
+			if parent != nil && parent.XML != nil {
+				parent.XML.Content = append(
+					parent.XML.Content, XMLContent{Token: e.Copy()})
+			}
 		}
 	}
 }
 
+// loadError makes a LoadError of err at the decoder's current position.
+func (loader *xmlFileLoader) loadError(err error) error {
+	return loader.position.loadError(loader.decoder.InputOffset(), err)
+}
+
 func (loader *xmlFileLoader) createNodeDef(e xml.StartElement) (nodedef *NodeDef, err error) {
 	parent := loader.getParentNodeDef()
 	name := loader.createNodeName(parent, e)
@@ -122,9 +238,22 @@ func (loader *xmlFileLoader) createNodeDef(e xml.StartElement) (nodedef *NodeDef
 	} else {
 		nodedef = parent.NewChild(name, classuri)
 	}
+	if suggested := getSuggestedXMLName(e); suggested != name.String() {
+		nodedef.OriginalName = MakeString(suggested)
+	}
+	if loader.options.Fidelity {
+		nodedef.XML = &XMLInfo{Name: e.Name}
+		if parent != nil {
+			parent.XML.Content = append(
+				parent.XML.Content, XMLContent{Child: nodedef})
+		}
+	}
 	for _, attr := range e.Attr {
-		if attr.Name.Space == "xmlns" {
-			// This is a namespace definition.  Ignore it.
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			// This is a namespace definition.  Only keep it for fidelity.
+			if nodedef.XML != nil {
+				nodedef.XML.Namespaces = append(nodedef.XML.Namespaces, attr)
+			}
 			continue
 		}
 		_, err = loader.createAttrNodeDef(nodedef, attr)
@@ -145,6 +274,9 @@ func (loader *xmlFileLoader) createAttrNodeDef(parent *NodeDef, a xml.Attr) (nod
 	}
 	child := parent.NewChild(MakeString(a.Name.Local), classuri)
 	child.Value = a.Value
+	if loader.options.Fidelity {
+		child.XML = &XMLInfo{Name: a.Name, Attr: true}
+	}
 	return child, nil
 }
 
@@ -164,13 +296,25 @@ func createURIFromXMLName(name xml.Name) (uri *url.URL, err error) {
 	return uri, nil
 }
 
+// dynamicClassSpace is the "namespace" of the Class URIs of XML elements
+// without a namespace, e.g. "dynamic#name".
+const dynamicClassSpace = "dynamic"
+
 func createURIStringFromXMLName(name xml.Name) string {
 	if name.Space == "" {
-		name.Space = "dynamic"
+		name.Space = dynamicClassSpace
 	}
 	return strings.Join([]string{name.Space, "#", name.Local}, "")
 }
 
+// isDynamicClassURI checks if uri is the Class URI of an XML element without
+// a namespace (see dynamicClassSpace).
+func isDynamicClassURI(uri *url.URL) bool {
+	space := *uri
+	space.Fragment = ""
+	return space.String() == dynamicClassSpace
+}
+
 func (loader *xmlFileLoader) getParentNodeDef() *NodeDef {
 	length := len(loader.nodedefs)
 	if length == 0 {
@@ -181,24 +325,8 @@ func (loader *xmlFileLoader) getParentNodeDef() *NodeDef {
 
 func (loader *xmlFileLoader) createNodeName(parent *NodeDef, e xml.StartElement) String {
 	name := MakeString(getSuggestedXMLName(e))
-	if parent != nil {
-		numbered := name
-		number := 2
-		for {
-			// Assuming whatever architecture we're on will roll over (maybe
-			// 2's complement or 1's complement, etc.)
-			if number < 2 {
-				panic(
-					fmt.Sprintf(
-						"createNodeName rolled over while attempting to "+
-							"create node %v under parent %v", e, parent))
-			}
-			if nodedef := parent.FindChild(numbered); nodedef == nil {
-				return numbered
-			}
-			numbered = MakeString(fmt.Sprintf("%s%d", name, number))
-			number++
-		}
+	if parent != nil && !loader.options.AllowDuplicates {
+		return parent.UniqueChildName(name)
 	}
 	return name
 }
@@ -208,13 +336,167 @@ var nameAttrString = MakeString("name")
 func getSuggestedXMLName(e xml.StartElement) string {
 	for _, attr := range e.Attr {
 		attrName := MakeString(attr.Name.Local)
-		if attr.Name.Space == "" && nameAttrString.Cmp(attrName) == 0 {
+		if attr.Name.Space == "" && nameAttrString.Equal(attrName) {
 			return attr.Value
 		}
 	}
 	return e.Name.Local
 }
 
+// WriteXML writes a NodeDef tree to w as an XML document that LoadXMLFile can
+// load back into an equivalent NodeDef tree.  Children with the String class
+// and no children of their own are written as attributes; all other children
+// are written as elements after the NodeDef's Value.
+//
+// NodeDefs that were loaded with XMLOptions.Fidelity are instead written the
+// way they were in the original document: with their original element or
+// attribute names, namespace declarations, comments and with their character
+// data interleaved with their child elements.  If a NodeDef's Value was
+// changed since it was loaded, it replaces the original character data and is
+// written before its child elements.
+func WriteXML(w io.Writer, nodedef *NodeDef) error {
+	encoder := xml.NewEncoder(w)
+	if err := writeXMLNodeDef(encoder, nodedef, ""); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+// writeXMLNodeDef writes a NodeDef as an element.  parentSpace is the
+// namespace of the parent element.
+func writeXMLNodeDef(encoder *xml.Encoder, nodedef *NodeDef, parentSpace string) error {
+	start, err := createXMLStartElement(nodedef, parentSpace)
+	if err != nil {
+		return err
+	}
+	if err = encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, content := range getXMLContent(nodedef) {
+		if content.Child == nil {
+			err = encoder.EncodeToken(content.Token)
+		} else {
+			err = writeXMLNodeDef(encoder, content.Child, start.Name.Space)
+		}
+		if err != nil {
+			return errors.ErrorfWithCause(
+				err,
+				"failed to write content of %v: %v",
+				nodedef.Name, err)
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// getXMLContent gets the character data, comments and child elements of a
+// NodeDef in the order they should be written.
+func getXMLContent(nodedef *NodeDef) []XMLContent {
+	contents := make([]XMLContent, 0, len(nodedef.Children)+1)
+	written := make(map[*NodeDef]bool, len(nodedef.Children))
+	if nodedef.XML != nil {
+		changed := xmlCharData(nodedef.XML.Content) != nodedef.Value
+		if changed && nodedef.Value != "" {
+			contents = append(contents, XMLContent{Token: xml.CharData(nodedef.Value)})
+		}
+		children := make(map[*NodeDef]bool, len(nodedef.Children))
+		for _, child := range nodedef.Children {
+			children[child] = true
+		}
+		for _, content := range nodedef.XML.Content {
+			if content.Child != nil && !children[content.Child] {
+				continue
+			}
+			if _, ok := content.Token.(xml.CharData); ok && changed {
+				continue
+			}
+			contents = append(contents, content)
+			written[content.Child] = true
+		}
+	} else if nodedef.Value != "" {
+		contents = append(contents, XMLContent{Token: xml.CharData(nodedef.Value)})
+	}
+	for _, child := range nodedef.Children {
+		if !written[child] && !isXMLAttrNodeDef(child) {
+			contents = append(contents, XMLContent{Child: child})
+		}
+	}
+	return contents
+}
+
+// xmlCharData concatenates the character data in an element's content.
+func xmlCharData(contents []XMLContent) string {
+	parts := make([]string, 0, len(contents))
+	for _, content := range contents {
+		if cd, ok := content.Token.(xml.CharData); ok {
+			parts = append(parts, string(cd))
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func createXMLStartElement(nodedef *NodeDef, parentSpace string) (xml.StartElement, error) {
+	start := xml.StartElement{}
+	if nodedef.XML != nil {
+		start.Name = nodedef.XML.Name
+		for _, ns := range nodedef.XML.Namespaces {
+			// Write the declaration verbatim instead of letting the
+			// xml.Encoder treat "xmlns" as a namespace URL.
+			if ns.Name.Space == "xmlns" {
+				ns.Name = xml.Name{Local: "xmlns:" + ns.Name.Local}
+			}
+			start.Attr = append(start.Attr, ns)
+		}
+	} else {
+		if nodedef.ClassURI == nil {
+			return start, errors.Errorf(
+				"cannot write NodeDef %v without a ClassURI", nodedef.Name)
+		}
+		classuri := *nodedef.ClassURI
+		start.Name.Local = classuri.Fragment
+		classuri.Fragment = ""
+		if space := classuri.String(); space != dynamicClassSpace {
+			start.Name.Space = space
+		}
+	}
+	if start.Name.Space == "" && parentSpace != "" {
+		// Don't inherit the parent's default namespace.
+		start.Attr = append(start.Attr, xml.Attr{
+			Name: xml.Name{Local: "xmlns"},
+		})
+	}
+	named := false
+	for _, child := range nodedef.Children {
+		if !isXMLAttrNodeDef(child) {
+			continue
+		}
+		name := xml.Name{Local: child.Name.String()}
+		if child.XML != nil {
+			name = child.XML.Name
+		}
+		if child.Name.Equal(nameAttrString) {
+			named = true
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: name, Value: child.Value})
+	}
+	if !named && nodedef.XML == nil && !nodedef.Name.Equal(MakeString(start.Name.Local)) {
+		start.Attr = append(start.Attr, xml.Attr{
+			Name:  xml.Name{Local: nameAttrString.String()},
+			Value: nodedef.Name.String(),
+		})
+	}
+	return start, nil
+}
+
+// isXMLAttrNodeDef checks if a NodeDef can be written as an XML attribute.
+func isXMLAttrNodeDef(nodedef *NodeDef) bool {
+	if nodedef.XML != nil {
+		return nodedef.XML.Attr
+	}
+	return len(nodedef.Children) == 0 &&
+		nodedef.ClassURI != nil &&
+		classURIsEqual(nodedef.ClassURI, StringClassURI)
+}
+
 // todo(sk): Make this possible:
 //
 // <root>
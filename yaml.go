@@ -0,0 +1,47 @@
+package skink
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteYAML writes a NodeDef tree to w as a YAML document with the same shape
+// as WriteJSON: each NodeDef is a mapping with its "name", its ClassURI as
+// "class", its "value" and its "children" as a sequence of mappings of the
+// same shape.  Empty values and children are omitted.  All strings are
+// written double-quoted so they never need to be re-interpreted.
+func WriteYAML(w io.Writer, nodedef *NodeDef) (err error) {
+	bw := bufio.NewWriter(w)
+	defer CatchDeferred(&err, bw.Flush)
+	writeYAMLNodeDef(bw, nodedef, "", "")
+	return nil
+}
+
+// writeYAMLNodeDef writes a NodeDef as a mapping.  first is the prefix of the
+// mapping's first line (e.g. "- " in a sequence) and indent is the prefix of
+// the rest.  Errors are left in the bufio.Writer to be returned by Flush.
+func writeYAMLNodeDef(w *bufio.Writer, nodedef *NodeDef, first, indent string) {
+	writeYAMLScalar(w, first, "name", nodedef.Name.String())
+	if nodedef.ClassURI != nil {
+		writeYAMLScalar(w, indent, "class", nodedef.ClassURI.String())
+	}
+	if nodedef.Value != "" {
+		writeYAMLScalar(w, indent, "value", nodedef.Value)
+	}
+	if len(nodedef.Children) == 0 {
+		return
+	}
+	w.WriteString(indent)
+	w.WriteString("children:\n")
+	childindent := indent + "    "
+	for _, child := range nodedef.Children {
+		writeYAMLNodeDef(w, child, indent+"  - ", childindent)
+	}
+}
+
+func writeYAMLScalar(w *bufio.Writer, prefix, key, value string) {
+	w.WriteString(strings.Join(
+		[]string{prefix, key, ": ", strconv.Quote(value), "\n"}, ""))
+}